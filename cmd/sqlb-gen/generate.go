@@ -0,0 +1,200 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jaypipes/sqlb"
+)
+
+// Words that are written in upper case in Go identifiers
+var initialisms = map[string]bool{
+	"api": true, "ascii": true, "cpu": true, "css": true, "dns": true,
+	"html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sql": true, "ssh": true, "tls": true, "ttl": true,
+	"ui": true, "uid": true, "uri": true, "url": true, "utf8": true,
+	"uuid": true, "xml": true,
+}
+
+// Identifiers that the generated code declares for itself
+var reservedNames = map[string]bool{
+	"Meta": true,
+}
+
+// A generatedTable holds the Go identifiers generated for a table
+type generatedTable struct {
+	table *sqlb.Table
+	// The table's name as accepted by Meta.Table()
+	name string
+	// The name of the variable holding the table's accessor
+	goName string
+	// The name of the accessor type
+	typeName string
+	// The name of the constant holding the table's name
+	constName string
+	// The names of the column fields, in column order
+	fields []string
+}
+
+// Returns the gofmt'd source of a Go package named pkg with typed accessors
+// for the tables of the supplied Meta. The source is described in the
+// generated code's header comment.
+func generate(pkg string, source string, meta *sqlb.Meta) ([]byte, error) {
+	snapshot, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tables := generatedTables(meta)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by sqlb-gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&b, "// Package %s describes the tables of a database schema for use with\n", pkg)
+	fmt.Fprintf(&b, "// github.com/jaypipes/sqlb.\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import (\n\t\"strings\"\n\n\t\"github.com/jaypipes/sqlb\"\n)\n\n")
+
+	if len(tables) > 0 {
+		fmt.Fprintf(&b, "// Table names, as accepted by Meta.Table()\nconst (\n")
+		for _, gt := range tables {
+			fmt.Fprintf(&b, "\t%s = %s\n", gt.constName, strconv.Quote(gt.name))
+		}
+		fmt.Fprintf(&b, ")\n\n")
+	}
+
+	for _, gt := range tables {
+		fmt.Fprintf(&b, "// %s is the %s %s\n", gt.typeName, gt.name, kindName(gt.table))
+		fmt.Fprintf(&b, "type %s struct {\n\t*sqlb.Table\n", gt.typeName)
+		for x, c := range gt.table.Columns() {
+			fmt.Fprintf(&b, "\t%s *sqlb.Column // %s\n", gt.fields[x], c.Name())
+		}
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	fmt.Fprintf(&b, "var (\n\t// Meta describes all of the schema's tables\n\tMeta *sqlb.Meta\n")
+	for _, gt := range tables {
+		fmt.Fprintf(&b, "\t%s *%s\n", gt.goName, gt.typeName)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	fmt.Fprintf(&b, "func init() {\n")
+	fmt.Fprintf(&b, "\tm, err := sqlb.LoadMeta(strings.NewReader(snapshot))\n")
+	fmt.Fprintf(&b, "\tif err != nil {\n\t\tpanic(\"sqlb-gen: \" + err.Error())\n\t}\n")
+	fmt.Fprintf(&b, "\tMeta = m\n")
+	for _, gt := range tables {
+		fmt.Fprintf(&b, "\t%s = &%s{Table: m.Table(%s)}\n", gt.goName, gt.typeName, gt.constName)
+		for x, c := range gt.table.Columns() {
+			fmt.Fprintf(&b, "\t%s.%s = %s.C(%s)\n", gt.goName, gt.fields[x], gt.goName, strconv.Quote(c.Name()))
+		}
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// The schema snapshot that Meta is loaded from. See sqlb.LoadMeta().\n")
+	fmt.Fprintf(&b, "const snapshot = %s\n", rawString(string(snapshot)))
+
+	return format.Source(b.Bytes())
+}
+
+// Returns the tables of the Meta, in name order, with unique Go identifiers
+func generatedTables(meta *sqlb.Meta) []*generatedTable {
+	used := make(map[string]bool, len(reservedNames))
+	for name := range reservedNames {
+		used[name] = true
+	}
+	res := make([]*generatedTable, 0)
+	for _, t := range meta.Tables() {
+		name := t.Name()
+		if t.Schema() != "" && t.Schema() != meta.DefaultSchema() {
+			name = t.Schema() + "." + name
+		}
+		base := goName(name)
+		goName := base
+		for n := 2; used[goName] || used[goName+"Table"] || used[goName+"TableName"]; n++ {
+			goName = base + strconv.Itoa(n)
+		}
+		gt := &generatedTable{
+			table:     t,
+			name:      name,
+			goName:    goName,
+			typeName:  goName + "Table",
+			constName: goName + "TableName",
+		}
+		used[gt.goName] = true
+		used[gt.typeName] = true
+		used[gt.constName] = true
+		gt.fields = fieldNames(t)
+		res = append(res, gt)
+	}
+	return res
+}
+
+// Returns unique Go field names for the table's columns
+func fieldNames(t *sqlb.Table) []string {
+	// The accessor types embed *sqlb.Table, so no column field may be named
+	// Table
+	used := map[string]bool{"Table": true}
+	res := make([]string, len(t.Columns()))
+	for x, c := range t.Columns() {
+		base := goName(c.Name())
+		if base == "Table" {
+			base = "TableColumn"
+		}
+		field := base
+		for n := 2; used[field]; n++ {
+			field = base + strconv.Itoa(n)
+		}
+		used[field] = true
+		res[x] = field
+	}
+	return res
+}
+
+// Returns an exported Go identifier for the supplied SQL identifier, e.g.
+// UserID for "user_id" and BillingInvoices for "billing.invoices"
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			sb.WriteString(strings.ToUpper(w))
+			continue
+		}
+		runes := []rune(w)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	res := sb.String()
+	if res == "" || !unicode.IsLetter([]rune(res)[0]) {
+		// Identifiers must start with a letter, and must start with an
+		// upper case letter to be exported
+		res = "X" + res
+	}
+	return res
+}
+
+func kindName(t *sqlb.Table) string {
+	switch t.Kind() {
+	case sqlb.TABLE_KIND_VIEW:
+		return "view"
+	case sqlb.TABLE_KIND_MATERIALIZED_VIEW:
+		return "materialized view"
+	}
+	return "table"
+}
+
+// Returns the supplied string as a Go raw string literal. Backquotes, which
+// can't appear in raw string literals, are concatenated as quoted strings.
+func rawString(s string) string {
+	return "`" + strings.Replace(s, "`", "` + \"`\" + `", -1) + "`"
+}
@@ -0,0 +1,108 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaypipes/sqlb"
+)
+
+const _DDL = `
+CREATE TABLE users (
+  id INT NOT NULL PRIMARY KEY,
+  email VARCHAR(100) NOT NULL,
+  "table" TEXT,
+  profile_url TEXT,
+  "profile-url" TEXT
+);
+CREATE TABLE billing.invoices (
+  id INT PRIMARY KEY,
+  user_id INT REFERENCES users (id),
+  memo TEXT DEFAULT '` + "`" + `quoted` + "`" + `'
+);
+CREATE TABLE users_table (id INT);
+`
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	meta := sqlb.NewMeta(sqlb.DIALECT_POSTGRESQL, "blog")
+	assert.Nil(sqlb.LoadDDL(sqlb.DIALECT_POSTGRESQL, strings.NewReader(_DDL), meta))
+	meta.NewView("user_names").NewColumn("name")
+
+	src, err := generate("blogschema", "DDL schema.sql", meta)
+	assert.Nil(err)
+	code := string(src)
+
+	// The generated code is valid Go
+	_, err = parser.ParseFile(token.NewFileSet(), "schema.go", src, parser.AllErrors)
+	assert.Nil(err)
+
+	assert.True(strings.HasPrefix(code,
+		"// Code generated by sqlb-gen from DDL schema.sql. DO NOT EDIT.\n"))
+	assert.Contains(code, "package blogschema\n")
+
+	expected := []string{
+		`BillingInvoicesTableName = "billing.invoices"`,
+		`UserNamesTableName       = "user_names"`,
+		`UsersTableName           = "users"`,
+		// A table whose name clashes with the identifiers of another table
+		// gets a numbered name
+		`UsersTable2TableName     = "users_table"`,
+		"type UsersTable struct {",
+		"\tID          *sqlb.Column // id",
+		"\tEmail       *sqlb.Column // email",
+		// A column named "table" can't clash with the embedded *sqlb.Table
+		"\tTableColumn *sqlb.Column // table",
+		"\tProfileURL  *sqlb.Column // profile_url",
+		"\tProfileURL2 *sqlb.Column // profile-url",
+		"// UserNamesTable is the user_names view",
+		"\tBillingInvoices = &BillingInvoicesTable{Table: m.Table(BillingInvoicesTableName)}",
+		"\tBillingInvoices.UserID = BillingInvoices.C(\"user_id\")",
+		"\tUsers.TableColumn = Users.C(\"table\")",
+		"\tUsersTable2     *UsersTable2Table",
+		// Backquotes in the snapshot are concatenated
+		"` + \"`\" + `",
+	}
+	for _, exp := range expected {
+		assert.Contains(code, exp)
+	}
+
+	// The embedded snapshot loads the same schema
+	start := strings.Index(code, "const snapshot = ") + len("const snapshot = ")
+	lit := strings.TrimSpace(code[start:])
+	lit = strings.Replace(lit, "` + \"`\" + `", "`", -1)
+	lit = strings.TrimSuffix(strings.TrimPrefix(lit, "`"), "`")
+	loaded, err := sqlb.LoadMeta(strings.NewReader(lit))
+	assert.Nil(err)
+	assert.True(sqlb.Diff(meta, loaded).IsEmpty())
+}
+
+func TestGoName(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{name: "users", exp: "Users"},
+		{name: "user_id", exp: "UserID"},
+		{name: "api_key_uuid", exp: "APIKeyUUID"},
+		{name: "billing.invoices", exp: "BillingInvoices"},
+		{name: "createdOn", exp: "CreatedOn"},
+		{name: "2fa_secret", exp: "X2faSecret"},
+		{name: "__", exp: "X"},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, goName(test.name), test.name)
+	}
+}
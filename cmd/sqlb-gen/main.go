@@ -0,0 +1,193 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+// Command sqlb-gen generates a Go package with typed accessors for the tables
+// and columns of a database schema, so that a misspelled table or column name
+// is a compile error instead of a nil *sqlb.Column at runtime.
+//
+// The schema is reflected from a database, loaded from DDL files or loaded
+// from a schema snapshot written by json.Marshal() of a sqlb.Meta:
+//
+//	sqlb-gen -dialect mysql -dsn "user:pass@tcp(localhost)/blog" -out schema/schema.go
+//	sqlb-gen -dialect postgresql -ddl migrations/ -out schema/schema.go
+//	sqlb-gen -snapshot schema.json -package blogschema -out schema/schema.go
+//
+// The generated package contains a constant with the name of each table, a
+// type for each table with a *sqlb.Column field for each of the table's
+// columns, a variable holding an instance of each type and a Meta variable
+// holding the *sqlb.Meta that the tables belong to.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jaypipes/sqlb"
+)
+
+var (
+	errNoSource      = errors.New("Please specify exactly one of -dsn, -ddl or -snapshot.")
+	errNoDialect     = errors.New("Please specify -dialect as mysql or postgresql.")
+	errInvalidOption = errors.New("-include and -exclude may only be used with -dsn.")
+)
+
+type options struct {
+	dialect  string
+	dsn      string
+	ddl      string
+	snapshot string
+	database string
+	include  string
+	exclude  string
+	pkg      string
+	out      string
+}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.dialect, "dialect", "", "SQL dialect of the schema: mysql or postgresql")
+	flag.StringVar(&opts.dsn, "dsn", "", "data source name of a database to reflect the schema from")
+	flag.StringVar(&opts.ddl, "ddl", "", "DDL file, or directory of *.sql files, to load the schema from")
+	flag.StringVar(&opts.snapshot, "snapshot", "", "schema snapshot file to load the schema from")
+	flag.StringVar(&opts.database, "database", "", "name of the database, if not reflected")
+	flag.StringVar(&opts.include, "include", "", "comma-separated patterns of tables to reflect")
+	flag.StringVar(&opts.exclude, "exclude", "", "comma-separated patterns of tables not to reflect")
+	flag.StringVar(&opts.pkg, "package", "schema", "name of the generated package")
+	flag.StringVar(&opts.out, "out", "", "file to write the generated code to, instead of stdout")
+	flag.Parse()
+
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "sqlb-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts *options) error {
+	meta, source, err := loadMeta(opts)
+	if err != nil {
+		return err
+	}
+	src, err := generate(opts.pkg, source, meta)
+	if err != nil {
+		return err
+	}
+	if opts.out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(opts.out, src, 0644)
+}
+
+// Returns the Meta described by the source selected in the options, along
+// with a description of the source for the generated code's header
+func loadMeta(opts *options) (*sqlb.Meta, string, error) {
+	sources := 0
+	for _, s := range []string{opts.dsn, opts.ddl, opts.snapshot} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, "", errNoSource
+	}
+	if opts.dsn == "" && (opts.include != "" || opts.exclude != "") {
+		return nil, "", errInvalidOption
+	}
+
+	if opts.snapshot != "" {
+		f, err := os.Open(opts.snapshot)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		meta, err := sqlb.LoadMeta(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", opts.snapshot, err)
+		}
+		return meta, "snapshot " + opts.snapshot, nil
+	}
+
+	dialect, err := parseDialect(opts.dialect)
+	if err != nil {
+		return nil, "", err
+	}
+	meta := sqlb.NewMeta(dialect, opts.database)
+	if opts.ddl != "" {
+		fi, err := os.Stat(opts.ddl)
+		if err != nil {
+			return nil, "", err
+		}
+		if fi.IsDir() {
+			err = sqlb.LoadDDLDir(dialect, opts.ddl, meta)
+		} else {
+			err = loadDDLFile(dialect, opts.ddl, meta)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return meta, "DDL " + opts.ddl, nil
+	}
+
+	driver := "mysql"
+	if dialect == sqlb.DIALECT_POSTGRESQL {
+		driver = "postgres"
+	}
+	db, err := sql.Open(driver, opts.dsn)
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+	reflectOpts := []sqlb.ReflectOption{sqlb.WithDialect(dialect)}
+	if opts.include != "" {
+		reflectOpts = append(reflectOpts, sqlb.IncludeTables(splitList(opts.include)...))
+	}
+	if opts.exclude != "" {
+		reflectOpts = append(reflectOpts, sqlb.ExcludeTables(splitList(opts.exclude)...))
+	}
+	if err = sqlb.ReflectContext(context.Background(), db, meta, reflectOpts...); err != nil {
+		return nil, "", err
+	}
+	// The DSN may contain a password, so it is not included in the generated
+	// code
+	return meta, "a " + opts.dialect + " database", nil
+}
+
+func loadDDLFile(dialect sqlb.Dialect, path string, meta *sqlb.Meta) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = sqlb.LoadDDL(dialect, f, meta); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func parseDialect(name string) (sqlb.Dialect, error) {
+	switch strings.ToLower(name) {
+	case "mysql":
+		return sqlb.DIALECT_MYSQL, nil
+	case "postgresql", "postgres":
+		return sqlb.DIALECT_POSTGRESQL, nil
+	}
+	return sqlb.DIALECT_UNKNOWN, errNoDialect
+}
+
+func splitList(s string) []string {
+	res := make([]string, 0)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			res = append(res, part)
+		}
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaypipes/sqlb"
+)
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "sqlb-gen")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ddl := filepath.Join(dir, "schema.sql")
+	assert.Nil(os.WriteFile(ddl, []byte(_DDL), 0644))
+	out := filepath.Join(dir, "schema.go")
+	assert.Nil(run(&options{dialect: "postgresql", ddl: ddl, pkg: "schema", out: out}))
+	b, err := os.ReadFile(out)
+	assert.Nil(err)
+	assert.Contains(string(b), "type UsersTable struct {")
+
+	// A directory of DDL files
+	assert.Nil(run(&options{dialect: "postgres", ddl: dir, pkg: "schema", out: out}))
+
+	// A snapshot
+	meta := sqlb.NewMeta(sqlb.DIALECT_MYSQL, "blog")
+	assert.Nil(sqlb.LoadDDL(sqlb.DIALECT_MYSQL, strings.NewReader("CREATE TABLE users (id INT)"), meta))
+	snap, err := json.Marshal(meta)
+	assert.Nil(err)
+	snapshot := filepath.Join(dir, "schema.json")
+	assert.Nil(os.WriteFile(snapshot, snap, 0644))
+	assert.Nil(run(&options{snapshot: snapshot, pkg: "schema", out: out}))
+	b, err = os.ReadFile(out)
+	assert.Nil(err)
+	assert.Contains(string(b), "from snapshot "+snapshot+".")
+	assert.Contains(string(b), "\tID *sqlb.Column // id")
+}
+
+func TestLoadMetaErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		opts *options
+		exp  error
+	}{
+		{
+			name: "No source",
+			opts: &options{dialect: "mysql"},
+			exp:  errNoSource,
+		},
+		{
+			name: "Multiple sources",
+			opts: &options{dialect: "mysql", ddl: "schema.sql", snapshot: "schema.json"},
+			exp:  errNoSource,
+		},
+		{
+			name: "Unknown dialect",
+			opts: &options{dialect: "oracle", ddl: "schema.sql"},
+			exp:  errNoDialect,
+		},
+		{
+			name: "Table patterns without a database",
+			opts: &options{dialect: "mysql", ddl: "schema.sql", include: "users"},
+			exp:  errInvalidOption,
+		},
+	}
+	for _, test := range tests {
+		_, _, err := loadMeta(test.opts)
+		assert.Equal(test.exp, err, test.name)
+	}
+
+	_, _, err := loadMeta(&options{dialect: "mysql", ddl: "does-not-exist.sql"})
+	assert.True(os.IsNotExist(err))
+}
@@ -9,19 +9,160 @@ type Column struct {
 	alias string
 	name  string
 	tbl   *Table
+	def   columnDef
+}
+
+// A columnDef describes the definition of a column in the database schema:
+// its data type, nullability, default and key membership. A columnDef is
+// populated by Reflect() and is copied by value to aliased copies of the
+// column.
+type columnDef struct {
+	sqlType SqlType
+	// The data type as reported by the database server, e.g. "varchar(100)"
+	// or "int unsigned" for MySQL or "character varying" for PostgreSQL
+	rawType string
+	// The maximum length for character and binary types, or 0
+	length int
+	// The precision and scale for numeric types, or 0
+	precision int
+	scale     int
+	nullable  bool
+	// The default expression for the column, or nil if the column has no
+	// default
+	defaultExpr   *string
+	autoIncrement bool
+	// The 1-based position of the column in the table's primary key, or 0 if
+	// the column is not part of the primary key
+	pkOrdinal int
 }
 
 func (c *Column) from() selection {
 	return c.tbl
 }
 
-func (c *Column) disableAliasScan() func() {
-	origAlias := c.alias
-	c.alias = ""
-	return func() { c.alias = origAlias }
+func (c *Column) Column() *Column {
+	return c
 }
 
-func (c *Column) Column() *Column {
+// Returns the name of the column
+func (c *Column) Name() string {
+	return c.name
+}
+
+// Returns the alias of the column, or "" if the column is not aliased
+func (c *Column) Alias() string {
+	return c.alias
+}
+
+// Returns the Table the column belongs to
+func (c *Column) Table() *Table {
+	return c.tbl
+}
+
+// Returns the column's data type. Returns SQL_TYPE_UNKNOWN if the column's
+// data type is not known, for instance because the column was not reflected
+// from the database, or if the database's data type does not map to a
+// SqlType.
+func (c *Column) Type() SqlType {
+	if c.def.rawType == "" {
+		return SQL_TYPE_UNKNOWN
+	}
+	return c.def.sqlType
+}
+
+// Returns the column's data type as reported by the database server, or ""
+// if the data type is not known
+func (c *Column) RawType() string {
+	return c.def.rawType
+}
+
+// Returns the maximum length of the column's values for character and binary
+// data types, or 0 if the length is not known or does not apply
+func (c *Column) Length() int {
+	return c.def.length
+}
+
+// Returns the precision of the column for numeric data types, or 0 if the
+// precision is not known or does not apply
+func (c *Column) Precision() int {
+	return c.def.precision
+}
+
+// Returns the scale of the column for numeric data types, or 0 if the scale
+// is not known or does not apply
+func (c *Column) Scale() int {
+	return c.def.scale
+}
+
+// Returns whether the column accepts NULL values
+func (c *Column) IsNullable() bool {
+	return c.def.nullable
+}
+
+// Returns the column's default expression, as reported by the database
+// server, and true, or "" and false if the column has no default
+func (c *Column) Default() (string, bool) {
+	if c.def.defaultExpr == nil {
+		return "", false
+	}
+	return *c.def.defaultExpr, true
+}
+
+// Returns whether the column's value is generated by the database server on
+// insert, i.e. an AUTO_INCREMENT, SERIAL or IDENTITY column
+func (c *Column) IsAutoIncrement() bool {
+	return c.def.autoIncrement
+}
+
+// Returns whether the column is part of the table's primary key
+func (c *Column) IsPrimaryKey() bool {
+	return c.def.pkOrdinal > 0
+}
+
+// Sets the column's data type, written as it would be in a column definition
+// for the SQL dialect of the column's Meta, e.g. "varchar(100)",
+// "int unsigned" or "numeric(10,2)". A SERIAL data type also makes the column
+// auto-incrementing and NOT NULL. If the data type can't be parsed, the
+// column's data type is unknown and the DDL statement builders return
+// ERR_DDL_UNKNOWN_TYPE for the column.
+func (c *Column) SetType(dataType string) *Column {
+	dialect := c.tbl.meta.dialect
+	def, err := parseColumnType(dialect, dataType)
+	if err != nil {
+		def = columnDef{sqlType: SQL_TYPE_UNKNOWN}
+	}
+	serial := def.autoIncrement
+	def.nullable = c.def.nullable
+	def.defaultExpr = c.def.defaultExpr
+	def.autoIncrement = c.def.autoIncrement
+	def.pkOrdinal = c.def.pkOrdinal
+	if serial {
+		def.autoIncrement = true
+		setSerialDefaults(dialect, c.tbl, c.name, &def)
+	}
+	c.def = def
+	return c
+}
+
+// Sets whether the column accepts NULL values
+func (c *Column) SetNullable(nullable bool) *Column {
+	c.def.nullable = nullable
+	return c
+}
+
+// Sets the column's default expression, written as it would be in a column
+// definition, e.g. "0", "'active'" or "CURRENT_TIMESTAMP"
+func (c *Column) SetDefault(expr string) *Column {
+	c.def.defaultExpr = &expr
+	return c
+}
+
+// Sets whether the column's value is generated by the database server on
+// insert. Auto-incrementing columns are output as AUTO_INCREMENT columns for
+// MySQL and as GENERATED BY DEFAULT AS IDENTITY columns for PostgreSQL,
+// unless their data type is SERIAL.
+func (c *Column) SetAutoIncrement(autoIncrement bool) *Column {
+	c.def.autoIncrement = autoIncrement
 	return c
 }
 
@@ -34,11 +175,11 @@ func (c *Column) size(scanner *sqlScanner) int {
 	if c.tbl.alias != "" {
 		size += len(c.tbl.alias)
 	} else {
-		size += len(c.tbl.name)
+		size += c.tbl.nameSize()
 	}
 	size += len(Symbols[SYM_PERIOD])
 	size += len(c.name)
-	if c.alias != "" {
+	if c.alias != "" && !scanner.disableAliases {
 		size += len(Symbols[SYM_AS]) + len(c.alias)
 	}
 	return size
@@ -49,11 +190,11 @@ func (c *Column) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg
 	if c.tbl.alias != "" {
 		bw += copy(b[bw:], c.tbl.alias)
 	} else {
-		bw += copy(b[bw:], c.tbl.name)
+		bw += c.tbl.scanName(b[bw:])
 	}
 	bw += copy(b[bw:], Symbols[SYM_PERIOD])
 	bw += copy(b[bw:], c.name)
-	if c.alias != "" {
+	if c.alias != "" && !scanner.disableAliases {
 		bw += copy(b[bw:], Symbols[SYM_AS])
 		bw += copy(b[bw:], c.alias)
 	}
@@ -69,5 +210,6 @@ func (c *Column) As(alias string) *Column {
 		alias: alias,
 		name:  c.name,
 		tbl:   c.tbl,
+		def:   c.def,
 	}
 }
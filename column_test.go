@@ -64,3 +64,57 @@ func TestColumnAlias(t *testing.T) {
 	assert.Equal(written, s)
 	assert.Equal(exp, string(b))
 }
+
+func TestColumnDefinition(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	c := m.Table("users").C("name")
+
+	// Columns that were not reflected have no known type
+	assert.Equal(SQL_TYPE_UNKNOWN, c.Type())
+	assert.Equal("", c.RawType())
+
+	dflt := "anonymous"
+	c.def = columnDef{
+		sqlType:     SQL_TYPE_VARCHAR,
+		rawType:     "varchar(100)",
+		length:      100,
+		defaultExpr: &dflt,
+	}
+	assert.Equal(SQL_TYPE_VARCHAR, c.Type())
+	assert.Equal(100, c.Length())
+	d, hasDefault := c.Default()
+	assert.True(hasDefault)
+	assert.Equal("anonymous", d)
+
+	// Aliased copies of the column keep the column definition
+	aliased := c.As("user_name")
+	assert.Equal("user_name", aliased.Alias())
+	assert.Equal("varchar(100)", aliased.RawType())
+}
+
+func TestSqlTypeFromRaw(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		raw string
+		exp SqlType
+	}{
+		{raw: "varchar", exp: SQL_TYPE_VARCHAR},
+		{raw: "character varying", exp: SQL_TYPE_VARCHAR},
+		{raw: "INT", exp: SQL_TYPE_INT},
+		{raw: "tinyint", exp: SQL_TYPE_SMALLINT},
+		{raw: "bigint", exp: SQL_TYPE_BIGINT},
+		{raw: "double precision", exp: SQL_TYPE_FLOAT},
+		{raw: "numeric", exp: SQL_TYPE_DECIMAL},
+		{raw: "datetime", exp: SQL_TYPE_TIMESTAMP},
+		{raw: "timestamp with time zone", exp: SQL_TYPE_TIMESTAMP},
+		{raw: "bytea", exp: SQL_TYPE_BINARY},
+		{raw: "jsonb", exp: SQL_TYPE_JSON},
+		{raw: "geometry", exp: SQL_TYPE_UNKNOWN},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, sqlTypeFromRaw(test.raw), test.raw)
+	}
+}
@@ -0,0 +1,248 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import "sort"
+
+// A commentClause holds the comments, sqlcommenter-style tags and optimizer
+// hints that decorate a SQL statement. Leading comments are output before the
+// statement and trailing comments and tags are output after it, for example:
+//
+// /* leading */ SELECT users.id FROM users /* trailing */ /*route='%2Fusers'*/
+//
+// Optimizer hints are output in the position the dialect's optimizer expects
+// them. For MySQL, hints are output directly after the SELECT keyword:
+//
+// SELECT /*+ INDEX(users ix_email) */ users.id FROM users
+//
+// For PostgreSQL, the pg_hint_plan extension expects the hint comment to be
+// the very first thing in the statement:
+//
+// /*+ IndexScan(users ix_email) */ SELECT users.id FROM users
+//
+// All text is sanitized when it is added to the commentClause so that user
+// supplied text can never terminate the comment early and inject SQL.
+type commentClause struct {
+	leading  []string
+	trailing []string
+	hints    []string
+	tags     map[string]string
+}
+
+// Returns whether the statement's hints should be output before the statement
+// instead of after the SELECT keyword
+func hintsLead(scanner *sqlScanner) bool {
+	return scanner.dialect == DIALECT_POSTGRESQL
+}
+
+func (c *commentClause) hintsSize() int {
+	if len(c.hints) == 0 {
+		return 0
+	}
+	size := len(Symbols[SYM_HINT_START]) + len(Symbols[SYM_COMMENT_END])
+	for _, h := range c.hints {
+		size += len(h)
+	}
+	size += len(Symbols[SYM_SPACE]) * (len(c.hints) - 1)
+	return size + len(Symbols[SYM_SPACE])
+}
+
+func (c *commentClause) scanHints(b []byte) int {
+	if len(c.hints) == 0 {
+		return 0
+	}
+	bw := copy(b, Symbols[SYM_HINT_START])
+	for x, h := range c.hints {
+		if x > 0 {
+			bw += copy(b[bw:], Symbols[SYM_SPACE])
+		}
+		bw += copy(b[bw:], h)
+	}
+	bw += copy(b[bw:], Symbols[SYM_COMMENT_END])
+	bw += copy(b[bw:], Symbols[SYM_SPACE])
+	return bw
+}
+
+// Returns the number of bytes needed for the hints and comments that precede
+// the statement
+func (c *commentClause) leadingSize(scanner *sqlScanner) int {
+	size := 0
+	if hintsLead(scanner) {
+		size += c.hintsSize()
+	}
+	for _, text := range c.leading {
+		size += len(Symbols[SYM_COMMENT_START]) + len(text) +
+			len(Symbols[SYM_COMMENT_END]) + len(Symbols[SYM_SPACE])
+	}
+	return size
+}
+
+func (c *commentClause) scanLeading(scanner *sqlScanner, b []byte) int {
+	bw := 0
+	if hintsLead(scanner) {
+		bw += c.scanHints(b[bw:])
+	}
+	if scanner.fingerprint {
+		// Comments and tags often hold per-request values such as trace IDs,
+		// so they are left out of fingerprints. Hints are kept because they
+		// change how the query runs.
+		return bw
+	}
+	for _, text := range c.leading {
+		bw += copy(b[bw:], Symbols[SYM_COMMENT_START])
+		bw += copy(b[bw:], text)
+		bw += copy(b[bw:], Symbols[SYM_COMMENT_END])
+		bw += copy(b[bw:], Symbols[SYM_SPACE])
+	}
+	return bw
+}
+
+// Returns the number of bytes needed for the hints that follow the SELECT
+// keyword
+func (c *commentClause) inlineSize(scanner *sqlScanner) int {
+	if hintsLead(scanner) {
+		return 0
+	}
+	return c.hintsSize()
+}
+
+func (c *commentClause) scanInline(scanner *sqlScanner, b []byte) int {
+	if hintsLead(scanner) {
+		return 0
+	}
+	return c.scanHints(b)
+}
+
+// Returns the number of bytes needed for the comments and tags that follow
+// the statement
+func (c *commentClause) trailingSize(scanner *sqlScanner) int {
+	size := 0
+	for _, text := range c.trailing {
+		size += len(Symbols[SYM_SPACE]) + len(Symbols[SYM_COMMENT_START]) +
+			len(text) + len(Symbols[SYM_COMMENT_END])
+	}
+	if len(c.tags) > 0 {
+		size += len(Symbols[SYM_SPACE]) + len(c.tagString())
+	}
+	return size
+}
+
+func (c *commentClause) scanTrailing(scanner *sqlScanner, b []byte) int {
+	bw := 0
+	if scanner.fingerprint {
+		return bw
+	}
+	for _, text := range c.trailing {
+		bw += copy(b[bw:], Symbols[SYM_SPACE])
+		bw += copy(b[bw:], Symbols[SYM_COMMENT_START])
+		bw += copy(b[bw:], text)
+		bw += copy(b[bw:], Symbols[SYM_COMMENT_END])
+	}
+	if len(c.tags) > 0 {
+		bw += copy(b[bw:], Symbols[SYM_SPACE])
+		bw += copy(b[bw:], c.tagString())
+	}
+	return bw
+}
+
+// Returns the sqlcommenter serialization of the commentClause's tags. Keys
+// are sorted, and both keys and values are URL-encoded with values wrapped in
+// single quotes:
+//
+// /*action='%2Fparam%2Ad',controller='index'*/
+//
+// See https://google.github.io/sqlcommenter/spec/
+func (c *commentClause) tagString() string {
+	keys := make([]string, 0, len(c.tags))
+	for k := range c.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := make([]byte, 0, 64)
+	b = append(b, "/*"...)
+	for x, k := range keys {
+		if x > 0 {
+			b = append(b, ',')
+		}
+		b = appendURLEncoded(b, k)
+		b = append(b, '=', '\'')
+		b = appendURLEncoded(b, c.tags[k])
+		b = append(b, '\'')
+	}
+	return string(append(b, "*/"...))
+}
+
+// Appends the URL-encoded form of the supplied string to the byte slice.
+// Every byte other than unreserved characters (RFC 3986) is percent-encoded,
+// which means the result can never contain a quote or comment delimiter.
+func appendURLEncoded(b []byte, s string) []byte {
+	const hexChars = "0123456789ABCDEF"
+	for x := 0; x < len(s); x++ {
+		c := s[x]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b = append(b, c)
+		default:
+			b = append(b, '%', hexChars[c>>4], hexChars[c&0x0f])
+		}
+	}
+	return b
+}
+
+// Returns the supplied comment text with any sequence that would open or
+// close a comment broken up with a space so that the text cannot terminate
+// the enclosing comment early. PostgreSQL supports nested comments, so "/*"
+// needs to be neutralized as well as "*/".
+func sanitizeComment(text string) string {
+	b := make([]byte, 0, len(text))
+	for x := 0; x < len(text); x++ {
+		c := text[x]
+		b = append(b, c)
+		if x+1 < len(text) {
+			next := text[x+1]
+			if (c == '*' && next == '/') || (c == '/' && next == '*') {
+				b = append(b, ' ')
+			}
+		}
+	}
+	return string(b)
+}
+
+func addLeadingComment(c *commentClause, text string) *commentClause {
+	if c == nil {
+		c = &commentClause{}
+	}
+	c.leading = append(c.leading, sanitizeComment(text))
+	return c
+}
+
+func addTrailingComment(c *commentClause, text string) *commentClause {
+	if c == nil {
+		c = &commentClause{}
+	}
+	c.trailing = append(c.trailing, sanitizeComment(text))
+	return c
+}
+
+func addHint(c *commentClause, hint string) *commentClause {
+	if c == nil {
+		c = &commentClause{}
+	}
+	c.hints = append(c.hints, sanitizeComment(hint))
+	return c
+}
+
+func addTag(c *commentClause, key string, value string) *commentClause {
+	if c == nil {
+		c = &commentClause{}
+	}
+	if c.tags == nil {
+		c.tags = make(map[string]string, 0)
+	}
+	c.tags[key] = value
+	return c
+}
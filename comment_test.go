@@ -0,0 +1,115 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeComment(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		text string
+		exp  string
+	}{
+		{text: "service=api", exp: "service=api"},
+		{text: "oops */ DROP TABLE users; /*", exp: "oops * / DROP TABLE users; / *"},
+		{text: "*/*/", exp: "* / * /"},
+		{text: "nested /* comment", exp: "nested / * comment"},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, sanitizeComment(test.text))
+	}
+}
+
+func TestCommentTags(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &commentClause{}
+	c = addTag(c, "route", "/users/{id}")
+	c = addTag(c, "action", "it's */ here")
+	c = addTag(c, "db driver", "mysql")
+	assert.Equal(
+		"/*action='it%27s%20%2A%2F%20here',db%20driver='mysql',route='%2Fusers%2F%7Bid%7D'*/",
+		c.tagString(),
+	)
+}
+
+func TestQueryComments(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	colUserId := users.C("id")
+
+	pm := testFixtureMeta()
+	pm.dialect = DIALECT_POSTGRESQL
+	pusers := pm.Table("users")
+
+	tests := []struct {
+		name  string
+		q     Query
+		qs    string
+		qargs []interface{}
+	}{
+		{
+			name: "SELECT leading and trailing comments",
+			q:    Select(users).Comment("lead").TrailingComment("trail"),
+			qs:   "/* lead */ SELECT users.id, users.name FROM users /* trail */",
+		},
+		{
+			name: "SELECT sanitized comment",
+			q:    Select(users).Comment("x */ DELETE FROM users; /* y"),
+			qs:   "/* x * / DELETE FROM users; / * y */ SELECT users.id, users.name FROM users",
+		},
+		{
+			name: "SELECT with tags",
+			q: Select(users).Where(Equal(colUserId, 1)).
+				Tag("route", "/users").Tag("service", "api"),
+			qs:    "SELECT users.id, users.name FROM users WHERE users.id = ? /*route='%2Fusers',service='api'*/",
+			qargs: []interface{}{1},
+		},
+		{
+			name: "SELECT MySQL hints",
+			q:    Select(users).Hint("INDEX(users ix_name)").Hint("MAX_EXECUTION_TIME(1000)"),
+			qs:   "SELECT /*+ INDEX(users ix_name) MAX_EXECUTION_TIME(1000) */ users.id, users.name FROM users",
+		},
+		{
+			name: "SELECT PostgreSQL hints and comment",
+			q:    Select(pusers).Comment("lead").Hint("IndexScan(users ix_name)"),
+			qs:   "/*+ IndexScan(users ix_name) */ /* lead */ SELECT users.id, users.name FROM users",
+		},
+		{
+			name:  "INSERT comments",
+			q:     Insert(users, map[string]interface{}{"id": 1}).Comment("lead").Tag("a", "b"),
+			qs:    "/* lead */ INSERT INTO users (id) VALUES (?) /*a='b'*/",
+			qargs: []interface{}{1},
+		},
+		{
+			name:  "UPDATE comments",
+			q:     Update(users, map[string]interface{}{"name": "foo"}).Where(Equal(colUserId, 1)).TrailingComment("trail"),
+			qs:    "UPDATE users SET name = ? WHERE users.id = ? /* trail */",
+			qargs: []interface{}{"foo", 1},
+		},
+		{
+			name: "DELETE comments",
+			q:    Delete(users).Comment("lead").TrailingComment("trail"),
+			qs:   "/* lead */ DELETE FROM users /* trail */",
+		},
+	}
+	for _, test := range tests {
+		qs, qargs := test.q.StringArgs()
+		assert.Equal(test.qs, qs, test.name)
+		assert.Equal(len(test.qargs), len(qargs), test.name)
+	}
+
+	// Interpolation must leave markers appearing in comments alone
+	q := Select(users).Comment("why?").Where(Equal(colUserId, 1))
+	assert.Equal("/* why? */ SELECT users.id, users.name FROM users WHERE users.id = 1", q.Interpolated())
+}
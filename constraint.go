@@ -0,0 +1,176 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import "strings"
+
+type ConstraintType int
+
+const (
+	CONSTRAINT_TYPE_PRIMARY_KEY ConstraintType = iota
+	CONSTRAINT_TYPE_UNIQUE
+)
+
+// A ReferentialAction is the action the database server takes on rows of a
+// referencing table when the referenced row is deleted or updated
+type ReferentialAction int
+
+const (
+	REFERENTIAL_ACTION_NO_ACTION ReferentialAction = iota
+	REFERENTIAL_ACTION_RESTRICT
+	REFERENTIAL_ACTION_CASCADE
+	REFERENTIAL_ACTION_SET_NULL
+	REFERENTIAL_ACTION_SET_DEFAULT
+)
+
+var (
+	referentialActionNames = map[ReferentialAction]string{
+		REFERENTIAL_ACTION_NO_ACTION:   "NO ACTION",
+		REFERENTIAL_ACTION_RESTRICT:    "RESTRICT",
+		REFERENTIAL_ACTION_CASCADE:     "CASCADE",
+		REFERENTIAL_ACTION_SET_NULL:    "SET NULL",
+		REFERENTIAL_ACTION_SET_DEFAULT: "SET DEFAULT",
+	}
+)
+
+// Returns the SQL keywords for the referential action, e.g. "SET NULL"
+func (a ReferentialAction) String() string {
+	return referentialActionNames[a]
+}
+
+// Returns the ReferentialAction for an UPDATE_RULE or DELETE_RULE value from
+// the information schema's REFERENTIAL_CONSTRAINTS table. Unknown values are
+// returned as REFERENTIAL_ACTION_NO_ACTION, which is the SQL standard
+// default.
+func referentialActionFromRule(rule string) ReferentialAction {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	for a, name := range referentialActionNames {
+		if name == rule {
+			return a
+		}
+	}
+	return REFERENTIAL_ACTION_NO_ACTION
+}
+
+// A Constraint is a PRIMARY KEY or UNIQUE constraint on one or more of a
+// table's columns
+type Constraint struct {
+	name    string
+	ctype   ConstraintType
+	tbl     *Table
+	columns []*Column
+}
+
+// Returns the name of the constraint
+func (c *Constraint) Name() string {
+	return c.name
+}
+
+// Returns the type of the constraint
+func (c *Constraint) Type() ConstraintType {
+	return c.ctype
+}
+
+// Returns the Table the constraint belongs to
+func (c *Constraint) Table() *Table {
+	return c.tbl
+}
+
+// Returns the constrained columns, in constraint order
+func (c *Constraint) Columns() []*Column {
+	return c.columns
+}
+
+// A ForeignKey is a FOREIGN KEY constraint relating one or more of a table's
+// columns to the columns of a referenced table
+type ForeignKey struct {
+	name       string
+	tbl        *Table
+	columns    []*Column
+	refTable   *Table
+	refColumns []*Column
+	onDelete   ReferentialAction
+	onUpdate   ReferentialAction
+}
+
+// Returns the name of the foreign key
+func (fk *ForeignKey) Name() string {
+	return fk.name
+}
+
+// Returns the referencing Table
+func (fk *ForeignKey) Table() *Table {
+	return fk.tbl
+}
+
+// Returns the referencing columns, in key order
+func (fk *ForeignKey) Columns() []*Column {
+	return fk.columns
+}
+
+// Returns the referenced Table
+func (fk *ForeignKey) ReferencedTable() *Table {
+	return fk.refTable
+}
+
+// Returns the referenced columns, in key order. The referenced column at
+// each position is referenced by the referencing column at the same position
+// in Columns().
+func (fk *ForeignKey) ReferencedColumns() []*Column {
+	return fk.refColumns
+}
+
+// Returns the action taken when a referenced row is deleted
+func (fk *ForeignKey) OnDelete() ReferentialAction {
+	return fk.onDelete
+}
+
+// Returns the action taken when a referenced row's key is updated
+func (fk *ForeignKey) OnUpdate() ReferentialAction {
+	return fk.onUpdate
+}
+
+// Sets the action taken when a referenced row is deleted
+func (fk *ForeignKey) SetOnDelete(action ReferentialAction) *ForeignKey {
+	fk.onDelete = action
+	return fk
+}
+
+// Sets the action taken when a referenced row's key is updated
+func (fk *ForeignKey) SetOnUpdate(action ReferentialAction) *ForeignKey {
+	fk.onUpdate = action
+	return fk
+}
+
+// An Index is a secondary index on one or more of a table's columns. The
+// index that implements a table's primary key is not included in a table's
+// indexes.
+type Index struct {
+	name    string
+	tbl     *Table
+	unique  bool
+	columns []*Column
+}
+
+// Returns the name of the index
+func (i *Index) Name() string {
+	return i.name
+}
+
+// Returns the Table the index belongs to
+func (i *Index) Table() *Table {
+	return i.tbl
+}
+
+// Returns whether the index enforces uniqueness of its columns' values
+func (i *Index) IsUnique() bool {
+	return i.unique
+}
+
+// Returns the indexed columns, in index order
+func (i *Index) Columns() []*Column {
+	return i.columns
+}
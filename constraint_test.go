@@ -0,0 +1,76 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferentialActionFromRule(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		rule string
+		exp  ReferentialAction
+	}{
+		{rule: "NO ACTION", exp: REFERENTIAL_ACTION_NO_ACTION},
+		{rule: "RESTRICT", exp: REFERENTIAL_ACTION_RESTRICT},
+		{rule: "CASCADE", exp: REFERENTIAL_ACTION_CASCADE},
+		{rule: "set null", exp: REFERENTIAL_ACTION_SET_NULL},
+		{rule: "SET DEFAULT", exp: REFERENTIAL_ACTION_SET_DEFAULT},
+		{rule: "BOGUS", exp: REFERENTIAL_ACTION_NO_ACTION},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, referentialActionFromRule(test.rule), test.rule)
+	}
+	assert.Equal("SET NULL", REFERENTIAL_ACTION_SET_NULL.String())
+}
+
+func TestTableConstraints(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	profiles := m.Table("user_profiles")
+
+	pk := users.NewPrimaryKey("PRIMARY", users.C("id"))
+	assert.Equal(CONSTRAINT_TYPE_PRIMARY_KEY, pk.Type())
+	assert.Equal(users, pk.Table())
+	assert.True(users.C("id").IsPrimaryKey())
+	assert.Equal([]*Column{users.C("id")}, users.PrimaryKey())
+
+	uc := users.NewUniqueConstraint("uc_name", users.C("name"))
+	assert.Equal(CONSTRAINT_TYPE_UNIQUE, uc.Type())
+	assert.Equal("uc_name", uc.Name())
+	assert.Equal([]*Constraint{pk, uc}, users.Constraints())
+
+	fk := profiles.NewForeignKey(
+		"fk_user",
+		[]*Column{profiles.C("user")},
+		[]*Column{users.C("id")},
+	).SetOnDelete(REFERENTIAL_ACTION_CASCADE)
+	assert.Equal("fk_user", fk.Name())
+	assert.Equal(profiles, fk.Table())
+	assert.Equal(users, fk.ReferencedTable())
+	assert.Equal([]*Column{profiles.C("user")}, fk.Columns())
+	assert.Equal([]*Column{users.C("id")}, fk.ReferencedColumns())
+	assert.Equal(REFERENTIAL_ACTION_CASCADE, fk.OnDelete())
+	assert.Equal(REFERENTIAL_ACTION_NO_ACTION, fk.OnUpdate())
+	assert.Equal([]*ForeignKey{fk}, profiles.ForeignKeys())
+
+	idx := profiles.NewIndex("ix_user", false, profiles.C("user"))
+	assert.Equal("ix_user", idx.Name())
+	assert.False(idx.IsUnique())
+	assert.Equal(profiles, idx.Table())
+	assert.Equal([]*Index{idx}, profiles.Indexes())
+
+	// Aliased tables share the original table's constraints
+	p := profiles.As("p")
+	assert.Equal(profiles.ForeignKeys(), p.ForeignKeys())
+	assert.Equal(profiles.Indexes(), p.Indexes())
+}
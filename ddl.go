@@ -0,0 +1,1504 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ERR_DDL_SYNTAX         = errors.New("Unable to parse DDL.")
+	ERR_DDL_UNKNOWN_TABLE  = errors.New("DDL refers to an unknown table.")
+	ERR_DDL_UNKNOWN_COLUMN = errors.New("DDL refers to an unknown column.")
+)
+
+// Reads SQL DDL statements from the supplied io.Reader and adds the tables,
+// columns, keys and indexes they describe to the supplied Meta. This allows a
+// Meta to be built from schema files or a migrations directory without
+// access to a database server.
+//
+// The following statements are understood, in both their MySQL and
+// PostgreSQL flavours:
+//
+// CREATE TABLE
+// CREATE [UNIQUE] INDEX
+// ALTER TABLE ... ADD [COLUMN | CONSTRAINT | INDEX | KEY] ...
+// ALTER TABLE ... DROP [COLUMN | CONSTRAINT | INDEX | KEY | FOREIGN KEY] ...
+// DROP TABLE
+// DROP INDEX
+//
+// All other statements, such as INSERT or CREATE FUNCTION, are skipped.
+// Statements are applied in order, so later statements may alter or drop
+// tables created by earlier ones. Foreign keys may reference tables that are
+// created later in the input.
+func LoadDDL(dialect Dialect, r io.Reader, meta *Meta) error {
+	if meta == nil {
+		return ERR_NO_META_STRUCT
+	}
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	l := newDDLLoader(dialect, meta)
+	if err = l.load(string(src)); err != nil {
+		return err
+	}
+	return l.resolve()
+}
+
+// Reads every file with a .sql extension in the supplied directory, in
+// lexical order of the file names, and loads the DDL statements they contain
+// into the supplied Meta. Migration tools conventionally prefix file names
+// with a sequence number or timestamp, so lexical order is the order the
+// migrations are applied in. See LoadDDL().
+func LoadDDLDir(dialect Dialect, dir string, meta *Meta) error {
+	if meta == nil {
+		return ERR_NO_META_STRUCT
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".sql") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	l := newDDLLoader(dialect, meta)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err = l.load(string(src)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return l.resolve()
+}
+
+type ddlTokenKind int
+
+const (
+	DDL_TOKEN_WORD ddlTokenKind = iota
+	DDL_TOKEN_QUOTED_IDENT
+	DDL_TOKEN_STRING
+	DDL_TOKEN_NUMBER
+	DDL_TOKEN_PUNCT
+)
+
+type ddlToken struct {
+	kind ddlTokenKind
+	// The token's value. For quoted identifiers and strings, the unquoted
+	// value.
+	text string
+	line int
+	// Offsets of the token in the source text
+	start int
+	end   int
+}
+
+// Splits DDL source text into tokens. Comments and whitespace are discarded.
+func lexDDL(dialect Dialect, src string) ([]ddlToken, error) {
+	toks := make([]ddlToken, 0, len(src)/4)
+	line := 1
+	x := 0
+	for x < len(src) {
+		c := src[x]
+		switch {
+		case c == '\n':
+			line++
+			x++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			x++
+		case c == '-' && x+1 < len(src) && src[x+1] == '-',
+			c == '#' && dialect == DIALECT_MYSQL:
+			for x < len(src) && src[x] != '\n' {
+				x++
+			}
+		case c == '/' && x+1 < len(src) && src[x+1] == '*':
+			end := strings.Index(src[x+2:], "*/")
+			if end < 0 {
+				return nil, ddlError(line, "unterminated comment")
+			}
+			line += strings.Count(src[x:x+2+end], "\n")
+			x += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			start := x
+			startLine := line
+			b := make([]byte, 0, 16)
+			x++
+			closed := false
+			for x < len(src) {
+				ch := src[x]
+				if ch == '\n' {
+					line++
+				}
+				if ch == '\\' && c == '\'' && dialect == DIALECT_MYSQL && x+1 < len(src) {
+					b = append(b, src[x+1])
+					x += 2
+					continue
+				}
+				if ch == c {
+					if x+1 < len(src) && src[x+1] == c {
+						b = append(b, c)
+						x += 2
+						continue
+					}
+					x++
+					closed = true
+					break
+				}
+				b = append(b, ch)
+				x++
+			}
+			if !closed {
+				return nil, ddlError(startLine, "unterminated quoted string")
+			}
+			kind := DDL_TOKEN_QUOTED_IDENT
+			if c == '\'' {
+				kind = DDL_TOKEN_STRING
+			}
+			toks = append(toks, ddlToken{kind, string(b), startLine, start, x})
+		case c == '$' && dialect == DIALECT_POSTGRESQL:
+			// PostgreSQL dollar-quoted string, e.g. $$ ... $$ or
+			// $body$ ... $body$, commonly used for function bodies
+			end := strings.IndexByte(src[x+1:], '$')
+			if end < 0 || !isDDLTag(src[x+1:x+1+end]) {
+				toks = append(toks, ddlToken{DDL_TOKEN_PUNCT, "$", line, x, x + 1})
+				x++
+				continue
+			}
+			tag := src[x : x+2+end]
+			body := strings.Index(src[x+len(tag):], tag)
+			if body < 0 {
+				return nil, ddlError(line, "unterminated dollar-quoted string")
+			}
+			start := x
+			startLine := line
+			text := src[x+len(tag) : x+len(tag)+body]
+			line += strings.Count(text, "\n")
+			x += len(tag)*2 + body
+			toks = append(toks, ddlToken{DDL_TOKEN_STRING, text, startLine, start, x})
+		case isDDLWordStart(c):
+			start := x
+			for x < len(src) && isDDLWordChar(src[x]) {
+				x++
+			}
+			toks = append(toks, ddlToken{DDL_TOKEN_WORD, src[start:x], line, start, x})
+		case c >= '0' && c <= '9':
+			start := x
+			for x < len(src) && (src[x] >= '0' && src[x] <= '9' || src[x] == '.') {
+				x++
+			}
+			toks = append(toks, ddlToken{DDL_TOKEN_NUMBER, src[start:x], line, start, x})
+		default:
+			toks = append(toks, ddlToken{DDL_TOKEN_PUNCT, src[x : x+1], line, x, x + 1})
+			x++
+		}
+	}
+	return toks, nil
+}
+
+func isDDLWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isDDLWordChar(c byte) bool {
+	return isDDLWordStart(c) || c >= '0' && c <= '9' || c == '$'
+}
+
+// Returns whether the supplied string is a valid dollar-quote tag, i.e. the
+// "body" in $body$. The empty tag of $$ is valid.
+func isDDLTag(s string) bool {
+	for x := 0; x < len(s); x++ {
+		if !isDDLWordChar(s[x]) || s[x] == '$' {
+			return false
+		}
+	}
+	return true
+}
+
+func ddlError(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w Line %d: %s", ERR_DDL_SYNTAX, line, fmt.Sprintf(format, args...))
+}
+
+// A pendingForeignKey is a foreign key whose referenced table may not have
+// been created yet. Foreign keys are resolved once all DDL has been loaded.
+type pendingForeignKey struct {
+	fk         *ForeignKey
+	refTable   string
+	refColumns []string
+	line       int
+}
+
+// A ddlLoader applies parsed DDL statements to a Meta
+type ddlLoader struct {
+	dialect Dialect
+	meta    *Meta
+	src     string
+	toks    []ddlToken
+	pos     int
+	pending []*pendingForeignKey
+}
+
+func newDDLLoader(dialect Dialect, meta *Meta) *ddlLoader {
+	if meta.tables == nil {
+		meta.tables = make(map[string]*Table, 0)
+	}
+	if meta.dialect == DIALECT_UNKNOWN {
+		meta.dialect = dialect
+	}
+	return &ddlLoader{dialect: dialect, meta: meta}
+}
+
+// Parses and applies all statements in the supplied source text
+func (l *ddlLoader) load(src string) error {
+	toks, err := lexDDL(l.dialect, src)
+	if err != nil {
+		return err
+	}
+	l.src = src
+	for len(toks) > 0 {
+		end := 0
+		for end < len(toks) && !(toks[end].kind == DDL_TOKEN_PUNCT && toks[end].text == ";") {
+			end++
+		}
+		if end > 0 {
+			l.toks = toks[:end]
+			l.pos = 0
+			if err = l.statement(); err != nil {
+				return err
+			}
+		}
+		if end < len(toks) {
+			end++
+		}
+		toks = toks[end:]
+	}
+	return nil
+}
+
+// Resolves the referenced tables and columns of all foreign keys
+func (l *ddlLoader) resolve() error {
+	for _, p := range l.pending {
+		if !p.fk.tbl.hasForeignKey(p.fk) {
+			// The foreign key was dropped
+			continue
+		}
+		refTable := l.meta.Table(p.refTable)
+		if refTable == nil {
+			return fmt.Errorf(
+				"%w Line %d: foreign key %s references table %s",
+				ERR_DDL_UNKNOWN_TABLE, p.line, p.fk.name, p.refTable,
+			)
+		}
+		refCols := p.refColumns
+		if len(refCols) == 0 {
+			// REFERENCES without a column list refers to the primary key
+			for _, c := range refTable.PrimaryKey() {
+				refCols = append(refCols, c.name)
+			}
+		}
+		cols, err := l.columns(refTable, refCols, p.line)
+		if err != nil {
+			return err
+		}
+		p.fk.refTable = refTable
+		p.fk.refColumns = cols
+	}
+	l.pending = nil
+	return nil
+}
+
+// Returns the current token, or a zero token if at the end of the statement
+func (l *ddlLoader) peek() ddlToken {
+	if l.pos < len(l.toks) {
+		return l.toks[l.pos]
+	}
+	return ddlToken{kind: DDL_TOKEN_PUNCT, line: l.line()}
+}
+
+func (l *ddlLoader) next() ddlToken {
+	t := l.peek()
+	if l.pos < len(l.toks) {
+		l.pos++
+	}
+	return t
+}
+
+func (l *ddlLoader) atEnd() bool {
+	return l.pos >= len(l.toks)
+}
+
+// Returns the line number of the current token
+func (l *ddlLoader) line() int {
+	if len(l.toks) == 0 {
+		return 0
+	}
+	if l.pos < len(l.toks) {
+		return l.toks[l.pos].line
+	}
+	return l.toks[len(l.toks)-1].line
+}
+
+// Returns whether the token at the supplied offset from the current token is
+// the supplied unquoted keyword
+func (l *ddlLoader) isKeywordAt(offset int, kw string) bool {
+	if l.pos+offset >= len(l.toks) {
+		return false
+	}
+	t := l.toks[l.pos+offset]
+	return t.kind == DDL_TOKEN_WORD && strings.EqualFold(t.text, kw)
+}
+
+// Consumes the supplied sequence of keywords if the upcoming tokens match it
+// and returns whether they did
+func (l *ddlLoader) acceptKeywords(kws ...string) bool {
+	for x, kw := range kws {
+		if !l.isKeywordAt(x, kw) {
+			return false
+		}
+	}
+	l.pos += len(kws)
+	return true
+}
+
+func (l *ddlLoader) expectKeywords(kws ...string) error {
+	if !l.acceptKeywords(kws...) {
+		return ddlError(l.line(), "expected %s near %q", strings.Join(kws, " "), l.peek().text)
+	}
+	return nil
+}
+
+func (l *ddlLoader) isPunct(p string) bool {
+	t := l.peek()
+	return !l.atEnd() && t.kind == DDL_TOKEN_PUNCT && t.text == p
+}
+
+func (l *ddlLoader) acceptPunct(p string) bool {
+	if l.isPunct(p) {
+		l.pos++
+		return true
+	}
+	return false
+}
+
+func (l *ddlLoader) expectPunct(p string) error {
+	if !l.acceptPunct(p) {
+		return ddlError(l.line(), "expected %q near %q", p, l.peek().text)
+	}
+	return nil
+}
+
+// Consumes tokens up to and including the parenthesis that closes the
+// parenthesis that was just consumed
+func (l *ddlLoader) skipParens() error {
+	depth := 1
+	for !l.atEnd() {
+		t := l.next()
+		if t.kind != DDL_TOKEN_PUNCT {
+			continue
+		}
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return ddlError(l.line(), "unbalanced parentheses")
+}
+
+// Consumes tokens until a comma or closing parenthesis that is not nested in
+// parentheses, without consuming the comma or parenthesis
+func (l *ddlLoader) skipToSeparator() error {
+	for !l.atEnd() && !l.isPunct(",") && !l.isPunct(")") {
+		if l.acceptPunct("(") {
+			if err := l.skipParens(); err != nil {
+				return err
+			}
+			continue
+		}
+		l.pos++
+	}
+	return nil
+}
+
+// Parses an identifier, which may be quoted
+func (l *ddlLoader) ident() (string, error) {
+	t := l.peek()
+	if l.atEnd() || (t.kind != DDL_TOKEN_WORD && t.kind != DDL_TOKEN_QUOTED_IDENT) {
+		return "", ddlError(t.line, "expected identifier near %q", t.text)
+	}
+	l.pos++
+	return t.text, nil
+}
+
+// Parses a possibly schema-qualified name and returns it in the form
+// accepted by Meta.Table()
+func (l *ddlLoader) qualifiedName() (string, error) {
+	name, err := l.ident()
+	if err != nil {
+		return "", err
+	}
+	for l.acceptPunct(".") {
+		part, err := l.ident()
+		if err != nil {
+			return "", err
+		}
+		name = name + "." + part
+	}
+	return name, nil
+}
+
+// Parses a parenthesized list of column names. Index key parts that are
+// expressions, along with any length, ordering and operator class modifiers,
+// are skipped.
+func (l *ddlLoader) columnList() ([]string, error) {
+	if err := l.expectPunct("("); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, 2)
+	for {
+		t := l.peek()
+		if t.kind == DDL_TOKEN_WORD || t.kind == DDL_TOKEN_QUOTED_IDENT {
+			l.pos++
+			// A word followed by a parenthesis is a function call in an
+			// expression index, unless it is a MySQL key part length, as in
+			// name(10)
+			if !l.isPunct("(") || l.isKeyPartLength() {
+				names = append(names, t.text)
+			}
+		}
+		if err := l.skipToSeparator(); err != nil {
+			return nil, err
+		}
+		if l.acceptPunct(")") {
+			return names, nil
+		}
+		if err := l.expectPunct(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Returns whether the upcoming tokens are a parenthesized number
+func (l *ddlLoader) isKeyPartLength() bool {
+	return l.pos+2 < len(l.toks) &&
+		l.toks[l.pos+1].kind == DDL_TOKEN_NUMBER &&
+		l.toks[l.pos+2].kind == DDL_TOKEN_PUNCT && l.toks[l.pos+2].text == ")"
+}
+
+// Returns the Columns of the supplied table with the supplied names
+func (l *ddlLoader) columns(t *Table, names []string, line int) ([]*Column, error) {
+	cols := make([]*Column, len(names))
+	for x, name := range names {
+		c := t.C(name)
+		if c == nil {
+			return nil, fmt.Errorf(
+				"%w Line %d: %s.%s", ERR_DDL_UNKNOWN_COLUMN, line, t.name, name,
+			)
+		}
+		cols[x] = c
+	}
+	return cols, nil
+}
+
+// Returns the table with the supplied name or an error if it is not known
+func (l *ddlLoader) table(name string, line int) (*Table, error) {
+	t := l.meta.Table(name)
+	if t == nil {
+		return nil, fmt.Errorf("%w Line %d: %s", ERR_DDL_UNKNOWN_TABLE, line, name)
+	}
+	return t, nil
+}
+
+// Parses and applies a single statement. Statements that do not affect
+// tables, columns, keys or indexes are skipped.
+func (l *ddlLoader) statement() error {
+	switch {
+	case l.acceptKeywords("CREATE"):
+		l.acceptKeywords("OR", "REPLACE")
+		l.acceptKeywords("GLOBAL")
+		l.acceptKeywords("LOCAL")
+		if l.acceptKeywords("TEMPORARY") || l.acceptKeywords("TEMP") {
+			// Temporary tables are not part of the schema
+			return nil
+		}
+		l.acceptKeywords("UNLOGGED")
+		switch {
+		case l.acceptKeywords("TABLE"):
+			return l.createTable()
+		case l.acceptKeywords("UNIQUE", "INDEX"):
+			return l.createIndex(true)
+		case l.acceptKeywords("INDEX"):
+			return l.createIndex(false)
+		}
+	case l.acceptKeywords("ALTER", "TABLE"):
+		return l.alterTable()
+	case l.acceptKeywords("DROP", "TABLE"):
+		return l.dropTable()
+	case l.acceptKeywords("DROP", "INDEX"):
+		return l.dropIndex()
+	}
+	return nil
+}
+
+// CREATE TABLE [IF NOT EXISTS] <name> (<column or constraint>, ...) ...
+func (l *ddlLoader) createTable() error {
+	ifNotExists := l.acceptKeywords("IF", "NOT", "EXISTS")
+	line := l.line()
+	name, err := l.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if ifNotExists && l.meta.Table(name) != nil {
+		return nil
+	}
+	if !l.isPunct("(") {
+		// CREATE TABLE ... AS SELECT and CREATE TABLE ... LIKE are not
+		// supported
+		return ddlError(line, "unsupported CREATE TABLE form for table %s", name)
+	}
+	l.pos++
+	if t := l.meta.Table(name); t != nil {
+		l.removeTable(t)
+	}
+	t := l.meta.NewTable(name)
+	for {
+		if err = l.tableElement(t); err != nil {
+			return err
+		}
+		if l.acceptPunct(")") {
+			break
+		}
+		if err = l.expectPunct(","); err != nil {
+			return err
+		}
+	}
+	// Table options such as ENGINE=InnoDB are ignored
+	return nil
+}
+
+// Parses a column definition or table constraint in CREATE TABLE or ALTER
+// TABLE ADD
+func (l *ddlLoader) tableElement(t *Table) error {
+	if l.isConstraintStart() {
+		return l.tableConstraint(t)
+	}
+	if l.isKeywordAt(0, "CHECK") || l.isKeywordAt(0, "LIKE") || l.isKeywordAt(0, "EXCLUDE") {
+		return l.skipToSeparator()
+	}
+	return l.columnDef(t)
+}
+
+// Returns whether the upcoming tokens start a table constraint or index
+// definition rather than a column definition
+func (l *ddlLoader) isConstraintStart() bool {
+	for _, kw := range []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "INDEX", "KEY", "FULLTEXT", "SPATIAL"} {
+		// These are reserved words, so a column with one of these names must
+		// be quoted and is lexed as a quoted identifier
+		if l.isKeywordAt(0, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// [CONSTRAINT <name>] PRIMARY KEY | UNIQUE | FOREIGN KEY | CHECK ...
+// INDEX | KEY | FULLTEXT | SPATIAL ...
+func (l *ddlLoader) tableConstraint(t *Table) error {
+	line := l.line()
+	var name string
+	var err error
+	if l.acceptKeywords("CONSTRAINT") {
+		if !l.isKeywordAt(0, "PRIMARY") && !l.isKeywordAt(0, "UNIQUE") &&
+			!l.isKeywordAt(0, "FOREIGN") && !l.isKeywordAt(0, "CHECK") {
+			if name, err = l.ident(); err != nil {
+				return err
+			}
+		}
+	}
+	switch {
+	case l.acceptKeywords("PRIMARY", "KEY"):
+		l.optionalIndexName()
+		cols, err := l.columnList()
+		if err != nil {
+			return err
+		}
+		if err = l.skipToSeparator(); err != nil {
+			return err
+		}
+		return l.addPrimaryKey(t, name, cols, line)
+	case l.acceptKeywords("UNIQUE"):
+		if !l.acceptKeywords("INDEX") {
+			l.acceptKeywords("KEY")
+		}
+		if idxName := l.optionalIndexName(); idxName != "" {
+			name = idxName
+		}
+		cols, err := l.columnList()
+		if err != nil {
+			return err
+		}
+		if err = l.skipToSeparator(); err != nil {
+			return err
+		}
+		return l.addUnique(t, name, cols, line)
+	case l.acceptKeywords("FOREIGN", "KEY"):
+		// In MySQL, FOREIGN KEY <name> names the index that MySQL creates on
+		// the referencing columns, not the constraint
+		idxName := l.optionalIndexName()
+		if l.dialect != DIALECT_MYSQL && name == "" {
+			name = idxName
+		}
+		cols, err := l.columnList()
+		if err != nil {
+			return err
+		}
+		if err = l.references(t, name, idxName, cols, line); err != nil {
+			return err
+		}
+		return l.skipToSeparator()
+	case l.acceptKeywords("CHECK"):
+		return l.skipToSeparator()
+	}
+	// MySQL inline index definitions
+	l.acceptKeywords("FULLTEXT")
+	l.acceptKeywords("SPATIAL")
+	if !l.acceptKeywords("INDEX") {
+		if err = l.expectKeywords("KEY"); err != nil {
+			return err
+		}
+	}
+	idxName := l.optionalIndexName()
+	cols, err := l.columnList()
+	if err != nil {
+		return err
+	}
+	if err = l.skipToSeparator(); err != nil {
+		return err
+	}
+	if idxName == "" {
+		idxName = l.defaultIndexName(t, cols)
+	}
+	idxCols, err := l.columns(t, cols, line)
+	if err != nil {
+		return err
+	}
+	t.NewIndex(idxName, false, idxCols...)
+	return nil
+}
+
+// Parses an optional index name and USING clause that precede an index's
+// column list. Returns "" if there is no name.
+func (l *ddlLoader) optionalIndexName() string {
+	var name string
+	if !l.isPunct("(") && !l.isKeywordAt(0, "USING") && !l.atEnd() {
+		name, _ = l.ident()
+	}
+	if l.acceptKeywords("USING") {
+		l.pos++
+	}
+	return name
+}
+
+// REFERENCES <table> [(<column>, ...)] [ON DELETE <action>] [ON UPDATE <action>] ...
+func (l *ddlLoader) references(t *Table, name string, idxName string, cols []string, line int) error {
+	if err := l.expectKeywords("REFERENCES"); err != nil {
+		return err
+	}
+	refTable, err := l.qualifiedName()
+	if err != nil {
+		return err
+	}
+	var refCols []string
+	if l.isPunct("(") {
+		if refCols, err = l.columnList(); err != nil {
+			return err
+		}
+	}
+	fkCols, err := l.columns(t, cols, line)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = l.defaultForeignKeyName(t, cols)
+	}
+	fk := t.NewForeignKey(name, fkCols, nil)
+	for !l.atEnd() && !l.isPunct(",") && !l.isPunct(")") {
+		if l.acceptKeywords("ON", "DELETE") {
+			fk.onDelete = l.referentialAction()
+		} else if l.acceptKeywords("ON", "UPDATE") {
+			fk.onUpdate = l.referentialAction()
+		} else if l.isColumnConstraintStart() {
+			// The start of the column's next constraint
+			break
+		} else {
+			// MATCH FULL, DEFERRABLE, INITIALLY DEFERRED and friends
+			l.pos++
+		}
+	}
+	l.addForeignKey(fk, idxName, line, refTable, refCols)
+	return nil
+}
+
+// Records the foreign key for resolution once all DDL has been loaded and,
+// for MySQL, which requires an index on the referencing columns, adds an
+// index on them named idxName, or the foreign key's name if idxName is "",
+// if the table has none
+func (l *ddlLoader) addForeignKey(fk *ForeignKey, idxName string, line int, refTable string, refCols []string) {
+	l.pending = append(l.pending, &pendingForeignKey{
+		fk:         fk,
+		refTable:   refTable,
+		refColumns: refCols,
+		line:       line,
+	})
+	if l.dialect != DIALECT_MYSQL {
+		return
+	}
+	for _, idx := range fk.tbl.indexes {
+		if hasLeadingColumns(idx.columns, fk.columns) {
+			return
+		}
+	}
+	if hasLeadingColumns(fk.tbl.PrimaryKey(), fk.columns) {
+		return
+	}
+	if idxName == "" {
+		idxName = fk.name
+	}
+	fk.tbl.NewIndex(idxName, false, fk.columns...)
+}
+
+// Returns whether the supplied columns are the leading columns of cols
+func hasLeadingColumns(cols []*Column, leading []*Column) bool {
+	if len(leading) > len(cols) {
+		return false
+	}
+	for x, c := range leading {
+		if cols[x] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses a referential action following ON DELETE or ON UPDATE
+func (l *ddlLoader) referentialAction() ReferentialAction {
+	switch {
+	case l.acceptKeywords("CASCADE"):
+		return REFERENTIAL_ACTION_CASCADE
+	case l.acceptKeywords("RESTRICT"):
+		return REFERENTIAL_ACTION_RESTRICT
+	case l.acceptKeywords("SET", "NULL"):
+		return REFERENTIAL_ACTION_SET_NULL
+	case l.acceptKeywords("SET", "DEFAULT"):
+		return REFERENTIAL_ACTION_SET_DEFAULT
+	}
+	l.acceptKeywords("NO", "ACTION")
+	return REFERENTIAL_ACTION_NO_ACTION
+}
+
+// Returns whether the upcoming token starts a column constraint
+func (l *ddlLoader) isColumnConstraintStart() bool {
+	for _, kw := range []string{
+		"NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "REFERENCES", "CHECK",
+		"CONSTRAINT", "AUTO_INCREMENT", "COMMENT", "COLLATE", "GENERATED",
+		"CHARACTER", "CHARSET", "KEY", "ON", "AS", "VISIBLE", "INVISIBLE",
+		"STORAGE", "COLUMN_FORMAT", "SERIAL", "IDENTITY",
+	} {
+		if l.isKeywordAt(0, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// <name> <type> [<column constraint> ...]
+func (l *ddlLoader) columnDef(t *Table) error {
+	line := l.line()
+	name, err := l.ident()
+	if err != nil {
+		return err
+	}
+	def, err := l.columnType()
+	if err != nil {
+		return err
+	}
+	setSerialDefaults(l.dialect, t, name, &def)
+	c := t.C(name)
+	if c == nil {
+		c = t.NewColumn(name)
+	}
+	for !l.atEnd() && !l.isPunct(",") && !l.isPunct(")") {
+		var conName string
+		if l.acceptKeywords("CONSTRAINT") {
+			if conName, err = l.ident(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case l.acceptKeywords("NOT", "NULL"):
+			def.nullable = false
+		case l.acceptKeywords("NULL"):
+			def.nullable = true
+		case l.acceptKeywords("DEFAULT"):
+			expr, err := l.defaultExpr()
+			if err != nil {
+				return err
+			}
+			def.defaultExpr = &expr
+		case l.acceptKeywords("AUTO_INCREMENT"):
+			def.autoIncrement = true
+		case l.acceptKeywords("GENERATED"):
+			// GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY [(...)] or a
+			// generated column's GENERATED ALWAYS AS (expr) [STORED]
+			for !l.atEnd() && !l.isKeywordAt(0, "AS") {
+				l.pos++
+			}
+			l.acceptKeywords("AS")
+			if l.acceptKeywords("IDENTITY") {
+				def.autoIncrement = true
+				def.nullable = false
+			}
+			if l.acceptPunct("(") {
+				if err = l.skipParens(); err != nil {
+					return err
+				}
+			}
+		case l.acceptKeywords("AS"):
+			// MySQL generated column: AS (expr) [VIRTUAL | STORED]
+			if l.acceptPunct("(") {
+				if err = l.skipParens(); err != nil {
+					return err
+				}
+			}
+		case l.acceptKeywords("PRIMARY", "KEY"):
+			c.def = def
+			if err = l.addPrimaryKey(t, conName, []string{name}, line); err != nil {
+				return err
+			}
+			def = c.def
+		case l.acceptKeywords("UNIQUE"):
+			l.acceptKeywords("KEY")
+			c.def = def
+			if err = l.addUnique(t, conName, []string{name}, line); err != nil {
+				return err
+			}
+		case l.isKeywordAt(0, "REFERENCES"):
+			c.def = def
+			if err = l.references(t, conName, "", []string{name}, line); err != nil {
+				return err
+			}
+		case l.acceptKeywords("CHECK"):
+			if err = l.expectPunct("("); err != nil {
+				return err
+			}
+			if err = l.skipParens(); err != nil {
+				return err
+			}
+		case l.acceptKeywords("ON", "UPDATE"):
+			// MySQL ON UPDATE CURRENT_TIMESTAMP[(n)]
+			l.pos++
+			if l.acceptPunct("(") {
+				if err = l.skipParens(); err != nil {
+					return err
+				}
+			}
+		case l.acceptKeywords("COMMENT"), l.acceptKeywords("COLLATE"),
+			l.acceptKeywords("CHARACTER", "SET"), l.acceptKeywords("CHARSET"),
+			l.acceptKeywords("COLUMN_FORMAT"), l.acceptKeywords("STORAGE"):
+			l.pos++
+		default:
+			// Unknown column attributes such as VISIBLE are skipped
+			l.pos++
+		}
+	}
+	c.def = def
+	return nil
+}
+
+// Parses a default expression and returns its source text. The expression
+// ends at the next column constraint or the end of the column definition.
+func (l *ddlLoader) defaultExpr() (string, error) {
+	if l.atEnd() {
+		return "", ddlError(l.line(), "expected default expression")
+	}
+	start := l.peek().start
+	end := start
+	for first := true; !l.atEnd() && !l.isPunct(",") && !l.isPunct(")"); first = false {
+		if !first && l.isColumnConstraintStart() {
+			break
+		}
+		t := l.next()
+		end = t.end
+		if t.kind == DDL_TOKEN_PUNCT && t.text == "(" {
+			if err := l.skipParens(); err != nil {
+				return "", err
+			}
+			end = l.toks[l.pos-1].end
+		}
+	}
+	return l.src[start:end], nil
+}
+
+// SERIAL columns are implicitly NOT NULL and, in PostgreSQL, default to the
+// next value of a sequence named after the table and column
+func setSerialDefaults(dialect Dialect, t *Table, column string, def *columnDef) {
+	def.nullable = !def.autoIncrement
+	if def.autoIncrement && dialect == DIALECT_POSTGRESQL {
+		seq := "nextval('" + t.name + "_" + column + "_seq'::regclass)"
+		def.defaultExpr = &seq
+	}
+}
+
+// Parses the supplied data type, as it would be written in a column
+// definition, and returns a columnDef describing it
+func parseColumnType(dialect Dialect, dataType string) (columnDef, error) {
+	toks, err := lexDDL(dialect, dataType)
+	if err != nil {
+		return columnDef{}, err
+	}
+	l := &ddlLoader{dialect: dialect, src: dataType, toks: toks}
+	def, err := l.columnType()
+	if err != nil {
+		return def, err
+	}
+	if !l.atEnd() {
+		return def, ddlError(l.line(), "unexpected %q after data type", l.peek().text)
+	}
+	return def, nil
+}
+
+// Parses a column's data type, including any length, precision and scale
+// and type modifiers, and returns a columnDef describing it
+func (l *ddlLoader) columnType() (columnDef, error) {
+	def := columnDef{}
+	t := l.peek()
+	if l.atEnd() || (t.kind != DDL_TOKEN_WORD && t.kind != DDL_TOKEN_QUOTED_IDENT) {
+		return def, ddlError(t.line, "expected data type near %q", t.text)
+	}
+	l.pos++
+	base := strings.ToLower(t.text)
+	// Schema-qualified user-defined types
+	for l.acceptPunct(".") {
+		part, err := l.ident()
+		if err != nil {
+			return def, err
+		}
+		base = base + "." + strings.ToLower(part)
+	}
+	switch base {
+	case "double":
+		if l.acceptKeywords("PRECISION") {
+			base = "double precision"
+		}
+	case "character", "char", "bit":
+		if l.acceptKeywords("VARYING") {
+			base = base + " varying"
+		}
+	case "national":
+		l.acceptKeywords("CHARACTER")
+		l.acceptKeywords("CHAR")
+		base = "char"
+		if l.acceptKeywords("VARYING") {
+			base = "varchar"
+		}
+	}
+	args := make([]string, 0, 2)
+	if l.acceptPunct("(") {
+		for !l.atEnd() && !l.isPunct(")") {
+			a := l.next()
+			switch a.kind {
+			case DDL_TOKEN_NUMBER:
+				args = append(args, a.text)
+			case DDL_TOKEN_STRING:
+				args = append(args, "'"+strings.Replace(a.text, "'", "''", -1)+"'")
+			case DDL_TOKEN_WORD:
+				args = append(args, strings.ToLower(a.text))
+			}
+		}
+		if err := l.expectPunct(")"); err != nil {
+			return def, err
+		}
+	}
+	switch base {
+	case "time", "timestamp":
+		if l.acceptKeywords("WITH", "TIME", "ZONE") {
+			base = base + " with time zone"
+		} else if l.acceptKeywords("WITHOUT", "TIME", "ZONE") {
+			base = base + " without time zone"
+		}
+	}
+	modifiers := make([]string, 0, 1)
+	for {
+		switch {
+		case l.acceptKeywords("UNSIGNED"):
+			modifiers = append(modifiers, "unsigned")
+			continue
+		case l.acceptKeywords("SIGNED"):
+			continue
+		case l.acceptKeywords("ZEROFILL"):
+			modifiers = append(modifiers, "zerofill")
+			continue
+		case l.isPunct("["):
+			// PostgreSQL array types
+			l.pos++
+			for !l.atEnd() && !l.isPunct("]") {
+				l.pos++
+			}
+			l.acceptPunct("]")
+			base = base + "[]"
+			continue
+		}
+		break
+	}
+	def.rawType = canonicalRawType(l.dialect, base, args, modifiers)
+	// The SqlType is determined from the data type name the database server
+	// reports, as it is when reflecting, so that e.g. a MySQL BOOLEAN column,
+	// which is stored as tinyint(1), is SQL_TYPE_SMALLINT
+	dataType := def.rawType
+	if l.dialect == DIALECT_MYSQL {
+		if x := strings.IndexAny(dataType, "( "); x >= 0 {
+			dataType = dataType[:x]
+		}
+	}
+	def.sqlType = sqlTypeFromRaw(dataType)
+	switch def.sqlType {
+	case SQL_TYPE_CHAR, SQL_TYPE_VARCHAR, SQL_TYPE_BINARY:
+		if len(args) > 0 {
+			def.length, _ = strconv.Atoi(args[0])
+		} else if def.sqlType == SQL_TYPE_CHAR {
+			def.length = 1
+		}
+	case SQL_TYPE_DECIMAL:
+		if len(args) > 0 {
+			def.precision, _ = strconv.Atoi(args[0])
+		} else if l.dialect == DIALECT_MYSQL {
+			def.precision = 10
+		}
+		if len(args) > 1 {
+			def.scale, _ = strconv.Atoi(args[1])
+		}
+	}
+	switch base {
+	case "serial", "bigserial", "smallserial", "serial4", "serial8", "serial2":
+		def.autoIncrement = true
+	}
+	return def, nil
+}
+
+var (
+	// Maps data type names and aliases to the name PostgreSQL reports in
+	// INFORMATION_SCHEMA.COLUMNS.DATA_TYPE
+	postgresqlTypeNames = map[string]string{
+		"int":          "integer",
+		"int4":         "integer",
+		"integer":      "integer",
+		"serial":       "integer",
+		"serial4":      "integer",
+		"int2":         "smallint",
+		"smallserial":  "smallint",
+		"serial2":      "smallint",
+		"int8":         "bigint",
+		"bigserial":    "bigint",
+		"serial8":      "bigint",
+		"varchar":      "character varying",
+		"char":         "character",
+		"bpchar":       "character",
+		"bool":         "boolean",
+		"float8":       "double precision",
+		"float":        "double precision",
+		"float4":       "real",
+		"decimal":      "numeric",
+		"timestamp":    "timestamp without time zone",
+		"timestamptz":  "timestamp with time zone",
+		"time":         "time without time zone",
+		"timetz":       "time with time zone",
+		"varbit":       "bit varying",
+		"char varying": "character varying",
+	}
+	// Maps PostgreSQL data type names to the names of their internal types
+	postgresqlInternalTypeNames = map[string]string{
+		"integer":                     "int4",
+		"smallint":                    "int2",
+		"bigint":                      "int8",
+		"character varying":           "varchar",
+		"character":                   "bpchar",
+		"boolean":                     "bool",
+		"double precision":            "float8",
+		"real":                        "float4",
+		"timestamp without time zone": "timestamp",
+		"timestamp with time zone":    "timestamptz",
+		"time without time zone":      "time",
+		"time with time zone":         "timetz",
+		"bit varying":                 "varbit",
+	}
+	// Maps data type aliases to the name MySQL reports in
+	// INFORMATION_SCHEMA.COLUMNS.COLUMN_TYPE
+	mysqlTypeNames = map[string]string{
+		"integer":           "int",
+		"bool":              "tinyint",
+		"boolean":           "tinyint",
+		"dec":               "decimal",
+		"numeric":           "decimal",
+		"fixed":             "decimal",
+		"double precision":  "double",
+		"real":              "double",
+		"character":         "char",
+		"character varying": "varchar",
+		"char varying":      "varchar",
+	}
+)
+
+// Returns the data type in the form the database server reports it when the
+// schema is reflected, so that a Meta loaded from DDL can be compared with a
+// reflected Meta. For MySQL, this is COLUMN_TYPE, e.g. "varchar(100)" or
+// "int unsigned". For PostgreSQL, this is DATA_TYPE, e.g. "character varying".
+func canonicalRawType(dialect Dialect, base string, args []string, modifiers []string) string {
+	switch dialect {
+	case DIALECT_POSTGRESQL:
+		array := strings.HasSuffix(base, "[]")
+		base = strings.TrimSuffix(base, "[]")
+		if name, found := postgresqlTypeNames[base]; found {
+			base = name
+		}
+		if array {
+			// PostgreSQL reports array types by the name of their internal
+			// type, which is the element type's internal name prefixed with
+			// an underscore, e.g. _int4 for integer[]
+			if udt, found := postgresqlInternalTypeNames[base]; found {
+				return "_" + udt
+			}
+			return "_" + base
+		}
+		return base
+	case DIALECT_MYSQL:
+		if base == "serial" {
+			// An alias for BIGINT UNSIGNED NOT NULL AUTO_INCREMENT UNIQUE
+			base = "bigint"
+			modifiers = []string{"unsigned"}
+		}
+		if name, found := mysqlTypeNames[base]; found {
+			if base == "bool" || base == "boolean" {
+				args = []string{"1"}
+			}
+			base = name
+		}
+		switch base {
+		case "int", "bigint", "mediumint", "smallint":
+			// MySQL 8 no longer reports the display width of integer types
+			args = nil
+		case "tinyint":
+			if len(args) > 0 && args[0] != "1" {
+				args = nil
+			}
+		case "decimal":
+			if len(args) == 0 {
+				args = []string{"10", "0"}
+			} else if len(args) == 1 {
+				args = append(args, "0")
+			}
+		}
+		res := base
+		if len(args) > 0 {
+			res += "(" + strings.Join(args, ",") + ")"
+		}
+		for _, m := range modifiers {
+			res += " " + m
+		}
+		return res
+	}
+	return base
+}
+
+// Marks the supplied columns as the table's primary key
+func (l *ddlLoader) addPrimaryKey(t *Table, name string, cols []string, line int) error {
+	pkCols, err := l.columns(t, cols, line)
+	if err != nil {
+		return err
+	}
+	if name == "" || l.dialect == DIALECT_MYSQL {
+		// MySQL always names the primary key PRIMARY
+		name = l.defaultPrimaryKeyName(t)
+	}
+	for _, c := range pkCols {
+		c.def.nullable = false
+	}
+	t.NewPrimaryKey(name, pkCols...)
+	return nil
+}
+
+// Adds a UNIQUE constraint. The unique index that implements it isn't added
+// to the table's indexes, as reflection doesn't list it separately.
+func (l *ddlLoader) addUnique(t *Table, name string, cols []string, line int) error {
+	ucCols, err := l.columns(t, cols, line)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = l.defaultUniqueName(t, cols)
+	}
+	t.NewUniqueConstraint(name, ucCols...)
+	return nil
+}
+
+func (l *ddlLoader) defaultPrimaryKeyName(t *Table) string {
+	if l.dialect == DIALECT_MYSQL {
+		return "PRIMARY"
+	}
+	return t.name + "_pkey"
+}
+
+func (l *ddlLoader) defaultUniqueName(t *Table, cols []string) string {
+	if l.dialect == DIALECT_MYSQL {
+		return l.defaultIndexName(t, cols)
+	}
+	return t.name + "_" + strings.Join(cols, "_") + "_key"
+}
+
+func (l *ddlLoader) defaultIndexName(t *Table, cols []string) string {
+	if l.dialect == DIALECT_MYSQL {
+		// MySQL names an unnamed index after its first column, adding a
+		// numeric suffix if the name is taken
+		name := cols[0]
+		for x := 2; t.index(name) != nil || t.uniqueConstraint(name) != nil; x++ {
+			name = cols[0] + "_" + strconv.Itoa(x)
+		}
+		return name
+	}
+	return t.name + "_" + strings.Join(cols, "_") + "_idx"
+}
+
+func (l *ddlLoader) defaultForeignKeyName(t *Table, cols []string) string {
+	if l.dialect == DIALECT_MYSQL {
+		return t.name + "_ibfk_" + strconv.Itoa(len(t.foreignKeys)+1)
+	}
+	return t.name + "_" + strings.Join(cols, "_") + "_fkey"
+}
+
+// CREATE [UNIQUE] INDEX [CONCURRENTLY] [IF NOT EXISTS] [<name>] ON <table>
+// [USING <method>] (<column>, ...) ...
+func (l *ddlLoader) createIndex(unique bool) error {
+	line := l.line()
+	l.acceptKeywords("CONCURRENTLY")
+	l.acceptKeywords("IF", "NOT", "EXISTS")
+	var name string
+	var err error
+	if !l.isKeywordAt(0, "ON") {
+		if name, err = l.ident(); err != nil {
+			return err
+		}
+	}
+	if l.acceptKeywords("USING") {
+		l.pos++
+	}
+	if err = l.expectKeywords("ON"); err != nil {
+		return err
+	}
+	l.acceptKeywords("ONLY")
+	tname, err := l.qualifiedName()
+	if err != nil {
+		return err
+	}
+	t, err := l.table(tname, line)
+	if err != nil {
+		return err
+	}
+	if l.acceptKeywords("USING") {
+		l.pos++
+	}
+	cols, err := l.columnList()
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		// Indexes on expressions only are not represented
+		return nil
+	}
+	if unique && l.dialect == DIALECT_MYSQL {
+		// MySQL reports every unique index as a UNIQUE constraint
+		return l.addUnique(t, name, cols, line)
+	}
+	idxCols, err := l.columns(t, cols, line)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = l.defaultIndexName(t, cols)
+	}
+	t.NewIndex(name, unique, idxCols...)
+	return nil
+}
+
+// ALTER TABLE [ONLY] [IF EXISTS] <name> <action>, ...
+func (l *ddlLoader) alterTable() error {
+	l.acceptKeywords("IF", "EXISTS")
+	l.acceptKeywords("ONLY")
+	line := l.line()
+	tname, err := l.qualifiedName()
+	if err != nil {
+		return err
+	}
+	t, err := l.table(tname, line)
+	if err != nil {
+		return err
+	}
+	for !l.atEnd() {
+		switch {
+		case l.acceptKeywords("ADD"):
+			l.acceptKeywords("COLUMN")
+			l.acceptKeywords("IF", "NOT", "EXISTS")
+			if err = l.tableElement(t); err != nil {
+				return err
+			}
+		case l.acceptKeywords("DROP"):
+			if err = l.alterTableDrop(t); err != nil {
+				return err
+			}
+		}
+		// Skip the rest of the action, including actions that are not
+		// understood, such as ALTER COLUMN or RENAME
+		if err = l.skipToSeparator(); err != nil {
+			return err
+		}
+		if l.isPunct(")") {
+			return ddlError(l.line(), "unbalanced parentheses")
+		}
+		l.acceptPunct(",")
+	}
+	return nil
+}
+
+// ALTER TABLE ... DROP [COLUMN | CONSTRAINT | INDEX | KEY | FOREIGN KEY |
+// PRIMARY KEY] [IF EXISTS] <name>
+func (l *ddlLoader) alterTableDrop(t *Table) error {
+	if l.acceptKeywords("PRIMARY", "KEY") {
+		t.dropConstraint(l.defaultPrimaryKeyName(t))
+		return nil
+	}
+	kind := "COLUMN"
+	for _, kw := range []string{"COLUMN", "CONSTRAINT", "INDEX", "KEY", "CHECK"} {
+		if l.acceptKeywords(kw) {
+			kind = kw
+			break
+		}
+	}
+	if l.acceptKeywords("FOREIGN", "KEY") {
+		kind = "CONSTRAINT"
+	}
+	l.acceptKeywords("IF", "EXISTS")
+	name, err := l.ident()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "COLUMN":
+		t.dropColumn(name)
+	case "CONSTRAINT":
+		t.dropConstraint(name)
+	case "INDEX", "KEY":
+		dropIndex(t, name)
+	}
+	return nil
+}
+
+// DROP TABLE [IF EXISTS] <name>, ... [CASCADE | RESTRICT]
+func (l *ddlLoader) dropTable() error {
+	l.acceptKeywords("IF", "EXISTS")
+	for {
+		name, err := l.qualifiedName()
+		if err != nil {
+			return err
+		}
+		if t := l.meta.Table(name); t != nil {
+			l.removeTable(t)
+		}
+		if !l.acceptPunct(",") {
+			return nil
+		}
+	}
+}
+
+// DROP INDEX [CONCURRENTLY] [IF EXISTS] <name> [ON <table>] ...
+func (l *ddlLoader) dropIndex() error {
+	l.acceptKeywords("CONCURRENTLY")
+	l.acceptKeywords("IF", "EXISTS")
+	name, err := l.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if l.acceptKeywords("ON") {
+		// MySQL requires the table the index belongs to
+		tname, err := l.qualifiedName()
+		if err != nil {
+			return err
+		}
+		if t := l.meta.Table(tname); t != nil {
+			dropIndex(t, name)
+		}
+		return nil
+	}
+	// PostgreSQL index names are unique within a schema
+	schema, iname := splitTableName(name)
+	for _, t := range l.meta.Tables() {
+		if schema != "" && l.meta.tableKey(schema, "") != l.meta.tableKey(t.schema, "") {
+			continue
+		}
+		if t.index(iname) != nil {
+			dropIndex(t, iname)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Removes the index with the supplied name from the supplied table. In MySQL,
+// a UNIQUE constraint is its unique index, so dropping the index drops the
+// constraint.
+func dropIndex(t *Table, name string) {
+	if t.uniqueConstraint(name) != nil {
+		t.dropConstraint(name)
+	}
+	t.dropIndex(name)
+}
+
+// Removes the supplied table from the Meta along with the foreign keys of
+// other tables that reference it, as DROP TABLE ... CASCADE does
+func (l *ddlLoader) removeTable(t *Table) {
+	for _, other := range l.meta.tables {
+		fks := other.foreignKeys[:0]
+		for _, fk := range other.foreignKeys {
+			if fk.refTable != t {
+				fks = append(fks, fk)
+			}
+		}
+		other.foreignKeys = fks
+	}
+	pending := l.pending[:0]
+	for _, p := range l.pending {
+		if p.fk.tbl != t && l.meta.Table(p.refTable) != t {
+			pending = append(pending, p)
+		}
+	}
+	l.pending = pending
+	delete(l.meta.tables, l.meta.tableKey(t.schema, t.name))
+}
@@ -0,0 +1,352 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	_MYSQL_DDL = `
+-- The schema from the README
+CREATE TABLE users (
+  id INT NOT NULL,
+  email VARCHAR(100) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  is_author CHAR(1) NOT NULL,
+  profile TEXT NULL,
+  created_on DATETIME NOT NULL,
+  updated_on DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE INDEX (email)
+);
+
+# MySQL-style comment
+CREATE TABLE articles (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  title VARCHAR(200) NOT NULL DEFAULT '',
+  content TEXT NOT NULL,
+  created_by INT NOT NULL,
+  price DECIMAL(10,2) NULL,
+  published_on DATETIME NULL ON UPDATE CURRENT_TIMESTAMP,
+  ` + "`key`" + ` BOOLEAN NOT NULL DEFAULT 0 COMMENT 'a reserved word',
+  PRIMARY KEY (id),
+  INDEX ix_title (title(10)),
+  FOREIGN KEY fk_users (created_by) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO users VALUES (1, 'a@example.com', 'a', 'Y', NULL, NOW(), NOW());
+`
+	_POSTGRESQL_DDL = `
+CREATE TABLE IF NOT EXISTS users (
+  id SERIAL PRIMARY KEY,
+  email character varying(100) NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  created_on TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  tags TEXT[]
+);
+
+CREATE SCHEMA billing;
+
+CREATE TABLE billing.invoices (
+  id BIGINT GENERATED ALWAYS AS IDENTITY,
+  user_id INT NOT NULL,
+  amount NUMERIC(12, 2) NOT NULL CHECK (amount >= 0),
+  CONSTRAINT invoices_pk PRIMARY KEY (id)
+);
+
+ALTER TABLE ONLY billing.invoices
+  ADD CONSTRAINT invoices_user_fk FOREIGN KEY (user_id)
+    REFERENCES users (id) ON DELETE SET NULL ON UPDATE CASCADE;
+
+CREATE UNIQUE INDEX CONCURRENTLY invoices_user_amount
+  ON billing.invoices USING btree (user_id, amount DESC);
+
+CREATE INDEX ON users (lower(email));
+
+CREATE FUNCTION noop() RETURNS trigger AS $$
+BEGIN
+  -- a semicolon; inside a function body
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+`
+)
+
+func TestLoadDDLMySQL(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_MYSQL, "test")
+	err := LoadDDL(DIALECT_MYSQL, strings.NewReader(_MYSQL_DDL), m)
+	assert.Nil(err)
+	assert.Equal(2, len(m.Tables()))
+
+	users := m.Table("users")
+	assert.NotNil(users)
+	assert.Equal(7, len(users.Columns()))
+	email := users.C("email")
+	assert.Equal(SQL_TYPE_VARCHAR, email.Type())
+	assert.Equal("varchar(100)", email.RawType())
+	assert.Equal(100, email.Length())
+	assert.False(email.IsNullable())
+	assert.Equal(1, users.C("is_author").Length())
+	assert.True(users.C("profile").IsNullable())
+	assert.Equal(SQL_TYPE_TIMESTAMP, users.C("created_on").Type())
+	assert.Equal([]*Column{users.C("id")}, users.PrimaryKey())
+	assert.Equal("PRIMARY", users.Constraints()[0].Name())
+	// The unique index implementing a UNIQUE constraint isn't listed
+	// separately, as in a reflected Meta
+	assert.Equal(0, len(users.Indexes()))
+	assert.Equal("email", users.Constraints()[1].Name())
+	assert.Equal(CONSTRAINT_TYPE_UNIQUE, users.Constraints()[1].Type())
+
+	articles := m.Table("articles")
+	id := articles.C("id")
+	assert.Equal("int unsigned", id.RawType())
+	assert.True(id.IsAutoIncrement())
+	assert.True(id.IsPrimaryKey())
+	title := articles.C("title")
+	def, found := title.Default()
+	assert.True(found)
+	assert.Equal("''", def)
+	price := articles.C("price")
+	assert.Equal(SQL_TYPE_DECIMAL, price.Type())
+	assert.Equal("decimal(10,2)", price.RawType())
+	assert.Equal(10, price.Precision())
+	assert.Equal(2, price.Scale())
+	key := articles.C("key")
+	assert.NotNil(key)
+	assert.Equal("tinyint(1)", key.RawType())
+	def, _ = key.Default()
+	assert.Equal("0", def)
+
+	fks := articles.ForeignKeys()
+	assert.Equal(1, len(fks))
+	assert.Equal("articles_ibfk_1", fks[0].Name())
+	assert.Equal(users, fks[0].ReferencedTable())
+	assert.Equal([]*Column{users.C("id")}, fks[0].ReferencedColumns())
+	assert.Equal(REFERENTIAL_ACTION_CASCADE, fks[0].OnDelete())
+
+	idxNames := []string{}
+	for _, idx := range articles.Indexes() {
+		idxNames = append(idxNames, idx.Name())
+	}
+	assert.Equal([]string{"ix_title", "fk_users"}, idxNames)
+}
+
+func TestLoadDDLMySQLSerial(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_MYSQL, "test")
+	err := LoadDDL(DIALECT_MYSQL, strings.NewReader("CREATE TABLE t (id SERIAL);"), m)
+	assert.Nil(err)
+
+	// SERIAL is an alias for an auto-incrementing BIGINT UNSIGNED and has no
+	// sequence default as in PostgreSQL
+	id := m.Table("t").C("id")
+	assert.Equal("bigint unsigned", id.RawType())
+	assert.True(id.IsAutoIncrement())
+	assert.False(id.IsNullable())
+	_, found := id.Default()
+	assert.False(found)
+}
+
+func TestLoadDDLPostgreSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	err := LoadDDL(DIALECT_POSTGRESQL, strings.NewReader(_POSTGRESQL_DDL), m)
+	assert.Nil(err)
+	assert.Equal(2, len(m.Tables()))
+
+	users := m.Table("users")
+	id := users.C("id")
+	assert.Equal("integer", id.RawType())
+	assert.True(id.IsAutoIncrement())
+	assert.False(id.IsNullable())
+	assert.Equal("users_pkey", users.Constraints()[0].Name())
+	assert.Equal("character varying", users.C("email").RawType())
+	assert.Equal(100, users.C("email").Length())
+	assert.Equal("timestamp with time zone", users.C("created_on").RawType())
+	def, found := users.C("created_on").Default()
+	assert.True(found)
+	assert.Equal("now()", def)
+	assert.Equal(SQL_TYPE_UNKNOWN, users.C("tags").Type())
+	assert.Equal("users_email_key", users.Constraints()[1].Name())
+	// Neither the index implementing the UNIQUE constraint nor the
+	// expression index on lower(email) is represented
+	assert.Equal(0, len(users.Indexes()))
+
+	invoices := m.Table("billing.invoices")
+	assert.NotNil(invoices)
+	assert.Equal("billing", invoices.Schema())
+	assert.True(invoices.C("id").IsAutoIncrement())
+	assert.Equal("bigint", invoices.C("id").RawType())
+	assert.Equal("numeric", invoices.C("amount").RawType())
+	assert.Equal(12, invoices.C("amount").Precision())
+	assert.Equal("invoices_pk", invoices.Constraints()[0].Name())
+	assert.Equal(1, len(invoices.ForeignKeys()))
+	fk := invoices.ForeignKeys()[0]
+	assert.Equal("invoices_user_fk", fk.Name())
+	assert.Equal(users, fk.ReferencedTable())
+	assert.Equal(REFERENTIAL_ACTION_SET_NULL, fk.OnDelete())
+	assert.Equal(REFERENTIAL_ACTION_CASCADE, fk.OnUpdate())
+	assert.Equal(1, len(invoices.Indexes()))
+	idx := invoices.Indexes()[0]
+	assert.True(idx.IsUnique())
+	assert.Equal(
+		[]*Column{invoices.C("user_id"), invoices.C("amount")},
+		idx.Columns(),
+	)
+}
+
+func TestLoadDDLAlterAndDrop(t *testing.T) {
+	assert := assert.New(t)
+
+	ddl := `
+CREATE TABLE authors (id INT PRIMARY KEY);
+CREATE TABLE posts (
+  id INT PRIMARY KEY,
+  author_id INT REFERENCES authors,
+  slug TEXT,
+  legacy TEXT
+);
+ALTER TABLE posts ADD COLUMN body TEXT NOT NULL, DROP COLUMN legacy;
+ALTER TABLE posts ADD CONSTRAINT posts_slug_key UNIQUE (slug);
+CREATE INDEX posts_body_idx ON posts (body);
+DROP INDEX posts_body_idx;
+ALTER TABLE posts DROP CONSTRAINT posts_slug_key;
+CREATE TABLE scratch (id INT);
+DROP TABLE IF EXISTS scratch, missing;
+`
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	err := LoadDDL(DIALECT_POSTGRESQL, strings.NewReader(ddl), m)
+	assert.Nil(err)
+	assert.Nil(m.Table("scratch"))
+
+	posts := m.Table("posts")
+	names := []string{}
+	for _, c := range posts.Columns() {
+		names = append(names, c.Name())
+	}
+	assert.Equal([]string{"id", "author_id", "slug", "body"}, names)
+	assert.Equal(1, len(posts.Constraints()))
+	assert.Equal(0, len(posts.Indexes()))
+
+	// REFERENCES without a column list refers to the primary key
+	fk := posts.ForeignKeys()[0]
+	assert.Equal("posts_author_id_fkey", fk.Name())
+	assert.Equal([]*Column{m.Table("authors").C("id")}, fk.ReferencedColumns())
+
+	// Dropping a referenced table drops the foreign keys referencing it
+	err = LoadDDL(DIALECT_POSTGRESQL, strings.NewReader("DROP TABLE authors CASCADE"), m)
+	assert.Nil(err)
+	assert.Equal(0, len(posts.ForeignKeys()))
+}
+
+func TestLoadDDLForwardReference(t *testing.T) {
+	assert := assert.New(t)
+
+	ddl := `
+CREATE TABLE b (id INT PRIMARY KEY, a_id INT REFERENCES a (id));
+CREATE TABLE a (id INT PRIMARY KEY);
+`
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	err := LoadDDL(DIALECT_POSTGRESQL, strings.NewReader(ddl), m)
+	assert.Nil(err)
+	assert.Equal(m.Table("a"), m.Table("b").ForeignKeys()[0].ReferencedTable())
+}
+
+func TestLoadDDLErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		ddl  string
+		exp  error
+	}{
+		{
+			name: "Unterminated string",
+			ddl:  "CREATE TABLE t (c TEXT DEFAULT 'oops);",
+			exp:  ERR_DDL_SYNTAX,
+		},
+		{
+			name: "Missing data type",
+			ddl:  "CREATE TABLE t (c);",
+			exp:  ERR_DDL_SYNTAX,
+		},
+		{
+			name: "Unbalanced parentheses",
+			ddl:  "CREATE TABLE t (c NUMERIC(10, 2) CHECK (c > 0);",
+			exp:  ERR_DDL_SYNTAX,
+		},
+		{
+			name: "Index on unknown table",
+			ddl:  "CREATE INDEX ix ON nope (c);",
+			exp:  ERR_DDL_UNKNOWN_TABLE,
+		},
+		{
+			name: "Primary key on unknown column",
+			ddl:  "CREATE TABLE t (c INT, PRIMARY KEY (d));",
+			exp:  ERR_DDL_UNKNOWN_COLUMN,
+		},
+		{
+			name: "Foreign key to unknown table",
+			ddl:  "CREATE TABLE t (c INT REFERENCES nope (id));",
+			exp:  ERR_DDL_UNKNOWN_TABLE,
+		},
+	}
+	for _, test := range tests {
+		m := NewMeta(DIALECT_POSTGRESQL, "test")
+		err := LoadDDL(DIALECT_POSTGRESQL, strings.NewReader(test.ddl), m)
+		assert.True(errors.Is(err, test.exp), "%s: %v", test.name, err)
+	}
+
+	err := LoadDDL(DIALECT_MYSQL, strings.NewReader(""), nil)
+	assert.Equal(ERR_NO_META_STRUCT, err)
+
+	err = LoadDDL(DIALECT_MYSQL, strings.NewReader("CREATE TABLE t (\n  c INT,\n  ?\n)"), NewMeta(DIALECT_MYSQL, "test"))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "Line 3")
+}
+
+func TestLoadDDLDir(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "sqlb-ddl")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"002_articles.sql": "CREATE TABLE articles (id INT PRIMARY KEY, user_id INT REFERENCES users (id));",
+		"001_users.sql":    "CREATE TABLE users (id INT PRIMARY KEY, legacy TEXT);",
+		"003_drop.sql":     "ALTER TABLE users DROP COLUMN legacy;",
+		"README.md":        "not SQL",
+	}
+	for name, content := range files {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		assert.Nil(err)
+	}
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	err = LoadDDLDir(DIALECT_POSTGRESQL, dir, m)
+	assert.Nil(err)
+	assert.Equal(2, len(m.Tables()))
+	assert.Equal(1, len(m.Table("users").Columns()))
+	assert.Equal(m.Table("users"), m.Table("articles").ForeignKeys()[0].ReferencedTable())
+
+	err = os.WriteFile(filepath.Join(dir, "004_bad.sql"), []byte("CREATE TABLE x ("), 0644)
+	assert.Nil(err)
+	err = LoadDDLDir(DIALECT_POSTGRESQL, dir, NewMeta(DIALECT_POSTGRESQL, "test"))
+	assert.True(errors.Is(err, ERR_DDL_SYNTAX))
+	assert.Contains(err.Error(), "004_bad.sql")
+}
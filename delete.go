@@ -7,16 +7,16 @@ package sqlb
 
 import (
 	"errors"
+	"io"
 )
 
 var (
 	ERR_DELETE_NO_TARGET = errors.New("No target table supplied.")
+	ERR_DELETE_READ_ONLY = errors.New("Cannot delete from a read-only view.")
 )
 
 type DeleteQuery struct {
 	e       error
-	b       []byte
-	args    []interface{}
 	stmt    *deleteStatement
 	scanner *sqlScanner
 }
@@ -29,28 +29,83 @@ func (q *DeleteQuery) Error() error {
 	return q.e
 }
 
+// Returns the SQL string for the query. Safe for concurrent use by multiple
+// goroutines as long as the query is not being modified.
 func (q *DeleteQuery) String() string {
-	sizes := q.scanner.size(q.stmt)
-	if len(q.args) != sizes.ArgCount {
-		q.args = make([]interface{}, sizes.ArgCount)
-	}
-	if len(q.b) != sizes.BufferSize {
-		q.b = make([]byte, sizes.BufferSize)
-	}
-	q.scanner.scan(q.b, q.args, q.stmt)
-	return string(q.b)
+	b, _ := q.scanner.appendSQL(nil, nil, q.stmt)
+	return string(b)
 }
 
+// Returns the SQL string for the query along with a new slice containing the
+// query arguments. Safe for concurrent use by multiple goroutines as long as
+// the query is not being modified.
 func (q *DeleteQuery) StringArgs() (string, []interface{}) {
-	sizes := q.scanner.size(q.stmt)
-	if len(q.args) != sizes.ArgCount {
-		q.args = make([]interface{}, sizes.ArgCount)
+	b, args := q.scanner.appendSQL(nil, nil, q.stmt)
+	return string(b), args
+}
+
+// Renders the query's SQL string once and returns a QueryTemplate that can
+// be bound to values for the query's named params (see Param()) any number
+// of times. Returns the query's error, if any.
+func (q *DeleteQuery) Compile() (*QueryTemplate, error) {
+	if q.e != nil {
+		return nil, q.e
 	}
-	if len(q.b) != sizes.BufferSize {
-		q.b = make([]byte, sizes.BufferSize)
+	qs, qargs := q.StringArgs()
+	return newQueryTemplate(qs, qargs), nil
+}
+
+// Appends the SQL string for the query to dst and the query arguments to
+// args, returning the extended slices. dst and args are only reallocated if
+// they lack the capacity to hold the query, so callers on hot paths can avoid
+// allocations by reusing buffers, e.g. from a sync.Pool.
+func (q *DeleteQuery) AppendSQL(dst []byte, args []interface{}) ([]byte, []interface{}) {
+	return q.scanner.appendSQL(dst, args, q.stmt)
+}
+
+// Writes the SQL string for the query to the supplied io.Writer. Implements
+// the io.WriterTo interface.
+func (q *DeleteQuery) WriteTo(w io.Writer) (int64, error) {
+	return q.scanner.writeTo(w, q.stmt)
+}
+
+// Returns the SQL string for the query with all query arguments inlined as
+// dialect-specific SQL literals. This is useful for debugging and logging
+// but the returned string should NOT be executed against a database. Use
+// StringArgs() and the database driver's parameter binding instead.
+func (q *DeleteQuery) Interpolated() string {
+	qs, args := q.StringArgs()
+	return interpolate(q.scanner.dialect, qs, args)
+}
+
+// Returns the query's Fingerprint, which identifies the shape of the query
+// independently of its argument values. Returns a zero Fingerprint if the
+// query has an error.
+func (q *DeleteQuery) Fingerprint() Fingerprint {
+	if !q.IsValid() {
+		return Fingerprint{}
 	}
-	q.scanner.scan(q.b, q.args, q.stmt)
-	return string(q.b), q.args
+	return fingerprintOf(q.scanner.dialect, q.stmt)
+}
+
+// Adds a comment that will be output before the SQL statement
+func (q *DeleteQuery) Comment(text string) *DeleteQuery {
+	q.stmt.comments = addLeadingComment(q.stmt.comments, text)
+	return q
+}
+
+// Adds a comment that will be output after the SQL statement
+func (q *DeleteQuery) TrailingComment(text string) *DeleteQuery {
+	q.stmt.comments = addTrailingComment(q.stmt.comments, text)
+	return q
+}
+
+// Adds a sqlcommenter-style key='value' tag to the comment that is output
+// after the SQL statement. Setting a key that already exists replaces the
+// key's value.
+func (q *DeleteQuery) Tag(key string, value string) *DeleteQuery {
+	q.stmt.comments = addTag(q.stmt.comments, key, value)
+	return q
 }
 
 func (q *DeleteQuery) Where(e *Expression) *DeleteQuery {
@@ -64,11 +119,11 @@ func Delete(t *Table) *DeleteQuery {
 	if t == nil {
 		return &DeleteQuery{e: ERR_DELETE_NO_TARGET}
 	}
-
-	scanner := &sqlScanner{
-		dialect: t.meta.dialect,
-		format:  defaultFormatOptions,
+	if t.IsReadOnly() {
+		return &DeleteQuery{e: ERR_DELETE_READ_ONLY}
 	}
+
+	scanner := newSqlScanner(t.meta.dialect, defaultFormatOptions)
 	stmt := &deleteStatement{
 		table: t,
 	}
@@ -8,8 +8,9 @@ package sqlb
 // DELETE FROM <table> WHERE <predicates>
 
 type deleteStatement struct {
-	table *Table
-	where *whereClause
+	table    *Table
+	where    *whereClause
+	comments *commentClause
 }
 
 func (s *deleteStatement) argCount() int {
@@ -21,21 +22,31 @@ func (s *deleteStatement) argCount() int {
 }
 
 func (s *deleteStatement) size(scanner *sqlScanner) int {
-	size := len(Symbols[SYM_DELETE]) + len(s.table.name)
+	size := len(Symbols[SYM_DELETE]) + s.table.nameSize()
 	if s.where != nil {
 		size += s.where.size(scanner)
 	}
+	if s.comments != nil {
+		size += s.comments.leadingSize(scanner)
+		size += s.comments.trailingSize(scanner)
+	}
 	return size
 }
 
 func (s *deleteStatement) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	bw := 0
+	if s.comments != nil {
+		bw += s.comments.scanLeading(scanner, b[bw:])
+	}
 	bw += copy(b[bw:], Symbols[SYM_DELETE])
 	// We don't add any table alias when outputting the table identifier
-	bw += copy(b[bw:], s.table.name)
+	bw += s.table.scanName(b[bw:])
 	if s.where != nil {
 		bw += s.where.scan(scanner, b[bw:], args, curArg)
 	}
+	if s.comments != nil {
+		bw += s.comments.scanTrailing(scanner, b[bw:])
+	}
 	return bw
 }
 
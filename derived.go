@@ -131,12 +131,6 @@ func (dc *derivedColumn) from() selection {
 	return dc.dt
 }
 
-func (dc *derivedColumn) disableAliasScan() func() {
-	origAlias := dc.alias
-	dc.alias = ""
-	return func() { dc.alias = origAlias }
-}
-
 func (dc *derivedColumn) argCount() int {
 	return 0
 }
@@ -149,7 +143,7 @@ func (dc *derivedColumn) size(scanner *sqlScanner) int {
 	} else {
 		size += len(dc.c.name)
 	}
-	if dc.alias != "" {
+	if dc.alias != "" && !scanner.disableAliases {
 		size += len(Symbols[SYM_AS]) + len(dc.alias)
 	}
 	return size
@@ -164,7 +158,7 @@ func (dc *derivedColumn) scan(scanner *sqlScanner, b []byte, args []interface{},
 	} else {
 		bw += copy(b[bw:], dc.c.name)
 	}
-	if dc.alias != "" {
+	if dc.alias != "" && !scanner.disableAliases {
 		bw += copy(b[bw:], Symbols[SYM_AS])
 		bw += copy(b[bw:], dc.alias)
 	}
@@ -0,0 +1,607 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A ChangeType describes how a table, column, constraint, foreign key or
+// index differs between two Metas
+type ChangeType int
+
+const (
+	CHANGE_TYPE_ADDED ChangeType = iota
+	CHANGE_TYPE_REMOVED
+	CHANGE_TYPE_CHANGED
+)
+
+var (
+	changeTypeSymbols = map[ChangeType]string{
+		CHANGE_TYPE_ADDED:   "+",
+		CHANGE_TYPE_REMOVED: "-",
+		CHANGE_TYPE_CHANGED: "~",
+	}
+	changeTypeNames = map[ChangeType]string{
+		CHANGE_TYPE_ADDED:   "added",
+		CHANGE_TYPE_REMOVED: "removed",
+		CHANGE_TYPE_CHANGED: "changed",
+	}
+)
+
+// Returns "added", "removed" or "changed"
+func (c ChangeType) String() string {
+	return changeTypeNames[c]
+}
+
+// A SchemaDiff describes the differences between two Metas. See Diff().
+type SchemaDiff struct {
+	// The tables that were added, removed or changed, ordered by
+	// schema-qualified table name
+	Tables []*TableDiff
+}
+
+// A TableDiff describes how a table differs between two Metas. For added
+// tables, From is nil and for removed tables, To is nil. The column,
+// constraint, foreign key and index differences are only populated for
+// changed tables.
+type TableDiff struct {
+	// The table's name, qualified with its schema name if the table is not
+	// in its Meta's default schema
+	Name        string
+	Change      ChangeType
+	From        *Table
+	To          *Table
+	KindChanged bool
+	Columns     []*ColumnDiff
+	Constraints []*ConstraintDiff
+	ForeignKeys []*ForeignKeyDiff
+	Indexes     []*IndexDiff
+}
+
+// A ColumnDiff describes how a column differs between two Metas. For added
+// columns, From is nil and for removed columns, To is nil.
+type ColumnDiff struct {
+	Name   string
+	Change ChangeType
+	From   *Column
+	To     *Column
+	// Whether the data type, including its length, precision and scale,
+	// differs. Data types are only compared when known in both Metas.
+	TypeChanged          bool
+	NullableChanged      bool
+	DefaultChanged       bool
+	AutoIncrementChanged bool
+}
+
+// A ConstraintDiff describes how a PRIMARY KEY or UNIQUE constraint differs
+// between two Metas. Primary keys are matched regardless of their names,
+// UNIQUE constraints are matched by name.
+type ConstraintDiff struct {
+	Name   string
+	Change ChangeType
+	From   *Constraint
+	To     *Constraint
+}
+
+// A ForeignKeyDiff describes how a foreign key differs between two Metas.
+// Foreign keys are matched by name.
+type ForeignKeyDiff struct {
+	Name   string
+	Change ChangeType
+	From   *ForeignKey
+	To     *ForeignKey
+}
+
+// An IndexDiff describes how an index differs between two Metas. Indexes
+// are matched by name.
+type IndexDiff struct {
+	Name   string
+	Change ChangeType
+	From   *Index
+	To     *Index
+}
+
+// Compares two Metas and returns the changes needed to go from the schema
+// described by a to the schema described by b. Tables and columns present in
+// b but not in a are reported as added and those present in a but not in b
+// are reported as removed.
+//
+// For instance, to check at startup that the live database has the schema
+// the application expects, reflect the live schema into one Meta, load the
+// expected schema with LoadMeta() or LoadDDL() into another, and compare:
+//
+//	diff := sqlb.Diff(live, expected)
+//	if !diff.IsEmpty() {
+//	    log.Fatalf("schema mismatch:\n%s", diff)
+//	}
+//
+// Tables are matched by schema-qualified name relative to each Meta's
+// default schema, and columns by name.
+func Diff(a *Meta, b *Meta) *SchemaDiff {
+	res := &SchemaDiff{Tables: make([]*TableDiff, 0)}
+	from := tablesByQualifiedName(a)
+	to := tablesByQualifiedName(b)
+	for name, ft := range from {
+		tt, found := to[name]
+		if !found {
+			res.Tables = append(res.Tables, &TableDiff{
+				Name:   name,
+				Change: CHANGE_TYPE_REMOVED,
+				From:   ft,
+			})
+			continue
+		}
+		if td := diffTable(name, ft, tt); td != nil {
+			res.Tables = append(res.Tables, td)
+		}
+	}
+	for name, tt := range to {
+		if _, found := from[name]; !found {
+			res.Tables = append(res.Tables, &TableDiff{
+				Name:   name,
+				Change: CHANGE_TYPE_ADDED,
+				To:     tt,
+			})
+		}
+	}
+	sort.Slice(res.Tables, func(x, y int) bool {
+		return res.Tables[x].Name < res.Tables[y].Name
+	})
+	return res
+}
+
+// Returns whether the two Metas describe the same schema
+func (d *SchemaDiff) IsEmpty() bool {
+	return len(d.Tables) == 0
+}
+
+// Returns a human-readable report of the differences, with one line per
+// added, removed or changed object. Lines start with "+" for added objects,
+// "-" for removed objects and "~" for changed objects. Returns "" if there
+// are no differences.
+func (d *SchemaDiff) String() string {
+	var sb strings.Builder
+	for _, td := range d.Tables {
+		td.writeReport(&sb)
+	}
+	return sb.String()
+}
+
+func (td *TableDiff) writeReport(sb *strings.Builder) {
+	sb.WriteString(changeTypeSymbols[td.Change])
+	sb.WriteString(" ")
+	t := td.To
+	if t == nil {
+		t = td.From
+	}
+	sb.WriteString(tableKindReportNames[t.kind])
+	sb.WriteString(" ")
+	sb.WriteString(td.Name)
+	if td.KindChanged {
+		sb.WriteString(": ")
+		sb.WriteString(tableKindReportNames[td.From.kind])
+		sb.WriteString(" -> ")
+		sb.WriteString(tableKindReportNames[td.To.kind])
+	}
+	sb.WriteString("\n")
+	for _, cd := range td.Columns {
+		sb.WriteString("    ")
+		sb.WriteString(changeTypeSymbols[cd.Change])
+		sb.WriteString(" column ")
+		sb.WriteString(cd.Name)
+		switch cd.Change {
+		case CHANGE_TYPE_ADDED:
+			sb.WriteString(" ")
+			sb.WriteString(columnReportDef(cd.To))
+		case CHANGE_TYPE_CHANGED:
+			sb.WriteString(": ")
+			sb.WriteString(strings.Join(cd.changes(), "; "))
+		}
+		sb.WriteString("\n")
+	}
+	for _, cd := range td.Constraints {
+		c := cd.To
+		if c == nil {
+			c = cd.From
+		}
+		sb.WriteString("    ")
+		sb.WriteString(changeTypeSymbols[cd.Change])
+		sb.WriteString(" ")
+		sb.WriteString(constraintReportNames[c.ctype])
+		sb.WriteString(" ")
+		sb.WriteString(cd.Name)
+		if cd.Change == CHANGE_TYPE_CHANGED {
+			sb.WriteString(": ")
+			sb.WriteString(columnReportList(cd.From.columns))
+			sb.WriteString(" -> ")
+		} else {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(columnReportList(c.columns))
+		sb.WriteString("\n")
+	}
+	for _, fd := range td.ForeignKeys {
+		fk := fd.To
+		if fk == nil {
+			fk = fd.From
+		}
+		sb.WriteString("    ")
+		sb.WriteString(changeTypeSymbols[fd.Change])
+		sb.WriteString(" foreign key ")
+		sb.WriteString(fd.Name)
+		if fd.Change == CHANGE_TYPE_CHANGED {
+			sb.WriteString(": ")
+			sb.WriteString(foreignKeyReportDef(fd.From))
+			sb.WriteString(" -> ")
+		} else {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(foreignKeyReportDef(fk))
+		sb.WriteString("\n")
+	}
+	for _, id := range td.Indexes {
+		i := id.To
+		if i == nil {
+			i = id.From
+		}
+		sb.WriteString("    ")
+		sb.WriteString(changeTypeSymbols[id.Change])
+		sb.WriteString(" index ")
+		sb.WriteString(id.Name)
+		if id.Change == CHANGE_TYPE_CHANGED {
+			sb.WriteString(": ")
+			sb.WriteString(indexReportDef(id.From))
+			sb.WriteString(" -> ")
+		} else {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(indexReportDef(i))
+		sb.WriteString("\n")
+	}
+}
+
+var (
+	tableKindReportNames = map[TableKind]string{
+		TABLE_KIND_TABLE:             "table",
+		TABLE_KIND_VIEW:              "view",
+		TABLE_KIND_MATERIALIZED_VIEW: "materialized view",
+	}
+	constraintReportNames = map[ConstraintType]string{
+		CONSTRAINT_TYPE_PRIMARY_KEY: "primary key",
+		CONSTRAINT_TYPE_UNIQUE:      "unique constraint",
+	}
+)
+
+// Returns descriptions of the changes to a column, e.g.
+// "type varchar(100) -> varchar(200)"
+func (cd *ColumnDiff) changes() []string {
+	res := make([]string, 0, 4)
+	if cd.TypeChanged {
+		res = append(res, "type "+columnReportType(cd.From)+" -> "+columnReportType(cd.To))
+	}
+	if cd.NullableChanged {
+		res = append(res, "nullable "+strconv.FormatBool(cd.From.def.nullable)+
+			" -> "+strconv.FormatBool(cd.To.def.nullable))
+	}
+	if cd.DefaultChanged {
+		res = append(res, "default "+columnReportDefault(cd.From)+" -> "+columnReportDefault(cd.To))
+	}
+	if cd.AutoIncrementChanged {
+		res = append(res, "auto increment "+strconv.FormatBool(cd.From.def.autoIncrement)+
+			" -> "+strconv.FormatBool(cd.To.def.autoIncrement))
+	}
+	return res
+}
+
+// Returns the column's data type for a report, including its length or
+// precision and scale if the raw type does not include them
+func columnReportType(c *Column) string {
+	if c.def.rawType == "" {
+		return "unknown"
+	}
+	res := c.def.rawType
+	if strings.Contains(res, "(") {
+		return res
+	}
+	switch c.def.sqlType {
+	case SQL_TYPE_CHAR, SQL_TYPE_VARCHAR, SQL_TYPE_BINARY:
+		if c.def.length > 0 {
+			res += "(" + strconv.Itoa(c.def.length) + ")"
+		}
+	case SQL_TYPE_DECIMAL:
+		if c.def.precision > 0 {
+			res += "(" + strconv.Itoa(c.def.precision) + "," + strconv.Itoa(c.def.scale) + ")"
+		}
+	}
+	return res
+}
+
+func columnReportDefault(c *Column) string {
+	if c.def.defaultExpr == nil {
+		return "none"
+	}
+	return *c.def.defaultExpr
+}
+
+// Returns a description of an added column, e.g. "varchar(100) NOT NULL"
+func columnReportDef(c *Column) string {
+	res := columnReportType(c)
+	if !c.def.nullable {
+		res += " NOT NULL"
+	}
+	if c.def.defaultExpr != nil {
+		res += " DEFAULT " + *c.def.defaultExpr
+	}
+	return res
+}
+
+// Returns the parenthesized, comma-separated names of the supplied columns
+func columnReportList(cols []*Column) string {
+	return "(" + strings.Join(columnNames(cols), ", ") + ")"
+}
+
+func foreignKeyReportDef(fk *ForeignKey) string {
+	res := columnReportList(fk.columns) + " REFERENCES "
+	if fk.refTable != nil {
+		res += fk.refTable.qualifiedName() + " "
+	}
+	res += columnReportList(fk.refColumns)
+	if fk.onDelete != REFERENTIAL_ACTION_NO_ACTION {
+		res += " ON DELETE " + fk.onDelete.String()
+	}
+	if fk.onUpdate != REFERENTIAL_ACTION_NO_ACTION {
+		res += " ON UPDATE " + fk.onUpdate.String()
+	}
+	return res
+}
+
+func indexReportDef(i *Index) string {
+	if i.unique {
+		return "UNIQUE " + columnReportList(i.columns)
+	}
+	return columnReportList(i.columns)
+}
+
+// Returns the Meta's tables keyed by schema-qualified name
+func tablesByQualifiedName(m *Meta) map[string]*Table {
+	res := make(map[string]*Table, len(m.tables))
+	for _, t := range m.tables {
+		res[t.qualifiedName()] = t
+	}
+	return res
+}
+
+// Returns the differences between two tables with the same name, or nil if
+// the tables are the same
+func diffTable(name string, from *Table, to *Table) *TableDiff {
+	td := &TableDiff{
+		Name:        name,
+		Change:      CHANGE_TYPE_CHANGED,
+		From:        from,
+		To:          to,
+		KindChanged: from.kind != to.kind,
+	}
+	for _, fc := range from.columns {
+		tc := to.C(fc.name)
+		if tc == nil {
+			td.Columns = append(td.Columns, &ColumnDiff{
+				Name:   fc.name,
+				Change: CHANGE_TYPE_REMOVED,
+				From:   fc,
+			})
+			continue
+		}
+		if cd := diffColumn(fc, tc); cd != nil {
+			td.Columns = append(td.Columns, cd)
+		}
+	}
+	for _, tc := range to.columns {
+		if from.C(tc.name) == nil {
+			td.Columns = append(td.Columns, &ColumnDiff{
+				Name:   tc.name,
+				Change: CHANGE_TYPE_ADDED,
+				To:     tc,
+			})
+		}
+	}
+	td.Constraints = diffConstraints(from.constraints, to.constraints)
+	td.ForeignKeys = diffForeignKeys(from.foreignKeys, to.foreignKeys)
+	td.Indexes = diffIndexes(from.indexes, to.indexes)
+	if !td.KindChanged && len(td.Columns) == 0 && len(td.Constraints) == 0 &&
+		len(td.ForeignKeys) == 0 && len(td.Indexes) == 0 {
+		return nil
+	}
+	return td
+}
+
+// Returns the differences between two columns with the same name, or nil if
+// the columns are the same
+func diffColumn(from *Column, to *Column) *ColumnDiff {
+	fd := from.def
+	td := to.def
+	cd := &ColumnDiff{
+		Name:   from.name,
+		Change: CHANGE_TYPE_CHANGED,
+		From:   from,
+		To:     to,
+	}
+	// Columns added manually with Table.NewColumn() have no known data type,
+	// nullability or default, so only compare definitions when both columns'
+	// data types are known
+	if fd.rawType == "" || td.rawType == "" {
+		return nil
+	}
+	cd.TypeChanged = !sameColumnType(fd, td)
+	cd.NullableChanged = fd.nullable != td.nullable
+	cd.AutoIncrementChanged = fd.autoIncrement != td.autoIncrement
+	// The default of an auto-incrementing column is an implementation detail,
+	// e.g. nextval() on a sequence for PostgreSQL SERIAL columns
+	if !(fd.autoIncrement && td.autoIncrement) {
+		cd.DefaultChanged = !sameDefault(fd.defaultExpr, td.defaultExpr)
+	}
+	if !cd.TypeChanged && !cd.NullableChanged && !cd.DefaultChanged && !cd.AutoIncrementChanged {
+		return nil
+	}
+	return cd
+}
+
+func sameColumnType(a columnDef, b columnDef) bool {
+	if !strings.EqualFold(a.rawType, b.rawType) {
+		return false
+	}
+	switch a.sqlType {
+	case SQL_TYPE_CHAR, SQL_TYPE_VARCHAR, SQL_TYPE_BINARY:
+		return a.length == b.length
+	case SQL_TYPE_DECIMAL:
+		return a.precision == b.precision && a.scale == b.scale
+	}
+	return true
+}
+
+// Returns whether two default expressions are equivalent. Database servers
+// report defaults differently from how they are written in DDL, e.g.
+// PostgreSQL reports the default ” of a varchar column as
+// ”::character varying and MySQL reports it as the empty string, so
+// expressions are normalized before comparison.
+func sameDefault(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return strings.EqualFold(normalizeDefault(*a), normalizeDefault(*b))
+}
+
+func normalizeDefault(expr string) string {
+	expr = strings.TrimSpace(expr)
+	for {
+		// Strip PostgreSQL casts, e.g. 'active'::character varying
+		if x := strings.LastIndex(expr, "::"); x > 0 && !strings.ContainsAny(expr[x:], "')") {
+			expr = strings.TrimSpace(expr[:x])
+			continue
+		}
+		// Strip enclosing parentheses, e.g. ('active')
+		if len(expr) > 1 && expr[0] == '(' && expr[len(expr)-1] == ')' &&
+			strings.Count(expr, "(") == 1 {
+			expr = strings.TrimSpace(expr[1 : len(expr)-1])
+			continue
+		}
+		break
+	}
+	// Unquote string literals
+	if len(expr) > 1 && expr[0] == '\'' && expr[len(expr)-1] == '\'' {
+		expr = strings.Replace(expr[1:len(expr)-1], "''", "'", -1)
+	}
+	return expr
+}
+
+func sameColumns(a []*Column, b []*Column) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for x := range a {
+		if a[x].name != b[x].name {
+			return false
+		}
+	}
+	return true
+}
+
+func diffConstraints(from []*Constraint, to []*Constraint) []*ConstraintDiff {
+	var res []*ConstraintDiff
+	// Primary keys are matched regardless of name, as MySQL always names
+	// them PRIMARY
+	find := func(cons []*Constraint, c *Constraint) *Constraint {
+		for _, other := range cons {
+			if other.ctype != c.ctype {
+				continue
+			}
+			if c.ctype == CONSTRAINT_TYPE_PRIMARY_KEY || other.name == c.name {
+				return other
+			}
+		}
+		return nil
+	}
+	for _, fc := range from {
+		tc := find(to, fc)
+		if tc == nil {
+			res = append(res, &ConstraintDiff{Name: fc.name, Change: CHANGE_TYPE_REMOVED, From: fc})
+		} else if !sameColumns(fc.columns, tc.columns) {
+			res = append(res, &ConstraintDiff{Name: tc.name, Change: CHANGE_TYPE_CHANGED, From: fc, To: tc})
+		}
+	}
+	for _, tc := range to {
+		if find(from, tc) == nil {
+			res = append(res, &ConstraintDiff{Name: tc.name, Change: CHANGE_TYPE_ADDED, To: tc})
+		}
+	}
+	return res
+}
+
+func diffForeignKeys(from []*ForeignKey, to []*ForeignKey) []*ForeignKeyDiff {
+	var res []*ForeignKeyDiff
+	find := func(fks []*ForeignKey, name string) *ForeignKey {
+		for _, fk := range fks {
+			if fk.name == name {
+				return fk
+			}
+		}
+		return nil
+	}
+	for _, ffk := range from {
+		tfk := find(to, ffk.name)
+		if tfk == nil {
+			res = append(res, &ForeignKeyDiff{Name: ffk.name, Change: CHANGE_TYPE_REMOVED, From: ffk})
+		} else if !sameForeignKey(ffk, tfk) {
+			res = append(res, &ForeignKeyDiff{Name: ffk.name, Change: CHANGE_TYPE_CHANGED, From: ffk, To: tfk})
+		}
+	}
+	for _, tfk := range to {
+		if find(from, tfk.name) == nil {
+			res = append(res, &ForeignKeyDiff{Name: tfk.name, Change: CHANGE_TYPE_ADDED, To: tfk})
+		}
+	}
+	return res
+}
+
+func sameForeignKey(a *ForeignKey, b *ForeignKey) bool {
+	if (a.refTable == nil) != (b.refTable == nil) {
+		return false
+	}
+	if a.refTable != nil && a.refTable.qualifiedName() != b.refTable.qualifiedName() {
+		return false
+	}
+	return sameColumns(a.columns, b.columns) &&
+		sameColumns(a.refColumns, b.refColumns) &&
+		a.onDelete == b.onDelete && a.onUpdate == b.onUpdate
+}
+
+func diffIndexes(from []*Index, to []*Index) []*IndexDiff {
+	var res []*IndexDiff
+	find := func(idxs []*Index, name string) *Index {
+		for _, i := range idxs {
+			if i.name == name {
+				return i
+			}
+		}
+		return nil
+	}
+	for _, fi := range from {
+		ti := find(to, fi.name)
+		if ti == nil {
+			res = append(res, &IndexDiff{Name: fi.name, Change: CHANGE_TYPE_REMOVED, From: fi})
+		} else if fi.unique != ti.unique || !sameColumns(fi.columns, ti.columns) {
+			res = append(res, &IndexDiff{Name: fi.name, Change: CHANGE_TYPE_CHANGED, From: fi, To: ti})
+		}
+	}
+	for _, ti := range to {
+		if find(from, ti.name) == nil {
+			res = append(res, &IndexDiff{Name: ti.name, Change: CHANGE_TYPE_ADDED, To: ti})
+		}
+	}
+	return res
+}
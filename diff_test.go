@@ -0,0 +1,170 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestDDL(t *testing.T, dialect Dialect, ddl string) *Meta {
+	m := NewMeta(dialect, "test")
+	if err := LoadDDL(dialect, strings.NewReader(ddl), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDiffIdentical(t *testing.T) {
+	assert := assert.New(t)
+
+	a := loadTestDDL(t, DIALECT_POSTGRESQL, _POSTGRESQL_DDL)
+	b := loadTestDDL(t, DIALECT_POSTGRESQL, _POSTGRESQL_DDL)
+	d := Diff(a, b)
+	assert.True(d.IsEmpty())
+	assert.Equal("", d.String())
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	a := loadTestDDL(t, DIALECT_POSTGRESQL, `
+CREATE TABLE users (
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(100) NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'active',
+  legacy TEXT
+);
+CREATE TABLE articles (
+  id INT PRIMARY KEY,
+  author_id INT REFERENCES users (id)
+);
+CREATE TABLE old_stuff (id INT);
+CREATE INDEX users_email_idx ON users (email);
+`)
+	b := loadTestDDL(t, DIALECT_POSTGRESQL, `
+CREATE TABLE users (
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(200),
+  status VARCHAR(10) NOT NULL DEFAULT 'active'::character varying,
+  bio TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE articles (
+  id INT PRIMARY KEY,
+  author_id INT REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE TABLE comments (id INT PRIMARY KEY);
+CREATE UNIQUE INDEX users_email_idx ON users (email);
+`)
+	d := Diff(a, b)
+	assert.False(d.IsEmpty())
+	assert.Equal(4, len(d.Tables))
+
+	assert.Equal("articles", d.Tables[0].Name)
+	assert.Equal(CHANGE_TYPE_CHANGED, d.Tables[0].Change)
+	assert.Equal(1, len(d.Tables[0].ForeignKeys))
+	assert.Equal(CHANGE_TYPE_CHANGED, d.Tables[0].ForeignKeys[0].Change)
+
+	assert.Equal("comments", d.Tables[1].Name)
+	assert.Equal(CHANGE_TYPE_ADDED, d.Tables[1].Change)
+	assert.Nil(d.Tables[1].From)
+
+	assert.Equal("old_stuff", d.Tables[2].Name)
+	assert.Equal(CHANGE_TYPE_REMOVED, d.Tables[2].Change)
+
+	users := d.Tables[3]
+	assert.Equal("users", users.Name)
+	assert.Equal(3, len(users.Columns))
+	email := users.Columns[0]
+	assert.Equal("email", email.Name)
+	assert.True(email.TypeChanged)
+	assert.True(email.NullableChanged)
+	assert.False(email.DefaultChanged)
+	assert.Equal(CHANGE_TYPE_REMOVED, users.Columns[1].Change)
+	assert.Equal("legacy", users.Columns[1].Name)
+	assert.Equal(CHANGE_TYPE_ADDED, users.Columns[2].Change)
+	assert.Equal("bio", users.Columns[2].Name)
+	assert.Equal(1, len(users.Indexes))
+	assert.Equal(CHANGE_TYPE_CHANGED, users.Indexes[0].Change)
+
+	exp := `~ table articles
+    ~ foreign key articles_author_id_fkey: (author_id) REFERENCES users (id) -> (author_id) REFERENCES users (id) ON DELETE CASCADE
++ table comments
+- table old_stuff
+~ table users
+    ~ column email: type character varying(100) -> character varying(200); nullable false -> true
+    - column legacy
+    + column bio text NOT NULL DEFAULT ''
+    ~ index users_email_idx: (email) -> UNIQUE (email)
+`
+	assert.Equal(exp, d.String())
+
+	// Diffing in the other direction reverses the changes
+	r := Diff(b, a)
+	assert.Equal(CHANGE_TYPE_REMOVED, r.Tables[1].Change)
+	assert.Equal(CHANGE_TYPE_ADDED, r.Tables[2].Change)
+}
+
+func TestDiffConstraints(t *testing.T) {
+	assert := assert.New(t)
+
+	a := loadTestDDL(t, DIALECT_MYSQL, `
+CREATE TABLE t (a INT NOT NULL, b INT NOT NULL, PRIMARY KEY (a), UNIQUE KEY uq (a, b));
+`)
+	b := loadTestDDL(t, DIALECT_MYSQL, `
+CREATE TABLE t (a INT NOT NULL, b INT NOT NULL, PRIMARY KEY (a, b));
+`)
+	d := Diff(a, b)
+	assert.Equal(1, len(d.Tables))
+	cons := d.Tables[0].Constraints
+	assert.Equal(2, len(cons))
+	assert.Equal(CHANGE_TYPE_CHANGED, cons[0].Change)
+	assert.Equal("PRIMARY", cons[0].Name)
+	assert.Equal(CHANGE_TYPE_REMOVED, cons[1].Change)
+	assert.Equal("uq", cons[1].Name)
+	assert.Contains(d.String(), "~ primary key PRIMARY: (a) -> (a, b)")
+	assert.Contains(d.String(), "- unique constraint uq (a, b)")
+}
+
+func TestDiffManualColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	// Columns without a known definition are only compared by name
+	manual := NewMeta(DIALECT_POSTGRESQL, "test")
+	users := manual.NewTable("users")
+	users.NewColumn("id")
+	users.NewColumn("email")
+
+	loaded := loadTestDDL(t, DIALECT_POSTGRESQL, "CREATE TABLE users (id INT, email TEXT)")
+	assert.True(Diff(manual, loaded).IsEmpty())
+
+	users.NewColumn("name")
+	d := Diff(manual, loaded)
+	assert.Equal(1, len(d.Tables[0].Columns))
+	assert.Equal(CHANGE_TYPE_REMOVED, d.Tables[0].Columns[0].Change)
+}
+
+func TestNormalizeDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		expr string
+		exp  string
+	}{
+		{expr: "0", exp: "0"},
+		{expr: "'active'", exp: "active"},
+		{expr: "'active'::character varying", exp: "active"},
+		{expr: "('it''s')", exp: "it's"},
+		{expr: "now()", exp: "now()"},
+		{expr: "'{}'::jsonb", exp: "{}"},
+		{expr: "'a::b'", exp: "a::b"},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, normalizeDefault(test.expr), test.expr)
+	}
+}
@@ -0,0 +1,272 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+var (
+	ERR_EXEC_NO_EXECUTOR = errors.New("No executor supplied.")
+	ERR_EXEC_NO_DB       = errors.New("No database connection. Call Reflect() or Meta.SetDB() first.")
+)
+
+// An Executor runs SQL statements against a database. *sql.DB, *sql.Tx and
+// *sql.Conn are all Executors.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// A Row is the result of QueryRow(). It wraps a *sql.Row so that an error
+// from building the query, which is detected before the database is
+// contacted, is returned from Scan() in the same way as the database's
+// errors.
+type Row struct {
+	e   error
+	row *sql.Row
+	// Called with the number of rows read and any error once the row has
+	// been scanned, if the query is run by an Executor with hooks
+	done func(int64, error)
+}
+
+// Copies the columns of the matched row into the values pointed at by dest.
+// Returns sql.ErrNoRows if the query matched no rows, or the query's error if
+// it could not be built.
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.e != nil {
+		return r.e
+	}
+	err := r.row.Scan(dest...)
+	if r.done != nil {
+		switch err {
+		case nil:
+			r.done(1, nil)
+		case sql.ErrNoRows:
+			// Finding no rows is a result rather than a failure of the query
+			r.done(0, nil)
+		default:
+			r.done(-1, err)
+		}
+		r.done = nil
+	}
+	return err
+}
+
+// Returns the error, if any, from building or running the query. Like
+// sql.Row.Err(), it does not return sql.ErrNoRows.
+func (r *Row) Err() error {
+	if r.e != nil {
+		return r.e
+	}
+	return r.row.Err()
+}
+
+// Rows is the result of Query(). It embeds the query's *sql.Rows so that the
+// hooks of an Executor returned from WithHooks() are told the number of rows
+// read, and any error reading them, once the rows are closed.
+type Rows struct {
+	*sql.Rows
+	done func(int64, error)
+	once sync.Once
+	n    int64
+}
+
+// Prepares the next result row for reading with Scan(). See sql.Rows.Next().
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		r.n++
+		return true
+	}
+	// database/sql closes the rows once they have all been read
+	r.finish(r.Rows.Err())
+	return false
+}
+
+// Closes the rows. See sql.Rows.Close().
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	if err == nil {
+		err = r.Rows.Err()
+	}
+	r.finish(err)
+	return err
+}
+
+func (r *Rows) finish(err error) {
+	r.once.Do(func() {
+		r.done(r.n, err)
+	})
+}
+
+func execQuery(ctx context.Context, ex Executor, q Query) (sql.Result, error) {
+	if err := q.Error(); err != nil {
+		return nil, err
+	}
+	if ex == nil {
+		return nil, ERR_EXEC_NO_EXECUTOR
+	}
+	qs, qargs := q.StringArgs()
+	if hx, ok := ex.(*hookExecutor); ok {
+		return hx.exec(ctx, q, qs, qargs)
+	}
+	return ex.ExecContext(ctx, qs, qargs...)
+}
+
+func queryRows(ctx context.Context, ex Executor, q Query) (*Rows, error) {
+	rows, done, err := startQuery(ctx, ex, q)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: done}, nil
+}
+
+// Runs the query and returns its rows along with a function that must be
+// called with the number of rows read, or -1 if not known, and any error
+// reading them once the caller is done with the rows
+func startQuery(ctx context.Context, ex Executor, q Query) (*sql.Rows, func(int64, error), error) {
+	if err := q.Error(); err != nil {
+		return nil, nil, err
+	}
+	if ex == nil {
+		return nil, nil, ERR_EXEC_NO_EXECUTOR
+	}
+	qs, qargs := q.StringArgs()
+	if hx, ok := ex.(*hookExecutor); ok {
+		return hx.query(ctx, q, qs, qargs)
+	}
+	rows, err := ex.QueryContext(ctx, qs, qargs...)
+	return rows, func(int64, error) {}, err
+}
+
+func queryRow(ctx context.Context, ex Executor, q Query) *Row {
+	if err := q.Error(); err != nil {
+		return &Row{e: err}
+	}
+	if ex == nil {
+		return &Row{e: ERR_EXEC_NO_EXECUTOR}
+	}
+	qs, qargs := q.StringArgs()
+	if hx, ok := ex.(*hookExecutor); ok {
+		row, done := hx.queryRow(ctx, q, qs, qargs)
+		return &Row{row: row, done: done}
+	}
+	return &Row{row: ex.QueryRowContext(ctx, qs, qargs...)}
+}
+
+// Sets the database connection that Meta.Exec(), Meta.Query() and
+// Meta.QueryRow() run queries against. Reflect() sets the connection it
+// reflects from.
+func (m *Meta) SetDB(db *sql.DB) *Meta {
+	m.db = db
+	return m
+}
+
+// Returns the Meta's database connection, or nil if it has none
+func (m *Meta) DB() *sql.DB {
+	return m.db
+}
+
+// Runs the supplied query, which returns no rows, against the Meta's
+// database connection. Returns the query's error, if any, without contacting
+// the database.
+func (m *Meta) Exec(ctx context.Context, q Query) (sql.Result, error) {
+	if q.Error() == nil && m.db == nil {
+		return nil, ERR_EXEC_NO_DB
+	}
+	return execQuery(ctx, m.db, q)
+}
+
+// Runs the supplied query against the Meta's database connection and returns
+// the resulting rows. Returns the query's error, if any, without contacting
+// the database.
+func (m *Meta) Query(ctx context.Context, q Query) (*Rows, error) {
+	if q.Error() == nil && m.db == nil {
+		return nil, ERR_EXEC_NO_DB
+	}
+	return queryRows(ctx, m.db, q)
+}
+
+// Runs the supplied query, which is expected to return at most one row,
+// against the Meta's database connection. Errors are deferred until
+// Row.Scan() is called.
+func (m *Meta) QueryRow(ctx context.Context, q Query) *Row {
+	if q.Error() == nil && m.db == nil {
+		return &Row{e: ERR_EXEC_NO_DB}
+	}
+	return queryRow(ctx, m.db, q)
+}
+
+// Runs the query using the supplied Executor and returns the resulting rows.
+// Returns the query's error, if any, without contacting the database.
+func (q *SelectQuery) Query(ctx context.Context, ex Executor) (*Rows, error) {
+	return queryRows(ctx, ex, q)
+}
+
+// Runs the query, which is expected to return at most one row, using the
+// supplied Executor. Errors are deferred until Row.Scan() is called.
+func (q *SelectQuery) QueryRow(ctx context.Context, ex Executor) *Row {
+	return queryRow(ctx, ex, q)
+}
+
+// Runs the query using the supplied Executor. Returns the query's error, if
+// any, without contacting the database.
+func (q *InsertQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the query using the supplied Executor. Returns the query's error, if
+// any, without contacting the database.
+func (q *UpdateQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the query using the supplied Executor. Returns the query's error, if
+// any, without contacting the database.
+func (q *DeleteQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the query using the supplied Executor. Returns the query's error, if
+// any, without contacting the database.
+func (q *RefreshQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the statement using the supplied Executor. Returns the statement's
+// error, if any, without contacting the database.
+func (q *CreateTableQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the statement using the supplied Executor. Returns the statement's
+// error, if any, without contacting the database.
+func (q *DropTableQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the statement using the supplied Executor. Returns the statement's
+// error, if any, without contacting the database.
+func (q *TruncateQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the statement using the supplied Executor. Returns the statement's
+// error, if any, without contacting the database.
+func (q *CreateIndexQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
+
+// Runs the statement using the supplied Executor. Returns
+// ERR_DDL_NO_ACTIONS, without contacting the database, if no actions were
+// added.
+func (q *AlterTableQuery) Exec(ctx context.Context, ex Executor) (sql.Result, error) {
+	return execQuery(ctx, ex, q)
+}
@@ -0,0 +1,287 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A database/sql driver that records the statements it is asked to run. Each
+// query returns the driver's columns and rows, which default to a single row
+// with the value 1 in a column named "n".
+type recordingDriver struct {
+	mu      sync.Mutex
+	stmts   []string
+	args    [][]driver.Value
+	columns []string
+	rows    [][]driver.Value
+	// The number of statements prepared and closed
+	prepares int
+	closes   int
+	// The error that preparing a statement fails with, if any
+	prepareErr error
+}
+
+func (d *recordingDriver) record(query string, args []driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stmts = append(d.stmts, query)
+	d.args = append(d.args, args)
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{d: d}, nil
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.prepares++
+	if c.d.prepareErr != nil {
+		return nil, c.d.prepareErr
+	}
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN", nil)
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.d.record("COMMIT", nil)
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.d.record("ROLLBACK", nil)
+	return nil
+}
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.closes++
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.record(s.query, args)
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return &recordingRows{columns: s.d.columns, rows: s.d.rows}, nil
+}
+
+type recordingRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string {
+	return r.columns
+}
+
+func (r *recordingRows) Close() error {
+	return nil
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var (
+	recordingDriverOnce sync.Once
+	testRecordingDriver = &recordingDriver{}
+)
+
+// Returns a *sql.DB backed by the recording driver after clearing the
+// statements it has recorded
+func testRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	recordingDriverOnce.Do(func() {
+		sql.Register("sqlb-recording", testRecordingDriver)
+	})
+	testRecordingDriver.mu.Lock()
+	testRecordingDriver.stmts = nil
+	testRecordingDriver.args = nil
+	testRecordingDriver.prepares = 0
+	testRecordingDriver.closes = 0
+	testRecordingDriver.prepareErr = nil
+	testRecordingDriver.columns = []string{"n"}
+	testRecordingDriver.rows = [][]driver.Value{{int64(1)}}
+	testRecordingDriver.mu.Unlock()
+	db, err := sql.Open("sqlb-recording", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, testRecordingDriver
+}
+
+func TestExec(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+
+	res, err := users.Insert(map[string]interface{}{"name": "fred"}).Exec(ctx, db)
+	assert.Nil(err)
+	n, err := res.RowsAffected()
+	assert.Nil(err)
+	assert.Equal(int64(1), n)
+
+	tx, err := db.BeginTx(ctx, nil)
+	assert.Nil(err)
+	_, err = Delete(users).Where(Equal(users.C("id"), 1)).Exec(ctx, tx)
+	assert.Nil(err)
+	assert.Nil(tx.Commit())
+
+	conn, err := db.Conn(ctx)
+	assert.Nil(err)
+	_, err = users.Update(map[string]interface{}{"name": "barney"}).Exec(ctx, conn)
+	assert.Nil(err)
+	assert.Nil(conn.Close())
+
+	_, err = Delete(users).Exec(ctx, db)
+	assert.Nil(err)
+
+	assert.Equal([]string{
+		"INSERT INTO users (name) VALUES (?)",
+		"BEGIN",
+		"DELETE FROM users WHERE users.id = ?",
+		"COMMIT",
+		"UPDATE users SET name = ?",
+		"DELETE FROM users",
+	}, rec.stmts)
+	assert.Equal([]driver.Value{"fred"}, rec.args[0])
+	assert.Equal([]driver.Value{int64(1)}, rec.args[2])
+
+	// Errors building the query are returned before contacting the database
+	_, err = users.Insert(nil).Exec(ctx, db)
+	assert.Equal(ERR_INSERT_NO_VALUES, err)
+	_, err = NewMeta(DIALECT_MYSQL, "test").NewTable("t").Alter().Exec(ctx, db)
+	assert.Equal(ERR_DDL_NO_ACTIONS, err)
+	_, err = users.Insert(map[string]interface{}{"name": "fred"}).Exec(ctx, nil)
+	assert.Equal(ERR_EXEC_NO_EXECUTOR, err)
+	assert.Equal(6, len(rec.stmts))
+}
+
+func TestQuery(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	q := Select(users.C("id")).Where(Equal(users.C("name"), "fred"))
+
+	rows, err := q.Query(ctx, db)
+	assert.Nil(err)
+	count := 0
+	for rows.Next() {
+		var id int64
+		assert.Nil(rows.Scan(&id))
+		assert.Equal(int64(1), id)
+		count++
+	}
+	assert.Nil(rows.Err())
+	assert.Nil(rows.Close())
+	assert.Equal(1, count)
+
+	var id int64
+	assert.Nil(q.QueryRow(ctx, db).Scan(&id))
+	assert.Equal(int64(1), id)
+
+	assert.Equal([]string{
+		"SELECT users.id FROM users WHERE users.name = ?",
+		"SELECT users.id FROM users WHERE users.name = ?",
+	}, rec.stmts)
+	assert.Equal([]driver.Value{"fred"}, rec.args[1])
+
+	bad := Select().JoinAuto(users)
+	assert.Equal(ERR_JOIN_INVALID_NO_SELECT, bad.Error())
+	_, err = bad.Query(ctx, db)
+	assert.Equal(bad.Error(), err)
+	row := bad.QueryRow(ctx, db)
+	assert.Equal(bad.Error(), row.Err())
+	assert.Equal(bad.Error(), row.Scan(&id))
+	assert.Equal(ERR_EXEC_NO_EXECUTOR, q.QueryRow(ctx, nil).Scan(&id))
+	assert.Equal(2, len(rec.stmts))
+}
+
+func TestMetaExec(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	q := Select(users.C("id"))
+
+	_, err := m.Exec(ctx, Delete(users))
+	assert.Equal(ERR_EXEC_NO_DB, err)
+	_, err = m.Query(ctx, q)
+	assert.Equal(ERR_EXEC_NO_DB, err)
+	assert.Equal(ERR_EXEC_NO_DB, m.QueryRow(ctx, q).Err())
+	// The query's error takes precedence
+	_, err = m.Exec(ctx, users.Insert(nil))
+	assert.Equal(ERR_INSERT_NO_VALUES, err)
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	assert.Equal(m, m.SetDB(db))
+	assert.Equal(db, m.DB())
+
+	_, err = m.Exec(ctx, Delete(users))
+	assert.Nil(err)
+	rows, err := m.Query(ctx, q)
+	assert.Nil(err)
+	assert.Nil(rows.Close())
+	var id int64
+	assert.Nil(m.QueryRow(ctx, q).Scan(&id))
+	assert.Equal([]string{
+		"DELETE FROM users",
+		"SELECT users.id FROM users",
+		"SELECT users.id FROM users",
+	}, rec.stmts)
+}
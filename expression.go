@@ -20,6 +20,8 @@ const (
 	EXP_GREATER_EQUAL
 	EXP_LESS
 	EXP_LESS_EQUAL
+	EXP_ROW_GREATER
+	EXP_ROW_LESS
 )
 
 var (
@@ -62,6 +64,14 @@ var (
 		EXP_LESS_EQUAL: scanInfo{
 			SYM_ELEMENT, SYM_LESS_EQUAL, SYM_ELEMENT,
 		},
+		EXP_ROW_GREATER: scanInfo{
+			SYM_LPAREN, SYM_ELEMENT, SYM_RPAREN, SYM_GREATER,
+			SYM_LPAREN, SYM_ELEMENT, SYM_RPAREN,
+		},
+		EXP_ROW_LESS: scanInfo{
+			SYM_LPAREN, SYM_ELEMENT, SYM_RPAREN, SYM_LESS,
+			SYM_LPAREN, SYM_ELEMENT, SYM_RPAREN,
+		},
 	}
 )
 
@@ -93,19 +103,15 @@ func (e *Expression) argCount() int {
 func (e *Expression) size(scanner *sqlScanner) int {
 	size := 0
 	elidx := 0
+	// We need to disable alias output for elements that are projections. We
+	// don't want to output, for example, "ON users.id AS user_id =
+	// articles.author"
+	elScanner := scanner.noAliases()
 	for _, sym := range e.scanInfo {
 		if sym == SYM_ELEMENT {
 			el := e.elements[elidx]
-			// We need to disable alias output for elements that are
-			// projections. We don't want to output, for example,
-			// "ON users.id AS user_id = articles.author"
-			switch el.(type) {
-			case projection:
-				reset := el.(projection).disableAliasScan()
-				defer reset()
-			}
 			elidx++
-			size += el.size(scanner)
+			size += el.size(elScanner)
 		} else {
 			size += len(Symbols[sym])
 		}
@@ -116,19 +122,24 @@ func (e *Expression) size(scanner *sqlScanner) int {
 func (e *Expression) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	bw := 0
 	elidx := 0
-	for _, sym := range e.scanInfo {
+	// We need to disable alias output for elements that are projections. We
+	// don't want to output, for example, "ON users.id AS user_id =
+	// articles.author"
+	elScanner := scanner.noAliases()
+	for x, sym := range e.scanInfo {
 		if sym == SYM_ELEMENT {
 			el := e.elements[elidx]
-			// We need to disable alias output for elements that are
-			// projections. We don't want to output, for example,
-			// "ON users.id AS user_id = articles.author"
-			switch el.(type) {
-			case projection:
-				reset := el.(projection).disableAliasScan()
-				defer reset()
-			}
 			elidx++
-			bw += el.scan(scanner, b[bw:], args, curArg)
+			l, isList := el.(*List)
+			if isList && scanner.fingerprint && x > 0 && e.scanInfo[x-1] == SYM_IN && l.valuesOnly() {
+				// The values of an IN list are output as a single marker in
+				// fingerprints so that the fingerprint doesn't depend on the
+				// number of values
+				bw += scanner.scanMarker(b[bw:], *curArg)
+				*curArg += l.argCount()
+				continue
+			}
+			bw += el.scan(elScanner, b[bw:], args, curArg)
 		} else {
 			bw += copy(b[bw:], Symbols[sym])
 		}
@@ -0,0 +1,55 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// A Fingerprint identifies the shape of a query independently of the values
+// of its arguments, so that queries can be grouped by shape in logs, metrics
+// and dashboards.
+//
+// The fingerprint is built from the query's elements, not by rewriting its
+// SQL string. In the normalized SQL:
+//
+//   - every query argument is output as a ? marker, whatever the dialect's
+//     marker style
+//   - the values of an IN list are output as a single ? marker, so that
+//     In(users.C("id"), 1, 2) and In(users.C("id"), 1, 2, 3) share a
+//     fingerprint
+//   - comments and sqlcommenter tags are left out, while optimizer hints are
+//     kept
+//   - clauses are separated by a single space, whatever the query's
+//     FormatOptions
+type Fingerprint struct {
+	// A 64-bit FNV-1a hash of SQL as 16 hexadecimal digits
+	Hash string
+	// The normalized SQL string
+	SQL string
+}
+
+// Returns the Fingerprint of the supplied element as output by a scanner of
+// the supplied dialect
+func fingerprintOf(dialect Dialect, el element) Fingerprint {
+	s := newSqlScanner(dialect, defaultFormatOptions)
+	s.fingerprint = true
+	s.noAlias.fingerprint = true
+
+	// Sizes are calculated for the dialect's markers and for every value in IN
+	// lists, so the buffer may be larger than the SQL string.
+	argc := el.argCount()
+	b := make([]byte, el.size(s)+interpolationLength(dialect, argc))
+	args := make([]interface{}, argc)
+	curArg := 0
+	bw := el.scan(s, b, args, &curArg)
+	qs := string(b[:bw])
+
+	h := fnv.New64a()
+	h.Write(b[:bw])
+	return Fingerprint{Hash: fmt.Sprintf("%016x", h.Sum64()), SQL: qs}
+}
@@ -0,0 +1,113 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles")
+
+	pm := testFixtureMeta()
+	pm.dialect = DIALECT_POSTGRESQL
+	pusers := pm.Table("users")
+
+	tests := []struct {
+		name string
+		a    Query
+		b    Query
+		qs   string
+	}{
+		{
+			name: "Argument values",
+			a:    Select(users).Where(Equal(users.C("id"), 1)),
+			b:    Select(users).Where(Equal(users.C("id"), 2)),
+			qs:   "SELECT users.id, users.name FROM users WHERE users.id = ?",
+		},
+		{
+			name: "Dialect markers",
+			a:    Select(users).Where(Equal(users.C("id"), 1)).LimitWithOffset(10, 20),
+			b:    Select(pusers).Where(Equal(pusers.C("id"), 1)).LimitWithOffset(10, 20),
+			qs:   "SELECT users.id, users.name FROM users WHERE users.id = ? LIMIT ? OFFSET ?",
+		},
+		{
+			name: "IN list length",
+			a:    Select(users.C("id")).Where(In(users.C("id"), 1, 2)),
+			b:    Select(pusers.C("id")).Where(In(pusers.C("id"), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11)),
+			qs:   "SELECT users.id FROM users WHERE users.id IN (?)",
+		},
+		{
+			name: "Comments and tags",
+			a:    Select(users.C("id")).Comment("a").Tag("trace", "1"),
+			b:    Select(users.C("id")).TrailingComment("b").Tag("trace", "2"),
+			qs:   "SELECT users.id FROM users",
+		},
+		{
+			name: "INSERT",
+			a:    Insert(users, map[string]interface{}{"id": 1, "name": "fred"}),
+			b:    Insert(users, map[string]interface{}{"id": 2, "name": "barney"}),
+			qs:   "INSERT INTO users (id, name) VALUES (?, ?)",
+		},
+		{
+			name: "UPDATE",
+			a:    users.Update(map[string]interface{}{"name": "fred"}).Where(Equal(users.C("id"), 1)),
+			b:    pusers.Update(map[string]interface{}{"name": "barney"}).Where(Equal(pusers.C("id"), 2)),
+			qs:   "UPDATE users SET name = ? WHERE users.id = ?",
+		},
+		{
+			name: "DELETE",
+			a:    Delete(users).Where(Or(Equal(users.C("id"), 1), In(users.C("name"), "a"))),
+			b:    Delete(pusers).Where(Or(Equal(pusers.C("id"), 2), In(pusers.C("name"), "b", "c"))),
+			qs:   "DELETE FROM users WHERE (users.id = ? OR users.name IN (?))",
+		},
+	}
+	for _, test := range tests {
+		fa := test.a.Fingerprint()
+		fb := test.b.Fingerprint()
+		assert.Equal(test.qs, fa.SQL, test.name)
+		assert.Equal(fa, fb, test.name)
+		assert.Regexp("^[0-9a-f]{16}$", fa.Hash, test.name)
+	}
+
+	// Queries of different shapes have different fingerprints
+	byID := Select(users).Where(Equal(users.C("id"), 1)).Fingerprint()
+	byName := Select(users).Where(Equal(users.C("name"), 1)).Fingerprint()
+	assert.NotEqual(byID.Hash, byName.Hash)
+
+	// Hints change how a query runs, so they are part of the fingerprint
+	hinted := Select(articles.C("id")).Hint("INDEX(articles ix_author)").Fingerprint()
+	assert.Equal("SELECT /*+ INDEX(articles ix_author) */ articles.id FROM articles", hinted.SQL)
+
+	// Computing a fingerprint doesn't change how the query is output
+	q := Select(pusers.C("id")).Where(In(pusers.C("id"), 1, 2)).Comment("a")
+	q.Fingerprint()
+	qs, qargs := q.StringArgs()
+	assert.Equal("/* a */ SELECT users.id FROM users WHERE users.id IN ($1, $2)", qs)
+	assert.Equal([]interface{}{1, 2}, qargs)
+
+	assert.Equal(Fingerprint{}, Select().JoinAuto(users).Fingerprint())
+	assert.Equal(Fingerprint{}, users.Update(nil).Fingerprint())
+}
+
+func TestFingerprintDDL(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_MYSQL, "test")
+	users := m.NewTable("users")
+	users.NewColumn("id").SetType("integer")
+
+	q := users.Drop()
+	assert.Equal(Fingerprint{SQL: q.String(), Hash: q.Fingerprint().Hash}, q.Fingerprint())
+	assert.NotEqual(q.Fingerprint().Hash, users.Truncate().Fingerprint().Hash)
+	assert.Equal(Fingerprint{}, testFixtureMeta().Table("users").Create().Fingerprint())
+}
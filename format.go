@@ -5,6 +5,11 @@
 //
 package sqlb
 
+import (
+	"io"
+	"sync"
+)
+
 type FormatOptions struct {
 	SeparateClauseWith string
 	PrefixWith         string
@@ -15,10 +20,7 @@ var defaultFormatOptions = &FormatOptions{
 	PrefixWith:         "",
 }
 
-var defaultScanner = &sqlScanner{
-	dialect: DIALECT_MYSQL,
-	format:  defaultFormatOptions,
-}
+var defaultScanner = newSqlScanner(DIALECT_MYSQL, defaultFormatOptions)
 
 type ElementSizes struct {
 	// The number of interface{} arguments that the element will add to the
@@ -34,6 +36,68 @@ type ElementSizes struct {
 type sqlScanner struct {
 	dialect Dialect
 	format  *FormatOptions
+	// When true, projections do not output their "AS alias" extended
+	// notation. Used when scanning projections in GROUP BY, ORDER BY, ON and
+	// WHERE clauses and in function arguments.
+	disableAliases bool
+	// A copy of this scanner with disableAliases set to true. We keep a copy
+	// around instead of toggling disableAliases on the scanner itself so that
+	// scanning never mutates state that may be shared between goroutines.
+	noAlias *sqlScanner
+	// When true, the scanner outputs the normalized SQL used for query
+	// fingerprints. See Fingerprint.
+	fingerprint bool
+}
+
+// Returns a new sqlScanner for the supplied dialect and format options
+func newSqlScanner(dialect Dialect, format *FormatOptions) *sqlScanner {
+	s := &sqlScanner{
+		dialect: dialect,
+		format:  format,
+	}
+	s.noAlias = &sqlScanner{
+		dialect:        dialect,
+		format:         format,
+		disableAliases: true,
+	}
+	s.noAlias.noAlias = s.noAlias
+	return s
+}
+
+// Returns a scanner with the same dialect and format as this scanner that
+// instructs projections not to output their aliases
+func (s *sqlScanner) noAliases() *sqlScanner {
+	if s.disableAliases {
+		return s
+	}
+	if s.noAlias != nil {
+		return s.noAlias
+	}
+	return &sqlScanner{
+		dialect:        s.dialect,
+		format:         s.format,
+		disableAliases: true,
+		fingerprint:    s.fingerprint,
+	}
+}
+
+// Writes the interpolation marker for the query argument at the supplied
+// position. Fingerprinting scanners always write a question mark so that
+// fingerprints don't depend on the dialect's marker style.
+func (s *sqlScanner) scanMarker(b []byte, position int) int {
+	if s.fingerprint {
+		return copy(b, Symbols[SYM_QUEST_MARK])
+	}
+	return scanInterpolationMarker(s.dialect, b, position)
+}
+
+// Sets the dialect of the scanner. Should only be called while a query is
+// being constructed, never while it is being scanned.
+func (s *sqlScanner) setDialect(dialect Dialect) {
+	s.dialect = dialect
+	if s.noAlias != nil {
+		s.noAlias.dialect = dialect
+	}
 }
 
 func (s *sqlScanner) scan(b []byte, args []interface{}, scannables ...Scannable) {
@@ -61,3 +125,68 @@ func (s *sqlScanner) size(elements ...element) *ElementSizes {
 		BufferSize: buflen,
 	}
 }
+
+// Appends the SQL string for the supplied element to dst and the element's
+// query arguments to args, returning the extended slices. The slices are only
+// reallocated if their capacity is insufficient, which allows callers to
+// reuse buffers between calls. Numbered interpolation markers continue from
+// the arguments already in args, so that each marker binds to the element's
+// own argument.
+func (s *sqlScanner) appendSQL(dst []byte, args []interface{}, el element) ([]byte, []interface{}) {
+	// We don't call sqlScanner.size() and sqlScanner.scan() here because they
+	// allocate an ElementSizes struct and a slice of variadic arguments
+	// respectively, which defeats the purpose of allowing the caller to supply
+	// their own buffers.
+	elArgc := el.argCount()
+	argc := len(args)
+	// The markers for the element's arguments are numbered from argc+1
+	markersSize := interpolationLength(s.dialect, argc+elArgc) - interpolationLength(s.dialect, argc)
+	elSize := el.size(s) + markersSize + len(s.format.PrefixWith)
+	blen := len(dst)
+	if cap(dst)-blen < elSize {
+		nb := make([]byte, blen, blen+elSize)
+		copy(nb, dst)
+		dst = nb
+	}
+	dst = dst[:blen+elSize]
+	if cap(args)-argc < elArgc {
+		nargs := make([]interface{}, argc, argc+elArgc)
+		copy(nargs, args)
+		args = nargs
+	}
+	args = args[:argc+elArgc]
+	b := dst[blen:]
+	bw := copy(b, s.format.PrefixWith)
+	curArg := argc
+	el.scan(s, b[bw:], args, &curArg)
+	return dst, args
+}
+
+// A pooled pair of buffers used when writing SQL strings to an io.Writer
+type sqlBuffer struct {
+	b    []byte
+	args []interface{}
+}
+
+var sqlBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &sqlBuffer{
+			b:    make([]byte, 0, 256),
+			args: make([]interface{}, 0, 8),
+		}
+	},
+}
+
+// Writes the SQL string for the supplied element to the supplied io.Writer
+// using a pooled buffer, returning the number of bytes written
+func (s *sqlScanner) writeTo(w io.Writer, el element) (int64, error) {
+	buf := sqlBufferPool.Get().(*sqlBuffer)
+	buf.b, buf.args = s.appendSQL(buf.b[:0], buf.args[:0], el)
+	n, err := w.Write(buf.b)
+	// Don't keep references to the query's arguments alive in the pool
+	for x := range buf.args {
+		buf.args[x] = nil
+	}
+	sqlBufferPool.Put(buf)
+	return int64(n), err
+}
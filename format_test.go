@@ -6,6 +6,8 @@
 package sqlb
 
 import (
+	"bytes"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -112,3 +114,105 @@ LIMIT ?`,
 		assert.Equal(qargs, test.qargs)
 	}
 }
+
+func TestAppendSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	m.dialect = DIALECT_POSTGRESQL
+	users := m.Table("users")
+	colUserId := users.C("id")
+	colUserName := users.C("name")
+
+	queries := []Query{
+		Select(users).Where(Equal(colUserName, "foo")).Limit(10),
+		Insert(users, map[string]interface{}{"name": "foo"}),
+		Update(users, map[string]interface{}{"name": "foo"}).Where(Equal(colUserId, 1)),
+		Delete(users).Where(Equal(colUserId, 1)),
+	}
+	for _, q := range queries {
+		expqs, expqargs := q.StringArgs()
+
+		// Appending to empty slices produces the same output as StringArgs()
+		b, qargs := q.AppendSQL(nil, nil)
+		assert.Equal(expqs, string(b))
+		assert.Equal(expqargs, qargs)
+
+		// Appending to non-empty slices keeps the existing contents
+		b, qargs = q.AppendSQL([]byte("X "), nil)
+		assert.Equal("X "+expqs, string(b))
+		assert.Equal(expqargs, qargs)
+
+		// A buffer with enough capacity is reused without reallocation
+		buf := make([]byte, 0, 256)
+		b, _ = q.AppendSQL(buf, nil)
+		assert.Equal(&buf[:1][0], &b[0])
+
+		var w bytes.Buffer
+		n, err := q.WriteTo(&w)
+		assert.Nil(err)
+		assert.Equal(int64(len(expqs)), n)
+		assert.Equal(expqs, w.String())
+	}
+
+	// Interpolation markers continue from the arguments already supplied, so
+	// that each marker binds to its own argument
+	q := Delete(users).Where(And(Equal(colUserId, 1), Equal(colUserName, "foo")))
+	b, qargs := q.AppendSQL([]byte("X "), []interface{}{"pre"})
+	assert.Equal("X DELETE FROM users WHERE (users.id = $2 AND users.name = $3)", string(b))
+	assert.Equal([]interface{}{"pre", 1, "foo"}, qargs)
+
+	// The buffer is sized for markers that gain a digit from the offset
+	existing := make([]interface{}, 9)
+	b, qargs = q.AppendSQL(nil, existing)
+	assert.Equal("DELETE FROM users WHERE (users.id = $10 AND users.name = $11)", string(b))
+	assert.Equal(11, len(qargs))
+
+	mq := Delete(testFixtureMeta().Table("users")).Where(Equal(colUserId, 1))
+	b, qargs = mq.AppendSQL(nil, []interface{}{"pre"})
+	assert.Equal("DELETE FROM users WHERE users.id = ?", string(b))
+	assert.Equal([]interface{}{"pre", 1}, qargs)
+}
+
+func benchmarkQuery() *SelectQuery {
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles")
+	return Select(
+		articles.C("id"),
+		users.C("name").As("author"),
+	).Join(
+		users, Equal(articles.C("author"), users.C("id")),
+	).Where(
+		Equal(users.C("name"), "foo"),
+	).OrderBy(users.C("name").Desc()).Limit(10)
+}
+
+func BenchmarkSelectQueryStringArgs(b *testing.B) {
+	q := benchmarkQuery()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for x := 0; x < b.N; x++ {
+		q.StringArgs()
+	}
+}
+
+func BenchmarkSelectQueryAppendSQL(b *testing.B) {
+	q := benchmarkQuery()
+	buf := make([]byte, 0, 256)
+	args := make([]interface{}, 0, 8)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for x := 0; x < b.N; x++ {
+		buf, args = q.AppendSQL(buf[:0], args[:0])
+	}
+}
+
+func BenchmarkSelectQueryWriteTo(b *testing.B) {
+	q := benchmarkQuery()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for x := 0; x < b.N; x++ {
+		q.WriteTo(io.Discard)
+	}
+}
@@ -104,12 +104,6 @@ func (f *sqlFunc) from() selection {
 	return f.sel
 }
 
-func (f *sqlFunc) disableAliasScan() func() {
-	origAlias := f.alias
-	f.alias = ""
-	return func() { f.alias = origAlias }
-}
-
 func (f *sqlFunc) Alias(alias string) {
 	f.alias = alias
 }
@@ -130,25 +124,20 @@ func (e *sqlFunc) argCount() int {
 func (f *sqlFunc) size(scanner *sqlScanner) int {
 	size := 0
 	elidx := 0
+	// We need to disable alias output for elements that are projections. We
+	// don't want to output, for example, "MAX(users.id AS user_id)"
+	elScanner := scanner.noAliases()
 	for _, sym := range f.scanInfo {
 		switch sym {
 		case SYM_ELEMENT:
 			el := f.elements[elidx]
-			// We need to disable alias output for elements that are
-			// projections. We don't want to output, for example,
-			// "ON users.id AS user_id = articles.author"
-			switch el.(type) {
-			case projection:
-				reset := el.(projection).disableAliasScan()
-				defer reset()
-			}
 			elidx++
-			size += el.size(scanner)
+			size += el.size(elScanner)
 		default:
 			size += len(Symbols[sym])
 		}
 	}
-	if f.alias != "" {
+	if f.alias != "" && !scanner.disableAliases {
 		size += len(Symbols[SYM_AS]) + len(f.alias)
 	}
 	return size
@@ -157,24 +146,19 @@ func (f *sqlFunc) size(scanner *sqlScanner) int {
 func (f *sqlFunc) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	bw := 0
 	elidx := 0
+	// We need to disable alias output for elements that are projections. We
+	// don't want to output, for example, "MAX(users.id AS user_id)"
+	elScanner := scanner.noAliases()
 	for _, sym := range f.scanInfo {
 		if sym == SYM_ELEMENT {
 			el := f.elements[elidx]
-			// We need to disable alias output for elements that are
-			// projections. We don't want to output, for example,
-			// "ON users.id AS user_id = articles.author"
-			switch el.(type) {
-			case projection:
-				reset := el.(projection).disableAliasScan()
-				defer reset()
-			}
 			elidx++
-			bw += el.scan(scanner, b[bw:], args, curArg)
+			bw += el.scan(elScanner, b[bw:], args, curArg)
 		} else {
 			bw += copy(b[bw:], Symbols[sym])
 		}
 	}
-	if f.alias != "" {
+	if f.alias != "" && !scanner.disableAliases {
 		bw += copy(b[bw:], Symbols[SYM_AS])
 		bw += copy(b[bw:], f.alias)
 	}
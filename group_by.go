@@ -19,10 +19,10 @@ func (gb *groupByClause) size(scanner *sqlScanner) int {
 	size += len(scanner.format.SeparateClauseWith)
 	size += len(Symbols[SYM_GROUP_BY])
 	ncols := len(gb.cols)
+	// Projections in the GROUP BY clause never output their aliases
+	colScanner := scanner.noAliases()
 	for _, c := range gb.cols {
-		reset := c.disableAliasScan()
-		defer reset()
-		size += c.size(scanner)
+		size += c.size(colScanner)
 	}
 	return size + (len(Symbols[SYM_COMMA_WS]) * (ncols - 1)) // the commas...
 }
@@ -32,10 +32,10 @@ func (gb *groupByClause) scan(scanner *sqlScanner, b []byte, args []interface{},
 	bw += copy(b[bw:], scanner.format.SeparateClauseWith)
 	bw += copy(b[bw:], Symbols[SYM_GROUP_BY])
 	ncols := len(gb.cols)
+	// Projections in the GROUP BY clause never output their aliases
+	colScanner := scanner.noAliases()
 	for x, c := range gb.cols {
-		reset := c.disableAliasScan()
-		defer reset()
-		bw += c.scan(scanner, b[bw:], args, curArg)
+		bw += c.scan(colScanner, b[bw:], args, curArg)
 		if x != (ncols - 1) {
 			bw += copy(b[bw:], Symbols[SYM_COMMA_WS])
 		}
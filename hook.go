@@ -0,0 +1,219 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type QueryKind int
+
+const (
+	QUERY_KIND_OTHER QueryKind = iota
+	QUERY_KIND_SELECT
+	QUERY_KIND_INSERT
+	QUERY_KIND_UPDATE
+	QUERY_KIND_DELETE
+	QUERY_KIND_DDL
+)
+
+var queryKindNames = map[QueryKind]string{
+	QUERY_KIND_OTHER:  "other",
+	QUERY_KIND_SELECT: "select",
+	QUERY_KIND_INSERT: "insert",
+	QUERY_KIND_UPDATE: "update",
+	QUERY_KIND_DELETE: "delete",
+	QUERY_KIND_DDL:    "ddl",
+}
+
+func (k QueryKind) String() string {
+	return queryKindNames[k]
+}
+
+// Returns the kind of statement the supplied query produces. If the query is
+// nil, the kind is guessed from the first keyword of the SQL string.
+func queryKind(q Query, qs string) QueryKind {
+	switch q.(type) {
+	case *SelectQuery:
+		return QUERY_KIND_SELECT
+	case *InsertQuery:
+		return QUERY_KIND_INSERT
+	case *UpdateQuery:
+		return QUERY_KIND_UPDATE
+	case *DeleteQuery:
+		return QUERY_KIND_DELETE
+	case *CreateTableQuery, *AlterTableQuery, *DropTableQuery, *TruncateQuery, *CreateIndexQuery:
+		return QUERY_KIND_DDL
+	case nil:
+		break
+	default:
+		return QUERY_KIND_OTHER
+	}
+	fields := strings.Fields(qs)
+	if len(fields) == 0 {
+		return QUERY_KIND_OTHER
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH":
+		return QUERY_KIND_SELECT
+	case "INSERT":
+		return QUERY_KIND_INSERT
+	case "UPDATE":
+		return QUERY_KIND_UPDATE
+	case "DELETE":
+		return QUERY_KIND_DELETE
+	case "CREATE", "ALTER", "DROP", "TRUNCATE":
+		return QUERY_KIND_DDL
+	}
+	return QUERY_KIND_OTHER
+}
+
+// A QueryEvent describes a query that is run by an Executor returned from
+// WithHooks(). The same QueryEvent is passed to a Hook's BeforeQuery() and
+// AfterQuery() methods. The fields below Start are set before AfterQuery() is
+// called.
+type QueryEvent struct {
+	// The query being run, or nil if the Executor's methods were called
+	// directly with a SQL string
+	Query Query
+	Kind  QueryKind
+	SQL   string
+	Args  []interface{}
+	Start time.Time
+
+	// For Query(), QueryRow(), ScanAll() and ScanOne(), Duration includes
+	// the time taken to read the rows. AfterQuery() is called once the rows
+	// returned by Query() are closed or have all been read, and once the row
+	// returned by QueryRow() has been scanned.
+	Duration time.Duration
+	// The number of rows affected by an INSERT, UPDATE or DELETE, or the
+	// number of rows read from the result of a SELECT, or -1 if not known.
+	// The rows of a query run with the Executor's QueryContext() method
+	// directly are a *sql.Rows, which can't report when it is closed, so
+	// AfterQuery() is called once the query has started and Rows is -1.
+	Rows int64
+	Err  error
+}
+
+// A Hook is notified before and after each query that is run by an Executor
+// returned from WithHooks(). Hooks can be used for logging, tracing and
+// metrics. BeforeQuery() returns the context that the query is run with and
+// that is passed to AfterQuery(), which allows, for instance, a tracing span
+// to be started and finished.
+type Hook interface {
+	BeforeQuery(ctx context.Context, ev *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, ev *QueryEvent)
+}
+
+// Returns an Executor that runs queries with the supplied Executor and calls
+// the supplied hooks before and after each query. Hooks are called in the
+// order supplied before a query and in reverse order after it.
+//
+//	ex := sqlb.WithHooks(db, sqlb.NewLogHook(slog.Default()))
+//	rows, err := q.Query(ctx, ex)
+func WithHooks(ex Executor, hooks ...Hook) Executor {
+	if hx, ok := ex.(*hookExecutor); ok {
+		all := make([]Hook, 0, len(hx.hooks)+len(hooks))
+		all = append(all, hx.hooks...)
+		return &hookExecutor{ex: hx.ex, hooks: append(all, hooks...)}
+	}
+	return &hookExecutor{ex: ex, hooks: hooks}
+}
+
+type hookExecutor struct {
+	ex    Executor
+	hooks []Hook
+}
+
+func (hx *hookExecutor) before(ctx context.Context, q Query, qs string, args []interface{}) (context.Context, *QueryEvent) {
+	ev := &QueryEvent{
+		Query: q,
+		Kind:  queryKind(q, qs),
+		SQL:   qs,
+		Args:  args,
+		Rows:  -1,
+	}
+	for _, h := range hx.hooks {
+		ctx = h.BeforeQuery(ctx, ev)
+	}
+	ev.Start = time.Now()
+	return ctx, ev
+}
+
+func (hx *hookExecutor) after(ctx context.Context, ev *QueryEvent, rows int64, err error) {
+	ev.Duration = time.Since(ev.Start)
+	ev.Rows = rows
+	ev.Err = err
+	for x := len(hx.hooks) - 1; x >= 0; x-- {
+		hx.hooks[x].AfterQuery(ctx, ev)
+	}
+}
+
+func (hx *hookExecutor) exec(ctx context.Context, q Query, qs string, args []interface{}) (sql.Result, error) {
+	ctx, ev := hx.before(ctx, q, qs, args)
+	res, err := hx.ex.ExecContext(ctx, qs, args...)
+	rows := int64(-1)
+	if err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			rows = n
+		}
+	}
+	hx.after(ctx, ev, rows, err)
+	return res, err
+}
+
+// Runs the query and returns its rows along with a function that must be
+// called with the number of rows read, if known, and any error reading them
+// once the caller is done with the rows
+func (hx *hookExecutor) query(ctx context.Context, q Query, qs string, args []interface{}) (*sql.Rows, func(int64, error), error) {
+	ctx, ev := hx.before(ctx, q, qs, args)
+	rows, err := hx.ex.QueryContext(ctx, qs, args...)
+	if err != nil {
+		hx.after(ctx, ev, -1, err)
+		return nil, nil, err
+	}
+	return rows, func(n int64, err error) {
+		hx.after(ctx, ev, n, err)
+	}, nil
+}
+
+// Runs the query and returns its row along with a function that must be
+// called with the number of rows read and any error once the row has been
+// scanned. The function is nil if the query failed.
+func (hx *hookExecutor) queryRow(ctx context.Context, q Query, qs string, args []interface{}) (*sql.Row, func(int64, error)) {
+	ctx, ev := hx.before(ctx, q, qs, args)
+	row := hx.ex.QueryRowContext(ctx, qs, args...)
+	if err := row.Err(); err != nil {
+		hx.after(ctx, ev, -1, err)
+		return row, nil
+	}
+	return row, func(n int64, err error) {
+		hx.after(ctx, ev, n, err)
+	}
+}
+
+func (hx *hookExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return hx.exec(ctx, nil, query, args)
+}
+
+func (hx *hookExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, done, err := hx.query(ctx, nil, query, args)
+	if err == nil {
+		done(-1, nil)
+	}
+	return rows, err
+}
+
+func (hx *hookExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row, done := hx.queryRow(ctx, nil, query, args)
+	if done != nil {
+		done(-1, nil)
+	}
+	return row
+}
@@ -0,0 +1,164 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testHookKey struct{}
+
+// A Hook that records the events it is called with
+type recordingHook struct {
+	name   string
+	calls  *[]string
+	events []QueryEvent
+}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, ev *QueryEvent) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, testHookKey{}, h.name)
+}
+
+func (h *recordingHook) AfterQuery(ctx context.Context, ev *QueryEvent) {
+	*h.calls = append(*h.calls, "after "+h.name+" "+ctx.Value(testHookKey{}).(string))
+	h.events = append(h.events, *ev)
+}
+
+func TestWithHooks(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+
+	calls := []string{}
+	a := &recordingHook{name: "a", calls: &calls}
+	b := &recordingHook{name: "b", calls: &calls}
+	ex := WithHooks(WithHooks(db, a), b)
+
+	// Hooks run in order before the query and in reverse order after it, and
+	// see the context returned by the hooks before them
+	_, err := users.Insert(map[string]interface{}{"name": "fred"}).Exec(ctx, ex)
+	assert.Nil(err)
+	assert.Equal([]string{"before a", "before b", "after b b", "after a b"}, calls)
+	ev := b.events[0]
+	assert.Equal(QUERY_KIND_INSERT, ev.Kind)
+	assert.Equal("INSERT INTO users (name) VALUES (?)", ev.SQL)
+	assert.Equal([]interface{}{"fred"}, ev.Args)
+	assert.Equal(int64(1), ev.Rows)
+	assert.Nil(ev.Err)
+	assert.False(ev.Start.IsZero())
+	assert.IsType(&InsertQuery{}, ev.Query)
+
+	q := Select(users.C("id"), users.C("name"))
+	rec.columns = []string{"id", "name"}
+	rec.rows = [][]driver.Value{{int64(1), "fred"}, {int64(2), "barney"}}
+
+	// The hooks are called once the rows of Query() have been read and the
+	// row of QueryRow() has been scanned
+	rows, err := q.Query(ctx, ex)
+	assert.Nil(err)
+	assert.Equal(1, len(b.events))
+	for rows.Next() {
+	}
+	assert.Equal(2, len(b.events))
+	assert.Nil(rows.Close())
+	var id int64
+	var name string
+	row := q.QueryRow(ctx, ex)
+	assert.Equal(2, len(b.events))
+	assert.Nil(row.Scan(&id, &name))
+	all := []map[string]interface{}{}
+	assert.Nil(q.ScanAll(ctx, ex, &all))
+	var one testAuthorRow
+	assert.Nil(q.ScanOne(ctx, ex, &one))
+	rec.rows = nil
+	assert.Equal(sql.ErrNoRows, q.ScanOne(ctx, ex, &one))
+
+	events := b.events[1:]
+	assert.Equal(5, len(events))
+	for _, ev := range events {
+		assert.Equal(QUERY_KIND_SELECT, ev.Kind)
+		assert.Equal(q, ev.Query)
+		assert.Nil(ev.Err)
+	}
+	assert.Equal(int64(2), events[0].Rows)
+	assert.Equal(int64(1), events[1].Rows)
+	assert.Equal(int64(2), events[2].Rows)
+	assert.Equal(int64(1), events[3].Rows)
+	assert.Equal(int64(0), events[4].Rows)
+
+	// Queries that can't be built never reach the hooks
+	_, err = users.Update(nil).Exec(ctx, ex)
+	assert.Equal(ERR_UPDATE_NO_VALUES, err)
+	assert.Equal(6, len(b.events))
+
+	// The kind of a SQL string run directly is guessed from its first keyword
+	_, err = ex.ExecContext(ctx, "  delete from users")
+	assert.Nil(err)
+	ev = b.events[6]
+	assert.Nil(ev.Query)
+	assert.Equal(QUERY_KIND_DELETE, ev.Kind)
+	assert.Equal(7, len(a.events))
+
+	// Rows closed early report the rows read so far
+	rec.rows = [][]driver.Value{{int64(1), "fred"}, {int64(2), "barney"}}
+	rows, err = q.Query(ctx, ex)
+	assert.Nil(err)
+	assert.True(rows.Next())
+	assert.Nil(rows.Close())
+	assert.Nil(rows.Close())
+	assert.Equal(8, len(b.events))
+	assert.Equal(int64(1), b.events[7].Rows)
+
+	// The *sql.Rows returned by QueryContext() can't report their count
+	raw, err := ex.QueryContext(ctx, "SELECT id FROM users")
+	assert.Nil(err)
+	assert.Nil(raw.Close())
+	assert.Equal(int64(-1), b.events[8].Rows)
+}
+
+func TestQueryKind(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	users := m.NewTable("users")
+	users.NewColumn("id").SetType("integer")
+
+	tests := []struct {
+		q   Query
+		qs  string
+		exp QueryKind
+	}{
+		{Select(users), "", QUERY_KIND_SELECT},
+		{users.Insert(map[string]interface{}{"id": 1}), "", QUERY_KIND_INSERT},
+		{users.Update(map[string]interface{}{"id": 1}), "", QUERY_KIND_UPDATE},
+		{Delete(users), "", QUERY_KIND_DELETE},
+		{users.Create(), "", QUERY_KIND_DDL},
+		{users.Alter(), "", QUERY_KIND_DDL},
+		{users.Drop(), "", QUERY_KIND_DDL},
+		{users.Truncate(), "", QUERY_KIND_DDL},
+		{RefreshMaterializedView(m.NewMaterializedView("mv")), "", QUERY_KIND_OTHER},
+		{nil, "WITH x AS (SELECT 1) SELECT * FROM x", QUERY_KIND_SELECT},
+		{nil, "CREATE INDEX ix ON users (id)", QUERY_KIND_DDL},
+		{nil, "VACUUM", QUERY_KIND_OTHER},
+		{nil, "", QUERY_KIND_OTHER},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, queryKind(test.q, test.qs))
+	}
+	assert.Equal("select", QUERY_KIND_SELECT.String())
+	assert.Equal("ddl", QUERY_KIND_DDL.String())
+}
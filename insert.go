@@ -7,17 +7,17 @@ package sqlb
 
 import (
 	"errors"
+	"io"
 )
 
 var (
 	ERR_INSERT_NO_VALUES      = errors.New("No values supplied.")
 	ERR_INSERT_UNKNOWN_COLUMN = errors.New("Received an unknown column.")
+	ERR_INSERT_READ_ONLY      = errors.New("Cannot insert into a read-only view.")
 )
 
 type InsertQuery struct {
 	e       error
-	b       []byte
-	args    []interface{}
 	stmt    *insertStatement
 	scanner *sqlScanner
 }
@@ -30,56 +30,111 @@ func (q *InsertQuery) Error() error {
 	return q.e
 }
 
+// Returns the SQL string for the query. Safe for concurrent use by multiple
+// goroutines as long as the query is not being modified.
 func (q *InsertQuery) String() string {
-	sizes := q.scanner.size(q.stmt)
-	if len(q.args) != sizes.ArgCount {
-		q.args = make([]interface{}, sizes.ArgCount)
-	}
-	if len(q.b) != sizes.BufferSize {
-		q.b = make([]byte, sizes.BufferSize)
-	}
-	q.scanner.scan(q.b, q.args, q.stmt)
-	return string(q.b)
+	b, _ := q.scanner.appendSQL(nil, nil, q.stmt)
+	return string(b)
 }
 
+// Returns the SQL string for the query along with a new slice containing the
+// query arguments. Safe for concurrent use by multiple goroutines as long as
+// the query is not being modified.
 func (q *InsertQuery) StringArgs() (string, []interface{}) {
-	sizes := q.scanner.size(q.stmt)
-	if len(q.args) != sizes.ArgCount {
-		q.args = make([]interface{}, sizes.ArgCount)
+	b, args := q.scanner.appendSQL(nil, nil, q.stmt)
+	return string(b), args
+}
+
+// Renders the query's SQL string once and returns a QueryTemplate that can
+// be bound to values for the query's named params (see Param()) any number
+// of times. Returns the query's error, if any.
+func (q *InsertQuery) Compile() (*QueryTemplate, error) {
+	if q.e != nil {
+		return nil, q.e
 	}
-	if len(q.b) != sizes.BufferSize {
-		q.b = make([]byte, sizes.BufferSize)
+	qs, qargs := q.StringArgs()
+	return newQueryTemplate(qs, qargs), nil
+}
+
+// Appends the SQL string for the query to dst and the query arguments to
+// args, returning the extended slices. dst and args are only reallocated if
+// they lack the capacity to hold the query, so callers on hot paths can avoid
+// allocations by reusing buffers, e.g. from a sync.Pool.
+func (q *InsertQuery) AppendSQL(dst []byte, args []interface{}) ([]byte, []interface{}) {
+	return q.scanner.appendSQL(dst, args, q.stmt)
+}
+
+// Writes the SQL string for the query to the supplied io.Writer. Implements
+// the io.WriterTo interface.
+func (q *InsertQuery) WriteTo(w io.Writer) (int64, error) {
+	return q.scanner.writeTo(w, q.stmt)
+}
+
+// Returns the SQL string for the query with all query arguments inlined as
+// dialect-specific SQL literals. This is useful for debugging and logging
+// but the returned string should NOT be executed against a database. Use
+// StringArgs() and the database driver's parameter binding instead.
+func (q *InsertQuery) Interpolated() string {
+	qs, args := q.StringArgs()
+	return interpolate(q.scanner.dialect, qs, args)
+}
+
+// Returns the query's Fingerprint, which identifies the shape of the query
+// independently of its argument values. Returns a zero Fingerprint if the
+// query has an error.
+func (q *InsertQuery) Fingerprint() Fingerprint {
+	if !q.IsValid() {
+		return Fingerprint{}
 	}
-	q.scanner.scan(q.b, q.args, q.stmt)
-	return string(q.b), q.args
+	return fingerprintOf(q.scanner.dialect, q.stmt)
+}
+
+// Adds a comment that will be output before the SQL statement
+func (q *InsertQuery) Comment(text string) *InsertQuery {
+	q.stmt.comments = addLeadingComment(q.stmt.comments, text)
+	return q
+}
+
+// Adds a comment that will be output after the SQL statement
+func (q *InsertQuery) TrailingComment(text string) *InsertQuery {
+	q.stmt.comments = addTrailingComment(q.stmt.comments, text)
+	return q
+}
+
+// Adds a sqlcommenter-style key='value' tag to the comment that is output
+// after the SQL statement. Setting a key that already exists replaces the
+// key's value.
+func (q *InsertQuery) Tag(key string, value string) *InsertQuery {
+	q.stmt.comments = addTag(q.stmt.comments, key, value)
+	return q
 }
 
 // Given a table and a map of column name to value for that column to insert,
-// returns an InsertQuery that will produce an INSERT SQL statement
+// returns an InsertQuery that will produce an INSERT SQL statement. Columns
+// are output in the order of their names, so the same set of columns always
+// produces the same SQL string.
 func Insert(t *Table, values map[string]interface{}) *InsertQuery {
 	if len(values) == 0 {
 		return &InsertQuery{e: ERR_INSERT_NO_VALUES}
 	}
+	if t.IsReadOnly() {
+		return &InsertQuery{e: ERR_INSERT_READ_ONLY}
+	}
 
 	// Make sure all keys in the map point to actual columns in the target
 	// table.
 	cols := make([]*Column, len(values))
 	vals := make([]interface{}, len(values))
-	x := 0
-	for k, v := range values {
+	for x, k := range sortedKeys(values) {
 		c := t.C(k)
 		if c == nil {
 			return &InsertQuery{e: ERR_INSERT_UNKNOWN_COLUMN}
 		}
 		cols[x] = c
-		vals[x] = v
-		x++
+		vals[x] = values[k]
 	}
 
-	scanner := &sqlScanner{
-		dialect: t.meta.dialect,
-		format:  defaultFormatOptions,
-	}
+	scanner := newSqlScanner(t.meta.dialect, defaultFormatOptions)
 	stmt := &insertStatement{
 		table:   t,
 		columns: cols,
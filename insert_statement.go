@@ -8,9 +8,10 @@ package sqlb
 // INSERT INTO <table> (<columns>) VALUES (<values>)
 
 type insertStatement struct {
-	table   *Table
-	columns []*Column
-	values  []interface{}
+	table    *Table
+	columns  []*Column
+	values   []interface{}
+	comments *commentClause
 }
 
 func (s *insertStatement) argCount() int {
@@ -18,7 +19,7 @@ func (s *insertStatement) argCount() int {
 }
 
 func (s *insertStatement) size(scanner *sqlScanner) int {
-	size := len(Symbols[SYM_INSERT]) + len(s.table.name) + 1 // space after table name
+	size := len(Symbols[SYM_INSERT]) + s.table.nameSize() + 1 // space after table name
 	ncols := len(s.columns)
 	for _, c := range s.columns {
 		// We don't add the table identifier or use an alias when outputting
@@ -32,14 +33,21 @@ func (s *insertStatement) size(scanner *sqlScanner) int {
 	// values)
 	size += 2 * (len(Symbols[SYM_COMMA_WS]) * (ncols - 1)) // the commas...
 	size += len(Symbols[SYM_RPAREN])
+	if s.comments != nil {
+		size += s.comments.leadingSize(scanner)
+		size += s.comments.trailingSize(scanner)
+	}
 	return size
 }
 
 func (s *insertStatement) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	bw := 0
+	if s.comments != nil {
+		bw += s.comments.scanLeading(scanner, b[bw:])
+	}
 	bw += copy(b[bw:], Symbols[SYM_INSERT])
 	// We don't add any table alias when outputting the table identifier
-	bw += copy(b[bw:], s.table.name)
+	bw += s.table.scanName(b[bw:])
 	bw += copy(b[bw:], " ")
 	bw += copy(b[bw:], Symbols[SYM_LPAREN])
 
@@ -54,7 +62,7 @@ func (s *insertStatement) scan(scanner *sqlScanner, b []byte, args []interface{}
 	}
 	bw += copy(b[bw:], Symbols[SYM_VALUES])
 	for x, v := range s.values {
-		bw += scanInterpolationMarker(scanner.dialect, b[bw:], *curArg)
+		bw += scanner.scanMarker(b[bw:], *curArg)
 		args[*curArg] = v
 		*curArg++
 		if x != (ncols - 1) {
@@ -62,5 +70,8 @@ func (s *insertStatement) scan(scanner *sqlScanner, b []byte, args []interface{}
 		}
 	}
 	bw += copy(b[bw:], Symbols[SYM_RPAREN])
+	if s.comments != nil {
+		bw += s.comments.scanTrailing(scanner, b[bw:])
+	}
 	return bw
 }
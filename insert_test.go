@@ -46,6 +46,12 @@ func TestInsertQuery(t *testing.T) {
 			qs:    "INSERT INTO users (id) VALUES (?)",
 			qargs: []interface{}{1},
 		},
+		{
+			name:  "INSERT multiple columns in name order",
+			q:     Insert(users, map[string]interface{}{"name": "foo", "id": 1}),
+			qs:    "INSERT INTO users (id, name) VALUES (?, ?)",
+			qargs: []interface{}{1, "foo"},
+		},
 	}
 	for _, test := range tests {
 		if test.qe != nil {
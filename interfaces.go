@@ -5,6 +5,8 @@
 //
 package sqlb
 
+import "io"
+
 type Scannable interface {
 	// scan takes two slices and a pointer to an int. The first slice is a
 	// slice of bytes that the implementation should copy its string
@@ -32,16 +34,14 @@ type element interface {
 // definition, function, etc. When appearing in the SELECT clause's projection
 // list, the projection will output itself using the "AS alias" extended
 // notation. When outputting in GROUP BY, ORDER BY or ON clauses, the
-// projection will not include the alias extension
+// projection will not include the alias extension. Projections check the
+// sqlScanner's disableAliases field to determine whether to output the alias.
 type projection interface {
 	from() selection
 	// projections must also implement element
 	size(*sqlScanner) int
 	argCount() int
 	scan(*sqlScanner, []byte, []interface{}, *int) int
-	// disables the outputting of the "AS alias" extended output. Returns a
-	// function that resets the outputting of the "AS alias" extended output
-	disableAliasScan() func()
 }
 
 // A selection is something that produces rows. A table, table definition,
@@ -61,4 +61,14 @@ type Query interface {
 	Error() error
 	String() string
 	StringArgs() (string, []interface{})
+	// Appends the SQL string and query arguments to the supplied slices
+	AppendSQL([]byte, []interface{}) ([]byte, []interface{})
+	// Writes the SQL string to the supplied io.Writer
+	WriteTo(io.Writer) (int64, error)
+	// Returns the SQL string with arguments inlined as SQL literals. For
+	// debugging only; never execute the returned string.
+	Interpolated() string
+	// Returns the query's Fingerprint, which identifies the query's shape
+	// independently of its argument values
+	Fingerprint() Fingerprint
 }
@@ -0,0 +1,231 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Interpolation of query arguments into the SQL string is ONLY intended for
+// debugging and logging purposes. The SQL string returned by a Query's
+// Interpolated() method should never be sent to a database server. Always
+// use the SQL string and arguments returned from StringArgs() along with the
+// database driver's own parameter binding to execute a query.
+
+const (
+	mysqlTimeFormat      = "2006-01-02 15:04:05.999999"
+	postgresqlTimeFormat = "2006-01-02 15:04:05.999999-07:00"
+)
+
+// Given a dialect, a SQL string containing interpolation markers and the
+// slice of arguments that back those markers, returns a string with each
+// interpolation marker replaced by a dialect-specific literal representation
+// of the matching argument. Markers appearing inside quoted strings, quoted
+// identifiers or comments are left untouched. Markers that have no matching
+// argument are also left untouched.
+func interpolate(dialect Dialect, qs string, args []interface{}) string {
+	b := make([]byte, 0, len(qs)+(16*len(args)))
+	curArg := 0
+	n := len(qs)
+	for x := 0; x < n; x++ {
+		c := qs[x]
+		switch c {
+		case '\'', '"', '`':
+			// Copy the quoted string or identifier verbatim, including
+			// the closing quote character.
+			end := x + 1
+			for end < n && qs[end] != c {
+				if qs[end] == '\\' && c == '\'' && dialect == DIALECT_MYSQL {
+					end++
+				}
+				end++
+			}
+			if end >= n {
+				end = n - 1
+			}
+			b = append(b, qs[x:end+1]...)
+			x = end
+		case '/':
+			if x+1 < n && qs[x+1] == '*' {
+				end := x + 2
+				for end+1 < n && !(qs[end] == '*' && qs[end+1] == '/') {
+					end++
+				}
+				end++
+				if end >= n {
+					end = n - 1
+				}
+				b = append(b, qs[x:end+1]...)
+				x = end
+			} else {
+				b = append(b, c)
+			}
+		case '?':
+			if dialect == DIALECT_POSTGRESQL || curArg >= len(args) {
+				b = append(b, c)
+				continue
+			}
+			b = appendLiteral(dialect, b, args[curArg])
+			curArg++
+		case '$':
+			if dialect != DIALECT_POSTGRESQL {
+				b = append(b, c)
+				continue
+			}
+			end := x + 1
+			for end < n && qs[end] >= '0' && qs[end] <= '9' {
+				end++
+			}
+			pos, err := strconv.Atoi(qs[x+1 : end])
+			if err != nil || pos < 1 || pos > len(args) {
+				b = append(b, c)
+				continue
+			}
+			b = appendLiteral(dialect, b, args[pos-1])
+			x = end - 1
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// Appends the dialect-specific SQL literal representation of the supplied
+// argument to the supplied byte slice and returns the extended byte slice
+func appendLiteral(dialect Dialect, b []byte, arg interface{}) []byte {
+	switch v := arg.(type) {
+	case nil:
+		return append(b, "NULL"...)
+	case *NamedParam:
+		// Unbound params are output using the common :name notation
+		b = append(b, ':')
+		return append(b, v.name...)
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return appendStringLiteral(dialect, b, err.Error())
+		}
+		if _, isValuer := dv.(driver.Valuer); isValuer {
+			// Guard against a Valuer that returns itself
+			return appendStringLiteral(dialect, b, fmt.Sprint(dv))
+		}
+		return appendLiteral(dialect, b, dv)
+	case string:
+		return appendStringLiteral(dialect, b, v)
+	case []byte:
+		if v == nil {
+			return append(b, "NULL"...)
+		}
+		return appendBytesLiteral(dialect, b, v)
+	case bool:
+		if v {
+			return append(b, "TRUE"...)
+		}
+		return append(b, "FALSE"...)
+	case time.Time:
+		if dialect == DIALECT_POSTGRESQL {
+			return appendStringLiteral(dialect, b, v.Format(postgresqlTimeFormat))
+		}
+		return appendStringLiteral(dialect, b, v.Format(mysqlTimeFormat))
+	}
+	// Handle named types (e.g. type Status string) and pointers by looking at
+	// the underlying kind of the argument
+	rv := reflect.ValueOf(arg)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return append(b, "NULL"...)
+		}
+		return appendLiteral(dialect, b, rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(b, rv.Uint(), 10)
+	case reflect.Float32:
+		return appendFloatLiteral(dialect, b, rv.Float(), 32)
+	case reflect.Float64:
+		return appendFloatLiteral(dialect, b, rv.Float(), 64)
+	case reflect.Bool:
+		return appendLiteral(dialect, b, rv.Bool())
+	case reflect.String:
+		return appendStringLiteral(dialect, b, rv.String())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return appendLiteral(dialect, b, rv.Bytes())
+		}
+	}
+	return appendStringLiteral(dialect, b, fmt.Sprint(arg))
+}
+
+// Appends the shortest representation of the float that round trips at the
+// supplied bit size, so that float32(0.1) is output as 0.1. NaN and the
+// infinities have no numeric literal and are output as the quoted strings
+// that PostgreSQL accepts for them.
+func appendFloatLiteral(dialect Dialect, b []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return appendStringLiteral(dialect, b, "NaN")
+	case math.IsInf(f, 1):
+		return appendStringLiteral(dialect, b, "Infinity")
+	case math.IsInf(f, -1):
+		return appendStringLiteral(dialect, b, "-Infinity")
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, bitSize)
+}
+
+// Appends a quoted, escaped string literal to the supplied byte slice. MySQL
+// treats the backslash as an escape character in string literals while
+// PostgreSQL (with standard_conforming_strings enabled, the default since
+// 9.1) and standard SQL only require single quotes to be doubled.
+func appendStringLiteral(dialect Dialect, b []byte, s string) []byte {
+	b = append(b, '\'')
+	for x := 0; x < len(s); x++ {
+		c := s[x]
+		if dialect == DIALECT_MYSQL {
+			switch c {
+			case 0:
+				b = append(b, '\\', '0')
+				continue
+			case '\n':
+				b = append(b, '\\', 'n')
+				continue
+			case '\r':
+				b = append(b, '\\', 'r')
+				continue
+			case '\x1a':
+				b = append(b, '\\', 'Z')
+				continue
+			case '\\', '\'', '"':
+				b = append(b, '\\', c)
+				continue
+			}
+		}
+		if c == '\'' {
+			b = append(b, '\'')
+		}
+		b = append(b, c)
+	}
+	return append(b, '\'')
+}
+
+// Appends a hex-encoded binary string literal to the supplied byte slice.
+// MySQL uses the X'0A0B' form and PostgreSQL uses the bytea hex format
+// '\x0a0b'.
+func appendBytesLiteral(dialect Dialect, b []byte, v []byte) []byte {
+	if dialect == DIALECT_POSTGRESQL {
+		b = append(b, '\'', '\\', 'x')
+	} else {
+		b = append(b, 'X', '\'')
+	}
+	b = append(b, hex.EncodeToString(v)...)
+	return append(b, '\'')
+}
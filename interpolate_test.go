@@ -0,0 +1,162 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2017, 5, 1, 12, 30, 15, 0, time.UTC)
+	name := "foo"
+	var nilName *string
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		qs      string
+		qargs   []interface{}
+		exp     string
+	}{
+		{
+			name:    "MySQL string with quotes and backslashes",
+			dialect: DIALECT_MYSQL,
+			qs:      "SELECT users.id FROM users WHERE users.name = ?",
+			qargs:   []interface{}{`O'Reilly \ "Co"`},
+			exp:     `SELECT users.id FROM users WHERE users.name = 'O\'Reilly \\ \"Co\"'`,
+		},
+		{
+			name:    "PostgreSQL string with quotes and backslashes",
+			dialect: DIALECT_POSTGRESQL,
+			qs:      "SELECT users.id FROM users WHERE users.name = $1",
+			qargs:   []interface{}{`O'Reilly \ "Co"`},
+			exp:     `SELECT users.id FROM users WHERE users.name = 'O''Reilly \ "Co"'`,
+		},
+		{
+			name:    "MySQL scalar types",
+			dialect: DIALECT_MYSQL,
+			qs:      "INSERT INTO t (a, b, c, d, e, f) VALUES (?, ?, ?, ?, ?, ?)",
+			qargs:   []interface{}{1, uint8(2), 1.5, true, nil, ts},
+			exp:     "INSERT INTO t (a, b, c, d, e, f) VALUES (1, 2, 1.5, TRUE, NULL, '2017-05-01 12:30:15')",
+		},
+		{
+			name:    "float32 output at its own precision",
+			dialect: DIALECT_MYSQL,
+			qs:      "SELECT ?, ?",
+			qargs:   []interface{}{float32(0.1), float64(float32(0.1))},
+			exp:     "SELECT 0.1, 0.10000000149011612",
+		},
+		{
+			name:    "NaN and infinities are quoted",
+			dialect: DIALECT_POSTGRESQL,
+			qs:      "SELECT $1, $2, $3",
+			qargs:   []interface{}{math.NaN(), math.Inf(1), float32(math.Inf(-1))},
+			exp:     "SELECT 'NaN', 'Infinity', '-Infinity'",
+		},
+		{
+			name:    "PostgreSQL time",
+			dialect: DIALECT_POSTGRESQL,
+			qs:      "SELECT $1",
+			qargs:   []interface{}{ts},
+			exp:     "SELECT '2017-05-01 12:30:15+00:00'",
+		},
+		{
+			name:    "MySQL bytes",
+			dialect: DIALECT_MYSQL,
+			qs:      "SELECT ?",
+			qargs:   []interface{}{[]byte{0xde, 0xad}},
+			exp:     "SELECT X'dead'",
+		},
+		{
+			name:    "PostgreSQL bytes",
+			dialect: DIALECT_POSTGRESQL,
+			qs:      "SELECT $1",
+			qargs:   []interface{}{[]byte{0xde, 0xad}},
+			exp:     `SELECT '\xdead'`,
+		},
+		{
+			name:    "driver.Valuer and pointers",
+			dialect: DIALECT_MYSQL,
+			qs:      "SELECT ?, ?, ?, ?",
+			qargs: []interface{}{
+				sql.NullString{String: "bar", Valid: true},
+				sql.NullInt64{},
+				&name,
+				nilName,
+			},
+			exp: "SELECT 'bar', NULL, 'foo', NULL",
+		},
+		{
+			name:    "PostgreSQL out of order and repeated markers",
+			dialect: DIALECT_POSTGRESQL,
+			qs:      "SELECT $2, $1, $2",
+			qargs:   []interface{}{1, "a"},
+			exp:     "SELECT 'a', 1, 'a'",
+		},
+		{
+			name:    "PostgreSQL double digit markers",
+			dialect: DIALECT_POSTGRESQL,
+			qs:      "SELECT $1, $10",
+			qargs:   []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			exp:     "SELECT 1, 10",
+		},
+		{
+			name:    "Markers in comments and quoted strings are untouched",
+			dialect: DIALECT_MYSQL,
+			qs:      "/* why? */ SELECT '?', `a?`, ?",
+			qargs:   []interface{}{1},
+			exp:     "/* why? */ SELECT '?', `a?`, 1",
+		},
+		{
+			name:    "Missing arguments leave markers in place",
+			dialect: DIALECT_MYSQL,
+			qs:      "SELECT ?, ?",
+			qargs:   []interface{}{1},
+			exp:     "SELECT 1, ?",
+		},
+	}
+	for _, test := range tests {
+		res := interpolate(test.dialect, test.qs, test.qargs)
+		assert.Equal(test.exp, res, test.name)
+	}
+}
+
+func TestQueryInterpolated(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	m.dialect = DIALECT_POSTGRESQL
+	users := m.Table("users")
+	colUserId := users.C("id")
+	colUserName := users.C("name")
+
+	sq := Select(users).Where(Equal(colUserName, "it's")).Limit(10)
+	assert.Equal(
+		"SELECT users.id, users.name FROM users WHERE users.name = 'it''s' LIMIT 10",
+		sq.Interpolated(),
+	)
+
+	// Interpolation must not alter the query's own SQL string or arguments
+	qs, qargs := sq.StringArgs()
+	assert.Equal("SELECT users.id, users.name FROM users WHERE users.name = $1 LIMIT $2", qs)
+	assert.Equal([]interface{}{"it's", 10}, qargs)
+
+	uq := Update(users, map[string]interface{}{"name": nil}).Where(Equal(colUserId, 1))
+	assert.Equal("UPDATE users SET name = NULL WHERE users.id = 1", uq.Interpolated())
+
+	dq := Delete(users).Where(Equal(colUserId, 1))
+	assert.Equal("DELETE FROM users WHERE users.id = 1", dq.Interpolated())
+
+	iq := Insert(users, map[string]interface{}{"name": "bob"})
+	assert.Equal("INSERT INTO users (name) VALUES ('bob')", iq.Interpolated())
+}
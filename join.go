@@ -5,6 +5,18 @@
 //
 package sqlb
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ERR_JOIN_AUTO_NO_FOREIGN_KEY        = errors.New("Unable to infer join condition. No foreign key relates the joined table to a table in the query.")
+	ERR_JOIN_AUTO_MULTIPLE_FOREIGN_KEYS = errors.New("Unable to infer join condition. More than one foreign key relates the joined table to tables in the query. Use Join() with an explicit ON expression.")
+)
+
 type joinType int
 
 const (
@@ -82,3 +94,93 @@ func OuterJoin(left selection, right selection, on *Expression) *joinClause {
 func CrossJoin(left selection, right selection) *joinClause {
 	return &joinClause{joinType: JOIN_CROSS, left: left, right: right}
 }
+
+// A joinCandidate is a foreign key that relates a table already in a query to
+// a table being joined, oriented so that the referencing columns belong to
+// one of the two tables and the referenced columns to the other
+type joinCandidate struct {
+	fk       *ForeignKey
+	fkTable  *Table
+	refTable *Table
+}
+
+// Returns the ON expression equating the foreign key's referencing columns
+// with its referenced columns, using the columns of the (possibly aliased)
+// tables in the join
+func (jc *joinCandidate) on() *Expression {
+	var on *Expression
+	for x, c := range jc.fk.columns {
+		cond := Equal(jc.fkTable.C(c.name), jc.refTable.C(jc.fk.refColumns[x].name))
+		if on == nil {
+			on = cond
+		} else {
+			on = And(on, cond)
+		}
+	}
+	return on
+}
+
+// Returns whether two tables refer to the same database table, regardless of
+// any alias
+func sameTable(a *Table, b *Table) bool {
+	return a.name == b.name && a.qualifier() == b.qualifier()
+}
+
+// Given the tables already in a query and a table to join to them, returns
+// the ON expression for the join, derived from the single foreign key between the joined table
+// and the query's tables. Returns an error if there is no such foreign key or
+// if there is more than one.
+//
+// A self-referencing foreign key, as in the following:
+//
+// SELECT e.name, m.name FROM employees AS e JOIN employees AS m ON e.manager_id = m.id
+//
+// is always oriented so that the joined table is the referenced side.
+func inferJoin(tables []*Table, right *Table) (*Expression, error) {
+	candidates := make([]*joinCandidate, 0)
+	for _, left := range tables {
+		for _, fk := range left.foreignKeys {
+			if fk.refTable != nil && sameTable(fk.refTable, right) {
+				candidates = append(candidates, &joinCandidate{
+					fk:       fk,
+					fkTable:  left,
+					refTable: right,
+				})
+			}
+		}
+		if sameTable(left, right) {
+			// Self-referencing foreign keys were picked up above
+			continue
+		}
+		for _, fk := range right.foreignKeys {
+			if fk.refTable != nil && sameTable(fk.refTable, left) {
+				candidates = append(candidates, &joinCandidate{
+					fk:       fk,
+					fkTable:  right,
+					refTable: left,
+				})
+			}
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf(
+			"%w Joined table: %s",
+			ERR_JOIN_AUTO_NO_FOREIGN_KEY, right.name,
+		)
+	case 1:
+		return candidates[0].on(), nil
+	}
+	names := make([]string, len(candidates))
+	for x, jc := range candidates {
+		names[x] = jc.fkTable.name + "." + jc.fk.name
+	}
+	// The order of the query's selections is not stable, so sort the names
+	// to make the message deterministic
+	sort.Strings(names)
+	return nil, fmt.Errorf(
+		"%w Joined table: %s. Candidate foreign keys: %s",
+		ERR_JOIN_AUTO_MULTIPLE_FOREIGN_KEYS, right.name,
+		strings.Join(names, ", "),
+	)
+}
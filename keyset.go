@@ -0,0 +1,245 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	ERR_SEEK_NO_SORT_COLUMNS    = errors.New("No sort columns supplied.")
+	ERR_SEEK_WRONG_VALUE_COUNT  = errors.New("The number of seek values does not match the number of sort columns.")
+	ERR_CURSOR_INVALID          = errors.New("Invalid pagination cursor.")
+	ERR_CURSOR_UNSUPPORTED_TYPE = errors.New("Unsupported pagination cursor value type.")
+)
+
+// Orders the query by the supplied sort columns and, if values are supplied,
+// restricts it to the rows that sort after the row with those values. This
+// is keyset (or "seek") pagination, which, unlike LimitWithOffset(), lets the
+// database start each page from an index instead of reading and discarding
+// the rows of the pages before it.
+//
+// values holds the sort columns' values in the last row of the previous page.
+// Pass nil for the first page. Use Limit() for the page size:
+//
+//	q := sqlb.Select(articles).SeekAfter(
+//	    lastValues, articles.C("created_at").Desc(), articles.C("id").Asc(),
+//	).Limit(50)
+//
+// For PostgreSQL, when all sort columns have the same direction, the
+// comparison is a row-value comparison such as
+// (articles.created_at, articles.id) > ($1, $2). Otherwise, the comparison is
+// expanded into a chain such as (articles.created_at < ? OR
+// (articles.created_at = ? AND articles.id > ?)), which handles a mix of
+// ascending and descending sort columns.
+//
+// The sort columns must not be NULL, and the last sort column should be
+// unique, like a primary key, so that no two rows have the same values.
+// SeekAfter() adds the sort columns to the ORDER BY clause, so don't call
+// OrderBy() as well.
+func (q *SelectQuery) SeekAfter(values []interface{}, scols ...*sortColumn) *SelectQuery {
+	if len(scols) == 0 {
+		q.e = ERR_SEEK_NO_SORT_COLUMNS
+		return q
+	}
+	if len(values) > 0 && len(values) != len(scols) {
+		q.e = ERR_SEEK_WRONG_VALUE_COUNT
+		return q
+	}
+	q.sel.addOrderBy(scols...)
+	if len(values) > 0 {
+		q.sel.addWhere(seekExpression(q.scanner.dialect, scols, values))
+	}
+	return q
+}
+
+// Returns the expression that matches the rows that sort after the supplied
+// values
+func seekExpression(dialect Dialect, scols []*sortColumn, values []interface{}) *Expression {
+	if dialect == DIALECT_POSTGRESQL && len(scols) > 1 && sameSortDirection(scols) {
+		cols := make([]element, len(scols))
+		for x, sc := range scols {
+			cols[x] = sc.p
+		}
+		var et exprType = EXP_ROW_GREATER
+		if scols[0].desc {
+			et = EXP_ROW_LESS
+		}
+		return &Expression{
+			scanInfo: exprScanTable[et],
+			elements: []element{&List{elements: cols}, &List{elements: toElements(values...)}},
+		}
+	}
+	// We build the chain from the last sort column so that each column is
+	// compared once for equality, as in (a > ? OR (a = ? AND (b > ? OR (b = ?
+	// AND c > ?))))
+	x := len(scols) - 1
+	e := seekComparison(scols[x], values[x])
+	for x--; x >= 0; x-- {
+		e = Or(seekComparison(scols[x], values[x]), And(Equal(scols[x].p, values[x]), e))
+	}
+	return e
+}
+
+func seekComparison(sc *sortColumn, v interface{}) *Expression {
+	if sc.desc {
+		return LessThan(sc.p, v)
+	}
+	return GreaterThan(sc.p, v)
+}
+
+func sameSortDirection(scols []*sortColumn) bool {
+	for _, sc := range scols[1:] {
+		if sc.desc != scols[0].desc {
+			return false
+		}
+	}
+	return true
+}
+
+// A value in a pagination cursor. The value is stored as a string along with
+// its type so that numbers and times survive the JSON round trip exactly.
+type cursorValue struct {
+	T string `json:"t"`
+	V string `json:"v,omitempty"`
+}
+
+// Returns an opaque, URL-safe pagination cursor that holds the supplied
+// values, typically the values of the sort columns passed to SeekAfter() in
+// the last row of a page. Returns "" if no values are supplied.
+//
+// Values may be nil, bool, string, []byte, time.Time, any integer or float
+// type, or a driver.Valuer that returns one of those.
+//
+// Cursors are encoded, not encrypted or signed, so clients can read and
+// alter them. That is safe for SeekAfter(), which passes the values as query
+// arguments, but don't put anything in a cursor that clients mustn't see.
+func EncodeCursor(values ...interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	cvs := make([]cursorValue, len(values))
+	for x, v := range values {
+		cv, err := toCursorValue(v)
+		if err != nil {
+			return "", err
+		}
+		cvs[x] = cv
+	}
+	b, err := json.Marshal(cvs)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func toCursorValue(v interface{}) (cursorValue, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return cursorValue{}, err
+		}
+		v = dv
+	}
+	switch v := v.(type) {
+	case nil:
+		return cursorValue{T: "n"}, nil
+	case bool:
+		return cursorValue{T: "b", V: strconv.FormatBool(v)}, nil
+	case string:
+		return cursorValue{T: "s", V: v}, nil
+	case []byte:
+		return cursorValue{T: "x", V: base64.RawURLEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return cursorValue{T: "t", V: v.Format(time.RFC3339Nano)}, nil
+	case int:
+		return cursorValue{T: "i", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int8:
+		return cursorValue{T: "i", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int16:
+		return cursorValue{T: "i", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int32:
+		return cursorValue{T: "i", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int64:
+		return cursorValue{T: "i", V: strconv.FormatInt(v, 10)}, nil
+	case uint:
+		return cursorValue{T: "u", V: strconv.FormatUint(uint64(v), 10)}, nil
+	case uint8:
+		return cursorValue{T: "u", V: strconv.FormatUint(uint64(v), 10)}, nil
+	case uint16:
+		return cursorValue{T: "u", V: strconv.FormatUint(uint64(v), 10)}, nil
+	case uint32:
+		return cursorValue{T: "u", V: strconv.FormatUint(uint64(v), 10)}, nil
+	case uint64:
+		return cursorValue{T: "u", V: strconv.FormatUint(v, 10)}, nil
+	case float32:
+		return cursorValue{T: "f", V: strconv.FormatFloat(float64(v), 'g', -1, 32)}, nil
+	case float64:
+		return cursorValue{T: "f", V: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	}
+	return cursorValue{}, fmt.Errorf("%w Type: %T", ERR_CURSOR_UNSUPPORTED_TYPE, v)
+}
+
+// Returns the values held by a cursor returned from EncodeCursor(), ready to
+// be passed to SeekAfter(). Returns nil if the cursor is "", so that a
+// request without a cursor gets the first page:
+//
+//	values, err := sqlb.DecodeCursor(r.URL.Query().Get("cursor"))
+//	...
+//	q := sqlb.Select(articles).SeekAfter(values, articles.C("id").Asc()).Limit(50)
+//
+// Integers are returned as int64 or uint64, floats as float64 and times as
+// time.Time. Returns ERR_CURSOR_INVALID if the cursor is malformed.
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ERR_CURSOR_INVALID
+	}
+	cvs := []cursorValue{}
+	if err := json.Unmarshal(b, &cvs); err != nil || len(cvs) == 0 {
+		return nil, ERR_CURSOR_INVALID
+	}
+	values := make([]interface{}, len(cvs))
+	for x, cv := range cvs {
+		v, err := fromCursorValue(cv)
+		if err != nil {
+			return nil, ERR_CURSOR_INVALID
+		}
+		values[x] = v
+	}
+	return values, nil
+}
+
+func fromCursorValue(cv cursorValue) (interface{}, error) {
+	switch cv.T {
+	case "n":
+		return nil, nil
+	case "b":
+		return strconv.ParseBool(cv.V)
+	case "s":
+		return cv.V, nil
+	case "x":
+		return base64.RawURLEncoding.DecodeString(cv.V)
+	case "t":
+		return time.Parse(time.RFC3339Nano, cv.V)
+	case "i":
+		return strconv.ParseInt(cv.V, 10, 64)
+	case "u":
+		return strconv.ParseUint(cv.V, 10, 64)
+	case "f":
+		return strconv.ParseFloat(cv.V, 64)
+	}
+	return nil, ERR_CURSOR_INVALID
+}
@@ -0,0 +1,153 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeekAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles")
+
+	pm := testFixtureMeta()
+	pm.dialect = DIALECT_POSTGRESQL
+	pusers := pm.Table("users")
+
+	tests := []struct {
+		name  string
+		q     *SelectQuery
+		qs    string
+		qargs []interface{}
+	}{
+		{
+			name:  "First page",
+			q:     Select(users).SeekAfter(nil, users.C("id").Asc()).Limit(10),
+			qs:    "SELECT users.id, users.name FROM users ORDER BY users.id LIMIT ?",
+			qargs: []interface{}{10},
+		},
+		{
+			name:  "Single sort column",
+			q:     Select(users).SeekAfter([]interface{}{5}, users.C("id").Desc()).Limit(10),
+			qs:    "SELECT users.id, users.name FROM users WHERE users.id < ? ORDER BY users.id DESC LIMIT ?",
+			qargs: []interface{}{5, 10},
+		},
+		{
+			name:  "MySQL expands the comparison",
+			q:     Select(users).SeekAfter([]interface{}{"fred", 5}, users.C("name").Asc(), users.C("id").Asc()),
+			qs:    "SELECT users.id, users.name FROM users WHERE (users.name > ? OR (users.name = ? AND users.id > ?)) ORDER BY users.name, users.id",
+			qargs: []interface{}{"fred", "fred", 5},
+		},
+		{
+			name: "Mixed directions",
+			q: Select(articles.C("id")).SeekAfter(
+				[]interface{}{1, "draft", 5},
+				articles.C("author").Asc(), articles.C("state").Desc(), articles.C("id").Asc(),
+			),
+			qs:    "SELECT articles.id FROM articles WHERE (articles.author > ? OR (articles.author = ? AND (articles.state < ? OR (articles.state = ? AND articles.id > ?)))) ORDER BY articles.author, articles.state DESC, articles.id",
+			qargs: []interface{}{1, 1, "draft", "draft", 5},
+		},
+		{
+			name:  "Combined with another filter",
+			q:     Select(users.C("id")).Where(Equal(users.C("name"), "fred")).SeekAfter([]interface{}{5}, users.C("id").Asc()),
+			qs:    "SELECT users.id FROM users WHERE users.name = ? AND users.id > ? ORDER BY users.id",
+			qargs: []interface{}{"fred", 5},
+		},
+		{
+			name:  "PostgreSQL row-value comparison",
+			q:     Select(pusers).SeekAfter([]interface{}{"fred", 5}, pusers.C("name").Asc(), pusers.C("id").Asc()).Limit(10),
+			qs:    "SELECT users.id, users.name FROM users WHERE (users.name, users.id) > ($1, $2) ORDER BY users.name, users.id LIMIT $3",
+			qargs: []interface{}{"fred", 5, 10},
+		},
+		{
+			name:  "PostgreSQL descending row-value comparison",
+			q:     Select(pusers).SeekAfter([]interface{}{"fred", 5}, pusers.C("name").Desc(), pusers.C("id").Desc()),
+			qs:    "SELECT users.id, users.name FROM users WHERE (users.name, users.id) < ($1, $2) ORDER BY users.name DESC, users.id DESC",
+			qargs: []interface{}{"fred", 5},
+		},
+		{
+			name:  "PostgreSQL mixed directions",
+			q:     Select(pusers).SeekAfter([]interface{}{"fred", 5}, pusers.C("name").Desc(), pusers.C("id").Asc()),
+			qs:    "SELECT users.id, users.name FROM users WHERE (users.name < $1 OR (users.name = $2 AND users.id > $3)) ORDER BY users.name DESC, users.id",
+			qargs: []interface{}{"fred", "fred", 5},
+		},
+	}
+	for _, test := range tests {
+		assert.Nil(test.q.Error(), test.name)
+		qs, qargs := test.q.StringArgs()
+		assert.Equal(test.qs, qs, test.name)
+		assert.Equal(test.qargs, qargs, test.name)
+	}
+
+	q := Select(pusers).SeekAfter([]interface{}{"fred", 5}, pusers.C("name").Asc(), pusers.C("id").Asc())
+	assert.Equal(
+		"SELECT users.id, users.name FROM users WHERE (users.name, users.id) > (?, ?) ORDER BY users.name, users.id",
+		q.Fingerprint().SQL,
+	)
+
+	q = Select(users).SeekAfter([]interface{}{5})
+	assert.Equal(ERR_SEEK_NO_SORT_COLUMNS, q.Error())
+	q = Select(users).SeekAfter([]interface{}{5}, users.C("name").Asc(), users.C("id").Asc())
+	assert.Equal(ERR_SEEK_WRONG_VALUE_COUNT, q.Error())
+}
+
+func TestCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.FixedZone("X", 3600))
+	cursor, err := EncodeCursor(
+		int32(-7), uint64(1<<63), 1.5, "fred", []byte{0, 1, 2}, true, nil, created,
+		sql.NullString{String: "barney", Valid: true},
+	)
+	assert.Nil(err)
+	assert.NotContains(cursor, "fred")
+	assert.Regexp("^[A-Za-z0-9_-]+$", cursor)
+
+	values, err := DecodeCursor(cursor)
+	assert.Nil(err)
+	assert.Equal(9, len(values))
+	assert.Equal(int64(-7), values[0])
+	assert.Equal(uint64(1<<63), values[1])
+	assert.Equal(1.5, values[2])
+	assert.Equal("fred", values[3])
+	assert.Equal([]byte{0, 1, 2}, values[4])
+	assert.Equal(true, values[5])
+	assert.Nil(values[6])
+	assert.True(created.Equal(values[7].(time.Time)))
+	assert.Equal("barney", values[8])
+
+	// No cursor means the first page
+	cursor, err = EncodeCursor()
+	assert.Nil(err)
+	assert.Equal("", cursor)
+	values, err = DecodeCursor("")
+	assert.Nil(err)
+	assert.Nil(values)
+
+	_, err = EncodeCursor(struct{}{})
+	assert.True(errors.Is(err, ERR_CURSOR_UNSUPPORTED_TYPE))
+
+	invalid := []string{
+		"not a cursor!",
+		base64.RawURLEncoding.EncodeToString([]byte(`{}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`[]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`[{"t":"q","v":"1"}]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`[{"t":"i","v":"one"}]`)),
+	}
+	for _, c := range invalid {
+		_, err = DecodeCursor(c)
+		assert.Equal(ERR_CURSOR_INVALID, err, c)
+	}
+}
@@ -35,12 +35,12 @@ func (lc *limitClause) scan(scanner *sqlScanner, b []byte, args []interface{}, c
 	bw := 0
 	bw += copy(b[bw:], scanner.format.SeparateClauseWith)
 	bw += copy(b[bw:], Symbols[SYM_LIMIT])
-	bw += scanInterpolationMarker(scanner.dialect, b[bw:], *curArg)
+	bw += scanner.scanMarker(b[bw:], *curArg)
 	args[*curArg] = lc.limit
 	*curArg++
 	if lc.offset != nil {
 		bw += copy(b[bw:], Symbols[SYM_OFFSET])
-		bw += scanInterpolationMarker(scanner.dialect, b[bw:], *curArg)
+		bw += scanner.scanMarker(b[bw:], *curArg)
 		args[*curArg] = *lc.offset
 		*curArg++
 	}
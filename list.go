@@ -19,6 +19,17 @@ func (l *List) argCount() int {
 	return ac
 }
 
+// Returns whether every element of the list is a query argument, as in the
+// list of values supplied to In()
+func (l *List) valuesOnly() bool {
+	for _, el := range l.elements {
+		if _, ok := el.(*value); !ok {
+			return false
+		}
+	}
+	return len(l.elements) > 0
+}
+
 func (l *List) size(scanner *sqlScanner) int {
 	nels := len(l.elements)
 	size := 0
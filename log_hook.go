@@ -0,0 +1,226 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"log/slog"
+)
+
+// The value that redacted query arguments are replaced with
+const REDACTED_VALUE = "[REDACTED]"
+
+// Returns a copy of the supplied arguments of the query in which the
+// arguments bound to any of the supplied columns are replaced with
+// REDACTED_VALUE. Arguments are bound to a column if they are the value of an
+// INSERT or UPDATE for the column or if they are compared to the column in a
+// WHERE clause, as in Equal(users.C("email"), email) or
+// In(users.C("email"), a, b). Other arguments are left as they are.
+func RedactArgs(q Query, args []interface{}, cols ...*Column) []interface{} {
+	res := make([]interface{}, len(args))
+	copy(res, args)
+	if len(cols) == 0 {
+		return res
+	}
+	for x, c := range argColumns(q, len(args)) {
+		if c != nil && containsSameColumn(cols, c) {
+			res[x] = REDACTED_VALUE
+		}
+	}
+	return res
+}
+
+func containsSameColumn(cols []*Column, c *Column) bool {
+	for _, cc := range cols {
+		if cc.name == c.name && isSameTable(cc.tbl, c.tbl) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the column each of the query's n arguments is bound to, or nil for
+// arguments that are not bound to a column
+func argColumns(q Query, n int) []*Column {
+	cols := make([]*Column, 0, n)
+	switch q := q.(type) {
+	case *InsertQuery:
+		if q.stmt != nil {
+			cols = append(cols, q.stmt.columns...)
+		}
+	case *UpdateQuery:
+		if q.stmt != nil {
+			cols = append(cols, q.stmt.columns...)
+			cols = appendWhereColumns(cols, q.stmt.where)
+		}
+	case *DeleteQuery:
+		if q.stmt != nil {
+			cols = appendWhereColumns(cols, q.stmt.where)
+		}
+	case *SelectQuery:
+		if s := q.sel; s != nil {
+			// The arguments of the projections, selections and joins come
+			// before those of the WHERE clause
+			for _, p := range s.projs {
+				cols = appendUnbound(cols, p.argCount())
+			}
+			for _, sel := range s.selections {
+				cols = appendUnbound(cols, sel.argCount())
+			}
+			for _, j := range s.joins {
+				cols = appendUnbound(cols, j.argCount())
+			}
+			cols = appendWhereColumns(cols, s.where)
+		}
+	}
+	for len(cols) < n {
+		cols = append(cols, nil)
+	}
+	return cols[:n]
+}
+
+func appendUnbound(cols []*Column, count int) []*Column {
+	for x := 0; x < count; x++ {
+		cols = append(cols, nil)
+	}
+	return cols
+}
+
+func appendWhereColumns(cols []*Column, w *whereClause) []*Column {
+	if w == nil {
+		return cols
+	}
+	for _, e := range w.filters {
+		cols = appendExpressionColumns(cols, e)
+	}
+	return cols
+}
+
+// Appends the columns the expression's arguments are bound to. The values in
+// an expression are bound to the first column among its elements, which is
+// the subject of comparisons such as Equal(), In() and Between().
+func appendExpressionColumns(cols []*Column, e *Expression) []*Column {
+	subject := subjectColumn(e.elements)
+	for _, el := range e.elements {
+		switch el := el.(type) {
+		case *Expression:
+			cols = appendExpressionColumns(cols, el)
+		case *value:
+			cols = append(cols, subject)
+		case *List:
+			// In a row-value comparison, such as the one produced by
+			// SeekAfter(), each value is bound to the column in the same
+			// position of the row of columns
+			row, isRow := e.elements[0].(*List)
+			isRow = isRow && row != el && len(row.elements) == len(el.elements)
+			for x, lel := range el.elements {
+				if _, ok := lel.(*value); !ok {
+					cols = appendUnbound(cols, lel.argCount())
+				} else if isRow {
+					c, _ := row.elements[x].(*Column)
+					cols = append(cols, c)
+				} else {
+					cols = append(cols, subject)
+				}
+			}
+		default:
+			cols = appendUnbound(cols, el.argCount())
+		}
+	}
+	return cols
+}
+
+// Returns the first column among the supplied elements, looking inside the
+// arguments of SQL functions, so that the subject of Equal(Reverse(col), v) is
+// col
+func subjectColumn(els []element) *Column {
+	for _, el := range els {
+		var c *Column
+		switch el := el.(type) {
+		case *Column:
+			c = el
+		case *sqlFunc:
+			c = subjectColumn(el.elements)
+		case *List:
+			c = subjectColumn(el.elements)
+		}
+		if c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// A LogHook is a Hook that logs each query, with its kind, SQL string,
+// arguments, duration, number of rows and error, to a structured logger.
+// Successful queries are logged at the INFO level and failed queries at the
+// ERROR level.
+type LogHook struct {
+	logger    *slog.Logger
+	redact    []*Column
+	redactAll bool
+}
+
+// A LogHookOption modifies what a LogHook logs
+type LogHookOption func(*LogHook)
+
+// Replaces the arguments bound to the supplied columns with REDACTED_VALUE in
+// the logged arguments. See RedactArgs() for which arguments are bound to a
+// column.
+func RedactColumns(cols ...*Column) LogHookOption {
+	return func(h *LogHook) {
+		h.redact = append(h.redact, cols...)
+	}
+}
+
+// Replaces every argument with REDACTED_VALUE in the logged arguments
+func RedactAllArgs() LogHookOption {
+	return func(h *LogHook) {
+		h.redactAll = true
+	}
+}
+
+// Returns a LogHook that logs to the supplied logger
+func NewLogHook(logger *slog.Logger, opts ...LogHookOption) *LogHook {
+	h := &LogHook{logger: logger}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *LogHook) BeforeQuery(ctx context.Context, ev *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *LogHook) AfterQuery(ctx context.Context, ev *QueryEvent) {
+	attrs := []slog.Attr{
+		slog.String("kind", ev.Kind.String()),
+		slog.String("sql", ev.SQL),
+		slog.Any("args", h.args(ev)),
+		slog.Duration("duration", ev.Duration),
+	}
+	if ev.Rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", ev.Rows))
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, slog.Any("error", ev.Err))
+		h.logger.LogAttrs(ctx, slog.LevelError, "query failed", attrs...)
+		return
+	}
+	h.logger.LogAttrs(ctx, slog.LevelInfo, "query", attrs...)
+}
+
+func (h *LogHook) args(ev *QueryEvent) []interface{} {
+	if h.redactAll {
+		res := make([]interface{}, len(ev.Args))
+		for x := range res {
+			res[x] = REDACTED_VALUE
+		}
+		return res
+	}
+	return RedactArgs(ev.Query, ev.Args, h.redact...)
+}
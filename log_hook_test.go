@@ -0,0 +1,146 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles")
+	name := users.C("name")
+
+	pm := testFixtureMeta()
+	pm.dialect = DIALECT_POSTGRESQL
+	pusers := pm.Table("users")
+
+	tests := []struct {
+		name string
+		q    Query
+		exp  []interface{}
+	}{
+		{
+			name: "INSERT",
+			q:    Insert(users, map[string]interface{}{"name": "fred"}),
+			exp:  []interface{}{REDACTED_VALUE},
+		},
+		{
+			name: "UPDATE with WHERE",
+			q: users.Update(map[string]interface{}{"name": "fred"}).Where(
+				Equal(users.C("id"), 1),
+			),
+			exp: []interface{}{REDACTED_VALUE, 1},
+		},
+		{
+			name: "DELETE with nested expressions",
+			q: Delete(users).Where(Or(
+				Equal(name, "fred"),
+				And(In(users.C("id"), 1, 2), In(name, "barney", "wilma")),
+			)),
+			exp: []interface{}{REDACTED_VALUE, 1, 2, REDACTED_VALUE, REDACTED_VALUE},
+		},
+		{
+			name: "SELECT through a table alias",
+			q: Select(articles.C("id")).Where(And(
+				Equal(articles.C("author"), 1),
+				Equal(users.As("u").C("name"), "fred"),
+			)),
+			exp: []interface{}{1, REDACTED_VALUE},
+		},
+		{
+			name: "SELECT comparing functions of a column",
+			q: Select(users.C("id")).Where(Or(
+				Equal(Reverse(name), "derf"),
+				Equal(ConcatWs(" ", name, users.C("id")), "fred 1"),
+			)),
+			exp: []interface{}{REDACTED_VALUE, " ", REDACTED_VALUE},
+		},
+		{
+			name: "SELECT with a row-value comparison",
+			q: Select(pusers.C("id")).SeekAfter(
+				[]interface{}{"fred", 5}, pusers.C("name").Asc(), pusers.C("id").Asc(),
+			),
+			exp: []interface{}{REDACTED_VALUE, 5},
+		},
+	}
+	for _, test := range tests {
+		_, args := test.q.StringArgs()
+		assert.Equal(test.exp, RedactArgs(test.q, args, name, pusers.C("name")), test.name)
+		assert.Equal(args, RedactArgs(test.q, args), test.name)
+	}
+	// Arguments of a SQL string run directly are not bound to columns
+	assert.Equal([]interface{}{"fred"}, RedactArgs(nil, []interface{}{"fred"}, name))
+}
+
+func TestLogHook(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	q := users.Insert(map[string]interface{}{"name": "fred"})
+	qs, args := q.StringArgs()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	entry := func() map[string]interface{} {
+		line := strings.TrimSpace(buf.String())
+		buf.Reset()
+		res := map[string]interface{}{}
+		assert.Nil(json.Unmarshal([]byte(line), &res))
+		return res
+	}
+
+	h := NewLogHook(logger, RedactColumns(users.C("name")))
+	ev := &QueryEvent{
+		Query:    q,
+		Kind:     QUERY_KIND_INSERT,
+		SQL:      qs,
+		Args:     args,
+		Duration: 3 * time.Millisecond,
+		Rows:     1,
+	}
+	assert.Equal(ctx, h.BeforeQuery(ctx, ev))
+	h.AfterQuery(ctx, ev)
+	e := entry()
+	assert.Equal("INFO", e["level"])
+	assert.Equal("query", e["msg"])
+	assert.Equal("insert", e["kind"])
+	assert.Equal("INSERT INTO users (name) VALUES (?)", e["sql"])
+	assert.Equal([]interface{}{REDACTED_VALUE}, e["args"])
+	assert.Equal(float64(3*time.Millisecond), e["duration"])
+	assert.Equal(float64(1), e["rows"])
+	// The event's arguments are not modified
+	assert.Equal([]interface{}{"fred"}, ev.Args)
+
+	ev.Rows = -1
+	ev.Err = errors.New("boom")
+	NewLogHook(logger).AfterQuery(ctx, ev)
+	e = entry()
+	assert.Equal("ERROR", e["level"])
+	assert.Equal("query failed", e["msg"])
+	assert.Equal("boom", e["error"])
+	assert.Equal([]interface{}{"fred"}, e["args"])
+	_, found := e["rows"]
+	assert.False(found)
+
+	ev.Query = nil
+	NewLogHook(logger, RedactAllArgs()).AfterQuery(ctx, ev)
+	assert.Equal([]interface{}{REDACTED_VALUE}, entry()["args"])
+}
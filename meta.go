@@ -6,8 +6,13 @@
 package sqlb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -21,150 +26,598 @@ type Meta struct {
 	db         *sql.DB
 	dialect    Dialect
 	schemaName string
-	tables     map[string]*Table
+	// The schema that unqualified table names belong to. Tables in this
+	// schema are output without a schema qualifier. For MySQL, this is the
+	// current database. For PostgreSQL, this is the first schema in the
+	// search_path, usually "public".
+	defaultSchema string
+	// The schemas that Reflect() discovers tables in. If empty, only the
+	// default schema is reflected.
+	schemas []string
+	// Tables keyed by table name for tables in the default schema and by
+	// "schema.table" for tables in other schemas
+	tables map[string]*Table
 }
 
 func NewMeta(dialect Dialect, schemaName string) *Meta {
+	defaultSchema := schemaName
+	if dialect == DIALECT_POSTGRESQL {
+		defaultSchema = "public"
+	}
 	return &Meta{
-		schemaName: schemaName,
-		tables:     make(map[string]*Table, 0),
+		dialect:       dialect,
+		schemaName:    schemaName,
+		defaultSchema: defaultSchema,
+		tables:        make(map[string]*Table, 0),
 	}
 }
 
-// Create and return a new Table with the given table name.
+// Create and return a new Table with the given table name. The name may be
+// qualified with a schema name, as in "billing.invoices", in which case the
+// table is placed in that schema.
 func (m *Meta) NewTable(name string) *Table {
-	t, exists := m.tables[name]
+	schema, tname := splitTableName(name)
+	key := m.tableKey(schema, tname)
+	t, exists := m.tables[key]
 	if exists {
 		return t
 	}
-	t = &Table{meta: m, name: name}
-	m.tables[name] = t
+	t = &Table{meta: m, schema: schema, name: tname}
+	m.tables[key] = t
 	return t
 }
 
+// Create and return a new read-only view with the given name. Views are
+// represented as Tables that may be selected from. Use Table.SetUpdatable()
+// to allow Insert(), Update() and Delete() against an updatable view.
+func (m *Meta) NewView(name string) *Table {
+	t := m.NewTable(name)
+	t.kind = TABLE_KIND_VIEW
+	return t
+}
+
+// Create and return a new materialized view with the given name. Materialized
+// views are represented as read-only Tables that may be selected from and
+// refreshed with RefreshMaterializedView().
+func (m *Meta) NewMaterializedView(name string) *Table {
+	t := m.NewTable(name)
+	t.kind = TABLE_KIND_MATERIALIZED_VIEW
+	return t
+}
+
+// Return a pointer to a Table with a name matching the supplied string, or
+// nil if no such table is known. Tables outside the default schema are
+// addressed with a schema-qualified name, as in "billing.invoices".
 func (m *Meta) Table(name string) *Table {
-	t, found := m.tables[name]
+	t, found := m.tables[m.tableKey(splitTableName(name))]
 	if !found {
 		return nil
 	}
 	return t
 }
 
-func Reflect(dialect Dialect, db *sql.DB, meta *Meta) error {
-	if meta == nil {
-		return ERR_NO_META_STRUCT
+// Returns the tables known to the Meta, sorted by schema-qualified table name
+func (m *Meta) Tables() []*Table {
+	res := make([]*Table, 0, len(m.tables))
+	keys := make([]string, 0, len(m.tables))
+	for key := range m.tables {
+		keys = append(keys, key)
 	}
-	schemaName := getSchemaName(dialect, db)
-	var qs string
-	switch dialect {
-	case DIALECT_MYSQL:
-		qs = `
-SELECT t.TABLE_NAME
-FROM INFORMATION_SCHEMA.TABLES AS t
-WHERE t.TABLE_TYPE = 'BASE TABLE'
-AND t.TABLE_SCHEMA = ?
-ORDER BY t.TABLE_NAME
-`
-	case DIALECT_POSTGRESQL:
-		qs = `
-SELECT t.TABLE_NAME
-FROM INFORMATION_SCHEMA.TABLES AS t
-WHERE t.TABLE_SCHEMA = 'public'
-AND t.TABLE_CATALOG = $1
-AND t.TABLE_TYPE = 'BASE TABLE'
-ORDER BY t.TABLE_NAME
-`
+	sort.Strings(keys)
+	for _, key := range keys {
+		res = append(res, m.tables[key])
 	}
-	// Grab information about all tables in the schema
-	rows, err := db.Query(qs, schemaName)
-	if err != nil {
-		return err
+	return res
+}
+
+// Sets the schemas that Reflect() discovers tables in. Tables in schemas other
+// than the default schema are addressed as "schema.table".
+func (m *Meta) SetSchemas(schemas ...string) *Meta {
+	m.schemas = schemas
+	return m
+}
+
+// Returns the schemas that Reflect() discovers tables in
+func (m *Meta) Schemas() []string {
+	if len(m.schemas) == 0 && m.defaultSchema != "" {
+		return []string{m.defaultSchema}
 	}
-	meta.schemaName = schemaName
-	tables := make(map[string]*Table, 0)
-	for rows.Next() {
-		t := &Table{meta: meta}
-		err = rows.Scan(&t.name)
-		if err != nil {
-			return err
-		}
-		tables[t.name] = t
+	return m.schemas
+}
+
+// Returns the schema that unqualified table names belong to
+func (m *Meta) DefaultSchema() string {
+	return m.defaultSchema
+}
+
+// Returns the key of a table in the Meta's tables map
+func (m *Meta) tableKey(schema string, name string) string {
+	if schema == "" || schema == m.defaultSchema {
+		return name
 	}
-	if err = fillTableColumns(db, dialect, schemaName, &tables); err != nil {
-		return err
+	return schema + "." + name
+}
+
+// Splits a possibly schema-qualified table name into its schema and table
+// name parts. The schema is "" for an unqualified name.
+func splitTableName(name string) (string, string) {
+	if x := strings.IndexByte(name, '.'); x >= 0 {
+		return name[:x], name[x+1:]
 	}
-	meta.tables = tables
-	meta.db = db
-	meta.dialect = dialect
-	return nil
+	return "", name
+}
+
+// A reflectScope describes the database objects that Reflect() examines
+type reflectScope struct {
+	// The current database. For PostgreSQL, the information schema's
+	// TABLE_CATALOG columns are filtered on this.
+	catalog string
+	schemas []string
+}
+
+// Returns the query arguments for a reflection query and an SQL expression
+// comparing the supplied column to the scope's schemas, e.g.
+// "c.TABLE_SCHEMA IN ($2, $3)". For PostgreSQL, the first query argument is
+// the scope's catalog.
+func (rs *reflectScope) filter(dialect Dialect, col string) (string, []interface{}) {
+	qargs := make([]interface{}, 0, len(rs.schemas)+1)
+	if dialect == DIALECT_POSTGRESQL {
+		qargs = append(qargs, rs.catalog)
+	}
+	b := make([]byte, 0, len(col)+8+len(rs.schemas)*4)
+	b = append(b, col...)
+	b = append(b, " IN ("...)
+	for x, schema := range rs.schemas {
+		if x > 0 {
+			b = append(b, ", "...)
+		}
+		qargs = append(qargs, schema)
+		if dialect == DIALECT_POSTGRESQL {
+			b = append(b, '$')
+			b = strconv.AppendInt(b, int64(len(qargs)), 10)
+		} else {
+			b = append(b, '?')
+		}
+	}
+	b = append(b, ')')
+	return string(b), qargs
+}
+
+// Returns the key of a table in the map of tables being reflected
+func reflectKey(schema string, name string) string {
+	return schema + "." + name
 }
 
 // Grabs column information from the information schema and populates the
 // supplied map of TableDef descriptors' columns
-func fillTableColumns(db *sql.DB, dialect Dialect, schemaName string, tables *map[string]*Table) error {
+func fillTableColumns(ctx context.Context, db *sql.DB, dialect Dialect, scope *reflectScope, tables *map[string]*Table) error {
 	var qs string
 	switch dialect {
 	case DIALECT_MYSQL:
 		qs = `
-SELECT c.TABLE_NAME, c.COLUMN_NAME
+SELECT c.TABLE_SCHEMA, c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE, c.COLUMN_TYPE,
+ c.CHARACTER_MAXIMUM_LENGTH, c.NUMERIC_PRECISION, c.NUMERIC_SCALE,
+ c.IS_NULLABLE, c.COLUMN_DEFAULT, c.EXTRA
 FROM INFORMATION_SCHEMA.COLUMNS AS c
 JOIN INFORMATION_SCHEMA.TABLES AS t
  ON t.TABLE_SCHEMA = c.TABLE_SCHEMA
  AND t.TABLE_NAME = c.TABLE_NAME
-WHERE c.TABLE_SCHEMA = ?
-AND t.TABLE_TYPE = 'BASE TABLE'
-ORDER BY c.TABLE_NAME, c.COLUMN_NAME
+WHERE %s
+AND t.TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION
 `
 	case DIALECT_POSTGRESQL:
 		qs = `
-SELECT c.TABLE_NAME, c.COLUMN_NAME
+SELECT c.TABLE_SCHEMA, c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE, c.UDT_NAME,
+ c.CHARACTER_MAXIMUM_LENGTH, c.NUMERIC_PRECISION, c.NUMERIC_SCALE,
+ c.IS_NULLABLE, c.COLUMN_DEFAULT, c.IS_IDENTITY
 FROM INFORMATION_SCHEMA.COLUMNS AS c
 JOIN INFORMATION_SCHEMA.TABLES AS t
  ON t.TABLE_SCHEMA = c.TABLE_SCHEMA
  AND t.TABLE_NAME = c.TABLE_NAME
-WHERE c.TABLE_SCHEMA = 'public'
-AND c.TABLE_CATALOG = $1
-AND t.TABLE_TYPE = 'BASE TABLE'
-ORDER BY c.TABLE_NAME, c.COLUMN_NAME
+WHERE c.TABLE_CATALOG = $1
+AND %s
+AND t.TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION
 `
 	}
-	rows, err := db.Query(qs, schemaName)
+	filter, qargs := scope.filter(dialect, "c.TABLE_SCHEMA")
+	if err := fillColumnsFromQuery(ctx, db, dialect, fmt.Sprintf(qs, filter), qargs, tables); err != nil {
+		return err
+	}
+	if dialect != DIALECT_POSTGRESQL {
+		return nil
+	}
+	// Materialized views do not appear in PostgreSQL's information schema, so
+	// we need to build the same information from the system catalogs. Length,
+	// precision and scale are decoded from the attribute's type modifier the
+	// same way the information schema does.
+	qs = `
+SELECT n.nspname, c.relname, a.attname,
+ format_type(a.atttypid, NULL), ty.typname,
+ CASE WHEN ty.typname IN ('varchar', 'bpchar') AND a.atttypmod > 4
+  THEN a.atttypmod - 4 END,
+ CASE WHEN ty.typname = 'numeric' AND a.atttypmod > 4
+  THEN ((a.atttypmod - 4) >> 16) & 65535 END,
+ CASE WHEN ty.typname = 'numeric' AND a.atttypmod > 4
+  THEN (a.atttypmod - 4) & 65535 END,
+ CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END,
+ NULL::text, 'NO'
+FROM pg_catalog.pg_attribute AS a
+JOIN pg_catalog.pg_class AS c
+ ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace AS n
+ ON n.oid = c.relnamespace
+JOIN pg_catalog.pg_type AS ty
+ ON ty.oid = a.atttypid
+WHERE $1 = CURRENT_DATABASE()
+AND %s
+AND c.relkind = 'm'
+AND a.attnum > 0
+AND NOT a.attisdropped
+ORDER BY n.nspname, c.relname, a.attnum
+`
+	filter, qargs = scope.filter(dialect, "n.nspname")
+	return fillColumnsFromQuery(ctx, db, dialect, fmt.Sprintf(qs, filter), qargs, tables)
+}
+
+// Executes a query returning column information and populates the supplied
+// map of TableDef descriptors' columns. The query must return, in order, the
+// schema, table and column names followed by the values expected by
+// newColumnDef()
+func fillColumnsFromQuery(ctx context.Context, db *sql.DB, dialect Dialect, qs string, qargs []interface{}, tables *map[string]*Table) error {
+	rows, err := db.QueryContext(ctx, qs, qargs...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	var t *Table
 	for rows.Next() {
+		var tschema string
 		var tname string
 		var cname string
-		err = rows.Scan(&tname, &cname)
+		var dataType string
+		var fullType string
+		var length sql.NullInt64
+		var precision sql.NullInt64
+		var scale sql.NullInt64
+		var isNullable string
+		var defaultExpr sql.NullString
+		var extra sql.NullString
+		err = rows.Scan(
+			&tschema, &tname, &cname, &dataType, &fullType, &length, &precision,
+			&scale, &isNullable, &defaultExpr, &extra,
+		)
 		if err != nil {
 			return err
 		}
-		t = (*tables)[tname]
+		t = (*tables)[reflectKey(tschema, tname)]
+		if t == nil {
+			continue
+		}
 		if t.columns == nil {
 			t.columns = make([]*Column, 0)
 		}
 		c := &Column{tbl: t, name: cname}
+		c.def = newColumnDef(
+			dialect, dataType, fullType, length, precision, scale,
+			isNullable, defaultExpr, extra,
+		)
 		t.columns = append(t.columns, c)
 	}
-	return nil
+	return rows.Err()
+}
+
+// Returns a columnDef describing a column from the values of the information
+// schema's COLUMNS table. fullType is COLUMN_TYPE for MySQL and UDT_NAME for
+// PostgreSQL. extra is EXTRA for MySQL and IS_IDENTITY for PostgreSQL.
+func newColumnDef(
+	dialect Dialect,
+	dataType string,
+	fullType string,
+	length sql.NullInt64,
+	precision sql.NullInt64,
+	scale sql.NullInt64,
+	isNullable string,
+	defaultExpr sql.NullString,
+	extra sql.NullString,
+) columnDef {
+	def := columnDef{
+		sqlType:   sqlTypeFromRaw(dataType),
+		rawType:   dataType,
+		length:    int(length.Int64),
+		precision: int(precision.Int64),
+		scale:     int(scale.Int64),
+		nullable:  isNullable == "YES",
+	}
+	if defaultExpr.Valid {
+		d := defaultExpr.String
+		def.defaultExpr = &d
+	}
+	switch dialect {
+	case DIALECT_MYSQL:
+		// COLUMN_TYPE includes the length and modifiers, e.g. "int unsigned"
+		// or "varchar(100)"
+		def.rawType = fullType
+		def.autoIncrement = strings.Contains(
+			strings.ToLower(extra.String), "auto_increment",
+		)
+	case DIALECT_POSTGRESQL:
+		// Enums, domains and extension types are reported with a DATA_TYPE
+		// of "USER-DEFINED", with the type's name in UDT_NAME
+		if dataType == "USER-DEFINED" || dataType == "ARRAY" {
+			def.rawType = fullType
+			def.sqlType = sqlTypeFromRaw(fullType)
+		}
+		// SERIAL columns are implemented as a default of nextval() on an
+		// owned sequence
+		def.autoIncrement = extra.String == "YES" ||
+			strings.HasPrefix(defaultExpr.String, "nextval(")
+	}
+	return def
+}
+
+// Grabs PRIMARY KEY and UNIQUE constraint information from the information
+// schema and populates the supplied map of TableDef descriptors' constraints,
+// marking the primary key columns of each table
+func fillConstraints(ctx context.Context, db *sql.DB, dialect Dialect, scope *reflectScope, tables *map[string]*Table) error {
+	var qs string
+	switch dialect {
+	case DIALECT_MYSQL:
+		qs = `
+SELECT kcu.TABLE_SCHEMA, kcu.TABLE_NAME, tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE,
+ kcu.COLUMN_NAME
+FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS tc
+JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS kcu
+ ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+ AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+ AND kcu.TABLE_NAME = tc.TABLE_NAME
+WHERE %s
+AND tc.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE')
+ORDER BY kcu.TABLE_SCHEMA, kcu.TABLE_NAME, tc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION
+`
+	case DIALECT_POSTGRESQL:
+		qs = `
+SELECT kcu.TABLE_SCHEMA, kcu.TABLE_NAME, tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE,
+ kcu.COLUMN_NAME
+FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS tc
+JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS kcu
+ ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+ AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+ AND kcu.TABLE_NAME = tc.TABLE_NAME
+WHERE tc.TABLE_CATALOG = $1
+AND %s
+AND tc.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE')
+ORDER BY kcu.TABLE_SCHEMA, kcu.TABLE_NAME, tc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION
+`
+	}
+	filter, qargs := scope.filter(dialect, "tc.TABLE_SCHEMA")
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(qs, filter), qargs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var con *Constraint
+	for rows.Next() {
+		var tschema string
+		var tname string
+		var conName string
+		var conType string
+		var cname string
+		err = rows.Scan(&tschema, &tname, &conName, &conType, &cname)
+		if err != nil {
+			return err
+		}
+		t := (*tables)[reflectKey(tschema, tname)]
+		if t == nil {
+			continue
+		}
+		c := t.C(cname)
+		if c == nil {
+			continue
+		}
+		if con == nil || con.tbl != t || con.name != conName {
+			ctype := CONSTRAINT_TYPE_UNIQUE
+			if conType == "PRIMARY KEY" {
+				ctype = CONSTRAINT_TYPE_PRIMARY_KEY
+			}
+			con = t.newConstraint(ctype, conName, nil)
+		}
+		con.columns = append(con.columns, c)
+		if con.ctype == CONSTRAINT_TYPE_PRIMARY_KEY {
+			c.def.pkOrdinal = len(con.columns)
+		}
+	}
+	return rows.Err()
 }
 
-// Returns the database schema name given a driver name and a sql.DB handle
-func getSchemaName(dialect Dialect, db *sql.DB) string {
+// Grabs foreign key information from the information schema and populates
+// the supplied map of TableDef descriptors' foreign keys. Foreign keys that
+// reference a table that is not in the map are skipped.
+func fillForeignKeys(ctx context.Context, db *sql.DB, dialect Dialect, scope *reflectScope, tables *map[string]*Table) error {
 	var qs string
 	switch dialect {
 	case DIALECT_MYSQL:
-		qs = "SELECT DATABASE()"
+		qs = `
+SELECT kcu.TABLE_SCHEMA, kcu.TABLE_NAME, rc.CONSTRAINT_NAME, kcu.COLUMN_NAME,
+ kcu.REFERENCED_TABLE_SCHEMA, kcu.REFERENCED_TABLE_NAME,
+ kcu.REFERENCED_COLUMN_NAME,
+ rc.DELETE_RULE, rc.UPDATE_RULE
+FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS AS rc
+JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS kcu
+ ON kcu.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA
+ AND kcu.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+ AND kcu.TABLE_NAME = rc.TABLE_NAME
+WHERE %s
+ORDER BY kcu.TABLE_SCHEMA, kcu.TABLE_NAME, rc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION
+`
 	case DIALECT_POSTGRESQL:
-		qs = "SELECT CURRENT_DATABASE()"
+		// PostgreSQL's KEY_COLUMN_USAGE does not contain the referenced
+		// columns, so we join to the KEY_COLUMN_USAGE of the referenced unique
+		// constraint using POSITION_IN_UNIQUE_CONSTRAINT
+		qs = `
+SELECT kcu.TABLE_SCHEMA, kcu.TABLE_NAME, rc.CONSTRAINT_NAME, kcu.COLUMN_NAME,
+ rkcu.TABLE_SCHEMA, rkcu.TABLE_NAME, rkcu.COLUMN_NAME,
+ rc.DELETE_RULE, rc.UPDATE_RULE
+FROM INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS AS rc
+JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS kcu
+ ON kcu.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA
+ AND kcu.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS rkcu
+ ON rkcu.CONSTRAINT_SCHEMA = rc.UNIQUE_CONSTRAINT_SCHEMA
+ AND rkcu.CONSTRAINT_NAME = rc.UNIQUE_CONSTRAINT_NAME
+ AND rkcu.ORDINAL_POSITION = kcu.POSITION_IN_UNIQUE_CONSTRAINT
+WHERE rc.CONSTRAINT_CATALOG = $1
+AND %s
+ORDER BY kcu.TABLE_SCHEMA, kcu.TABLE_NAME, rc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION
+`
+	}
+	filter, qargs := scope.filter(dialect, "rc.CONSTRAINT_SCHEMA")
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(qs, filter), qargs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var fk *ForeignKey
+	for rows.Next() {
+		var tschema string
+		var tname string
+		var fkName string
+		var cname string
+		var refTschema string
+		var refTname string
+		var refCname string
+		var deleteRule string
+		var updateRule string
+		err = rows.Scan(
+			&tschema, &tname, &fkName, &cname, &refTschema, &refTname,
+			&refCname, &deleteRule, &updateRule,
+		)
+		if err != nil {
+			return err
+		}
+		t := (*tables)[reflectKey(tschema, tname)]
+		refT := (*tables)[reflectKey(refTschema, refTname)]
+		if t == nil || refT == nil {
+			continue
+		}
+		c := t.C(cname)
+		refC := refT.C(refCname)
+		if c == nil || refC == nil {
+			continue
+		}
+		if fk == nil || fk.tbl != t || fk.name != fkName {
+			fk = t.NewForeignKey(fkName, nil, nil)
+			fk.refTable = refT
+			fk.onDelete = referentialActionFromRule(deleteRule)
+			fk.onUpdate = referentialActionFromRule(updateRule)
+		}
+		fk.columns = append(fk.columns, c)
+		fk.refColumns = append(fk.refColumns, refC)
+	}
+	return rows.Err()
+}
+
+// Grabs secondary index information from the database server and populates
+// the supplied map of TableDef descriptors' indexes. Indexes on expressions
+// only include the indexed columns, if any. The indexes that implement UNIQUE
+// constraints are described by the constraints and are skipped, so this must
+// run after fillConstraints.
+func fillIndexes(ctx context.Context, db *sql.DB, dialect Dialect, scope *reflectScope, tables *map[string]*Table) error {
+	var qs string
+	switch dialect {
+	case DIALECT_MYSQL:
+		qs = `
+SELECT s.TABLE_SCHEMA, s.TABLE_NAME, s.INDEX_NAME, s.NON_UNIQUE, s.COLUMN_NAME
+FROM INFORMATION_SCHEMA.STATISTICS AS s
+WHERE %s
+AND s.INDEX_NAME != 'PRIMARY'
+AND s.COLUMN_NAME IS NOT NULL
+ORDER BY s.TABLE_SCHEMA, s.TABLE_NAME, s.INDEX_NAME, s.SEQ_IN_INDEX
+`
+	case DIALECT_POSTGRESQL:
+		// The information schema does not describe indexes in PostgreSQL, so
+		// we need to go to the system catalogs, which are always scoped to the
+		// current database
+		qs = `
+SELECT n.nspname, t.relname, i.relname,
+ CASE WHEN ix.indisunique THEN 0 ELSE 1 END,
+ a.attname
+FROM pg_catalog.pg_index AS ix
+JOIN pg_catalog.pg_class AS t
+ ON t.oid = ix.indrelid
+JOIN pg_catalog.pg_class AS i
+ ON i.oid = ix.indexrelid
+JOIN pg_catalog.pg_namespace AS n
+ ON n.oid = t.relnamespace
+CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+JOIN pg_catalog.pg_attribute AS a
+ ON a.attrelid = t.oid
+ AND a.attnum = k.attnum
+WHERE $1 = CURRENT_DATABASE()
+AND %s
+AND NOT ix.indisprimary
+ORDER BY n.nspname, t.relname, i.relname, k.ord
+`
+	}
+	filterCol := "s.TABLE_SCHEMA"
+	if dialect == DIALECT_POSTGRESQL {
+		filterCol = "n.nspname"
+	}
+	filter, qargs := scope.filter(dialect, filterCol)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(qs, filter), qargs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var idx *Index
+	for rows.Next() {
+		var tschema string
+		var tname string
+		var iname string
+		var nonUnique int
+		var cname string
+		err = rows.Scan(&tschema, &tname, &iname, &nonUnique, &cname)
+		if err != nil {
+			return err
+		}
+		t := (*tables)[reflectKey(tschema, tname)]
+		if t == nil || t.uniqueConstraint(iname) != nil {
+			continue
+		}
+		c := t.C(cname)
+		if c == nil {
+			continue
+		}
+		if idx == nil || idx.tbl != t || idx.name != iname {
+			idx = t.NewIndex(iname, nonUnique == 0)
+		}
+		idx.columns = append(idx.columns, c)
+	}
+	return rows.Err()
+}
+
+// Returns the name of the current database and the schema that unqualified
+// table names belong to given a dialect and a sql.DB handle. In MySQL, these
+// are the same.
+func getSchemaNames(ctx context.Context, dialect Dialect, db *sql.DB) (string, string, error) {
+	var schemaName sql.NullString
+	var defaultSchema sql.NullString
+	var err error
+	switch dialect {
+	case DIALECT_MYSQL:
+		err = db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&schemaName)
+		defaultSchema = schemaName
+	case DIALECT_POSTGRESQL:
+		err = db.QueryRowContext(
+			ctx, "SELECT CURRENT_DATABASE(), CURRENT_SCHEMA()",
+		).Scan(&schemaName, &defaultSchema)
+	}
+	if err != nil {
+		return "", "", err
 	}
-	var schemaName string
-	err := db.QueryRow(qs).Scan(&schemaName)
-	switch {
-	case err != nil:
-		return ""
-	default:
-		return schemaName
+	if !schemaName.Valid || !defaultSchema.Valid {
+		return "", "", ERR_REFLECT_NO_DATABASE
 	}
+	return schemaName.String, defaultSchema.String, nil
 }
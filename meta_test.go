@@ -16,6 +16,8 @@ import (
 
 var (
 	_MYSQL_DB_INIT = []string{
+		"DROP VIEW IF EXISTS author_counts",
+		"DROP VIEW IF EXISTS article_titles",
 		"DROP TABLE IF EXISTS articles",
 		"DROP TABLE IF EXISTS users",
 		`
@@ -41,10 +43,20 @@ var (
           INDEX ix_title (title),
           FOREIGN KEY fk_users (created_by) REFERENCES users (id)
         );
+        `,
+		"CREATE VIEW article_titles AS SELECT id, title FROM articles",
+		`
+        CREATE VIEW author_counts AS
+        SELECT created_by, COUNT(*) AS num_articles
+        FROM articles
+        GROUP BY created_by
         `,
 	}
 	_POSTGRESQL_DB_INIT = []string{
 		"BEGIN",
+		"DROP SCHEMA IF EXISTS billing CASCADE",
+		"DROP MATERIALIZED VIEW IF EXISTS author_counts",
+		"DROP VIEW IF EXISTS article_titles",
 		"DROP TABLE IF EXISTS articles",
 		"DROP TABLE IF EXISTS users",
 		`
@@ -70,6 +82,24 @@ var (
         );
         `,
 		"CREATE INDEX ix_title ON articles (title);",
+		"CREATE VIEW article_titles AS SELECT id, title FROM articles",
+		`
+        CREATE MATERIALIZED VIEW author_counts AS
+        SELECT created_by, COUNT(*) AS num_articles
+        FROM articles
+        GROUP BY created_by
+        `,
+		"CREATE UNIQUE INDEX ix_author_counts ON author_counts (created_by)",
+		"CREATE SCHEMA billing",
+		`
+        CREATE TABLE billing.invoices (
+          id SERIAL NOT NULL,
+          user_id INT NOT NULL,
+          total NUMERIC(10, 2) NOT NULL,
+          PRIMARY KEY (id),
+          CONSTRAINT fk_invoice_users FOREIGN KEY (user_id) REFERENCES public.users (id)
+        );
+        `,
 		"COMMIT",
 	}
 )
@@ -183,7 +213,7 @@ func TestReflectMySQL(t *testing.T) {
 	err = Reflect(DIALECT_MYSQL, db, &meta)
 	assert.Nil(err)
 
-	assert.Equal(2, len(meta.tables))
+	assert.Equal(4, len(meta.tables))
 
 	artTbl := meta.tables["articles"]
 	userTbl := meta.tables["users"]
@@ -197,6 +227,76 @@ func TestReflectMySQL(t *testing.T) {
 	createdOnCol := userTbl.C("created_on")
 	assert.NotNil(createdOnCol)
 	assert.Equal("created_on", createdOnCol.name)
+	assert.Equal(SQL_TYPE_TIMESTAMP, createdOnCol.Type())
+	assert.False(createdOnCol.IsNullable())
+
+	// Columns are returned in table definition order
+	assert.Equal("id", userTbl.columns[0].Name())
+	assert.Equal("email", userTbl.columns[1].Name())
+
+	emailCol := userTbl.C("email")
+	assert.Equal(SQL_TYPE_VARCHAR, emailCol.Type())
+	assert.Equal("varchar(100)", emailCol.RawType())
+	assert.Equal(100, emailCol.Length())
+	assert.False(emailCol.IsPrimaryKey())
+
+	profileCol := userTbl.C("profile")
+	assert.Equal(SQL_TYPE_TEXT, profileCol.Type())
+	assert.True(profileCol.IsNullable())
+	_, hasDefault := profileCol.Default()
+	assert.False(hasDefault)
+
+	pk := userTbl.PrimaryKey()
+	assert.Equal(1, len(pk))
+	assert.Equal("id", pk[0].Name())
+	assert.Equal(SQL_TYPE_INT, pk[0].Type())
+	assert.False(pk[0].IsAutoIncrement())
+
+	fks := artTbl.ForeignKeys()
+	assert.Equal(1, len(fks))
+	assert.Equal("fk_users", fks[0].Name())
+	assert.Equal(userTbl, fks[0].ReferencedTable())
+	assert.Equal([]*Column{artTbl.C("created_by")}, fks[0].Columns())
+	assert.Equal([]*Column{userTbl.C("id")}, fks[0].ReferencedColumns())
+	// MySQL 5.7 reports RESTRICT and MySQL 8 reports NO ACTION for the
+	// default referential action, which behave identically in InnoDB
+	assert.Contains(
+		[]ReferentialAction{REFERENTIAL_ACTION_RESTRICT, REFERENTIAL_ACTION_NO_ACTION},
+		fks[0].OnDelete(),
+	)
+
+	var uniqueEmail bool
+	for _, con := range userTbl.Constraints() {
+		if con.Type() == CONSTRAINT_TYPE_UNIQUE {
+			uniqueEmail = con.Columns()[0] == userTbl.C("email")
+		}
+	}
+	assert.True(uniqueEmail)
+
+	var titleIdx *Index
+	for _, idx := range artTbl.Indexes() {
+		if idx.Name() == "ix_title" {
+			titleIdx = idx
+		}
+	}
+	assert.NotNil(titleIdx)
+	assert.False(titleIdx.IsUnique())
+	assert.Equal([]*Column{artTbl.C("title")}, titleIdx.Columns())
+
+	assert.False(artTbl.IsView())
+
+	titles := meta.Table("article_titles")
+	assert.NotNil(titles)
+	assert.Equal(TABLE_KIND_VIEW, titles.Kind())
+	assert.False(titles.IsReadOnly())
+	assert.Equal(2, len(titles.Columns()))
+
+	counts := meta.Table("author_counts")
+	assert.NotNil(counts)
+	assert.Equal(TABLE_KIND_VIEW, counts.Kind())
+	assert.True(counts.IsReadOnly())
+	assert.Equal(SQL_TYPE_BIGINT, counts.C("num_articles").Type())
+	assert.Equal(ERR_DELETE_READ_ONLY, Delete(counts).Error())
 }
 
 func TestReflectPostgreSQL(t *testing.T) {
@@ -215,7 +315,7 @@ func TestReflectPostgreSQL(t *testing.T) {
 	err = Reflect(DIALECT_POSTGRESQL, db, &meta)
 	assert.Nil(err)
 
-	assert.Equal(2, len(meta.tables))
+	assert.Equal(4, len(meta.tables))
 
 	artTbl := meta.tables["articles"]
 	userTbl := meta.tables["users"]
@@ -229,6 +329,263 @@ func TestReflectPostgreSQL(t *testing.T) {
 	createdOnCol := userTbl.C("created_on")
 	assert.NotNil(createdOnCol)
 	assert.Equal("created_on", createdOnCol.name)
+	assert.Equal(SQL_TYPE_TIMESTAMP, createdOnCol.Type())
+	assert.False(createdOnCol.IsNullable())
+
+	// Columns are returned in table definition order
+	assert.Equal("id", userTbl.columns[0].Name())
+	assert.Equal("email", userTbl.columns[1].Name())
+
+	emailCol := userTbl.C("email")
+	assert.Equal(SQL_TYPE_VARCHAR, emailCol.Type())
+	assert.Equal("character varying", emailCol.RawType())
+	assert.Equal(100, emailCol.Length())
+	assert.False(emailCol.IsPrimaryKey())
+
+	profileCol := userTbl.C("profile")
+	assert.Equal(SQL_TYPE_TEXT, profileCol.Type())
+	assert.True(profileCol.IsNullable())
+	_, hasDefault := profileCol.Default()
+	assert.False(hasDefault)
+
+	pk := userTbl.PrimaryKey()
+	assert.Equal(1, len(pk))
+	assert.Equal("id", pk[0].Name())
+	assert.Equal(SQL_TYPE_INT, pk[0].Type())
+	assert.True(pk[0].IsAutoIncrement())
+
+	fks := artTbl.ForeignKeys()
+	assert.Equal(1, len(fks))
+	assert.Equal("fk_users", fks[0].Name())
+	assert.Equal(userTbl, fks[0].ReferencedTable())
+	assert.Equal([]*Column{artTbl.C("created_by")}, fks[0].Columns())
+	assert.Equal([]*Column{userTbl.C("id")}, fks[0].ReferencedColumns())
+	assert.Equal(REFERENTIAL_ACTION_NO_ACTION, fks[0].OnDelete())
+
+	var uniqueEmail bool
+	for _, con := range userTbl.Constraints() {
+		if con.Type() == CONSTRAINT_TYPE_UNIQUE {
+			uniqueEmail = con.Columns()[0] == userTbl.C("email")
+		}
+	}
+	assert.True(uniqueEmail)
+
+	var titleIdx *Index
+	for _, idx := range artTbl.Indexes() {
+		if idx.Name() == "ix_title" {
+			titleIdx = idx
+		}
+	}
+	assert.NotNil(titleIdx)
+	assert.False(titleIdx.IsUnique())
+	assert.Equal([]*Column{artTbl.C("title")}, titleIdx.Columns())
+
+	assert.False(artTbl.IsView())
+
+	titles := meta.Table("article_titles")
+	assert.NotNil(titles)
+	assert.Equal(TABLE_KIND_VIEW, titles.Kind())
+	assert.False(titles.IsReadOnly())
+	assert.Equal(2, len(titles.Columns()))
+
+	counts := meta.Table("author_counts")
+	assert.NotNil(counts)
+	assert.Equal(TABLE_KIND_MATERIALIZED_VIEW, counts.Kind())
+	assert.True(counts.IsReadOnly())
+	assert.Equal(2, len(counts.Columns()))
+	assert.Equal(SQL_TYPE_BIGINT, counts.C("num_articles").Type())
+	assert.Equal(1, len(counts.Indexes()))
+
+	_, err = db.Exec(RefreshMaterializedView(counts).Concurrently().String())
+	assert.Nil(err)
+}
+
+func TestNewColumnDef(t *testing.T) {
+	assert := assert.New(t)
+
+	null := sql.NullInt64{}
+	tests := []struct {
+		name        string
+		dialect     Dialect
+		dataType    string
+		fullType    string
+		length      sql.NullInt64
+		isNullable  string
+		defaultExpr sql.NullString
+		extra       sql.NullString
+		sqlType     SqlType
+		rawType     string
+		autoInc     bool
+	}{
+		{
+			name:       "MySQL auto increment",
+			dialect:    DIALECT_MYSQL,
+			dataType:   "int",
+			fullType:   "int unsigned",
+			isNullable: "NO",
+			extra:      sql.NullString{String: "auto_increment", Valid: true},
+			sqlType:    SQL_TYPE_INT,
+			rawType:    "int unsigned",
+			autoInc:    true,
+		},
+		{
+			name:        "MySQL varchar with default",
+			dialect:     DIALECT_MYSQL,
+			dataType:    "varchar",
+			fullType:    "varchar(100)",
+			length:      sql.NullInt64{Int64: 100, Valid: true},
+			isNullable:  "YES",
+			defaultExpr: sql.NullString{String: "foo", Valid: true},
+			sqlType:     SQL_TYPE_VARCHAR,
+			rawType:     "varchar(100)",
+		},
+		{
+			name:        "PostgreSQL serial",
+			dialect:     DIALECT_POSTGRESQL,
+			dataType:    "integer",
+			fullType:    "int4",
+			isNullable:  "NO",
+			defaultExpr: sql.NullString{String: "nextval('users_id_seq'::regclass)", Valid: true},
+			extra:       sql.NullString{String: "NO", Valid: true},
+			sqlType:     SQL_TYPE_INT,
+			rawType:     "integer",
+			autoInc:     true,
+		},
+		{
+			name:       "PostgreSQL identity",
+			dialect:    DIALECT_POSTGRESQL,
+			dataType:   "bigint",
+			fullType:   "int8",
+			isNullable: "NO",
+			extra:      sql.NullString{String: "YES", Valid: true},
+			sqlType:    SQL_TYPE_BIGINT,
+			rawType:    "bigint",
+			autoInc:    true,
+		},
+		{
+			name:       "PostgreSQL user-defined type",
+			dialect:    DIALECT_POSTGRESQL,
+			dataType:   "USER-DEFINED",
+			fullType:   "article_state",
+			isNullable: "YES",
+			sqlType:    SQL_TYPE_UNKNOWN,
+			rawType:    "article_state",
+		},
+	}
+	for _, test := range tests {
+		def := newColumnDef(
+			test.dialect, test.dataType, test.fullType, test.length, null,
+			null, test.isNullable, test.defaultExpr, test.extra,
+		)
+		c := &Column{name: "c", def: def}
+		assert.Equal(test.sqlType, c.Type(), test.name)
+		assert.Equal(test.rawType, c.RawType(), test.name)
+		assert.Equal(int(test.length.Int64), c.Length(), test.name)
+		assert.Equal(test.isNullable == "YES", c.IsNullable(), test.name)
+		assert.Equal(test.autoInc, c.IsAutoIncrement(), test.name)
+		d, hasDefault := c.Default()
+		assert.Equal(test.defaultExpr.Valid, hasDefault, test.name)
+		assert.Equal(test.defaultExpr.String, d, test.name)
+	}
+}
+
+func TestMetaTables(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	tables := m.Tables()
+	assert.Equal(4, len(tables))
+	assert.Equal("article_states", tables[0].Name())
+	assert.Equal("users", tables[3].Name())
+}
+
+func TestReflectPostgreSQLSchemas(t *testing.T) {
+	dsn, found := os.LookupEnv("SQLB_TESTING_POSTGRESQL_DSN")
+	if !found {
+		t.Skip("No SQLB_TESTING_POSTGRESQL_DSN environ set")
+	}
+	assert := assert.New(t)
+
+	db, err := sql.Open("postgres", dsn)
+	assert.Nil(err)
+
+	resetDB(DIALECT_POSTGRESQL, db)
+
+	meta := NewMeta(DIALECT_POSTGRESQL, "").SetSchemas("public", "billing")
+	err = Reflect(DIALECT_POSTGRESQL, db, meta)
+	assert.Nil(err)
+
+	assert.Equal(5, len(meta.tables))
+	assert.Equal("public", meta.DefaultSchema())
+
+	users := meta.Table("users")
+	assert.NotNil(users)
+	assert.Equal(users, meta.Table("public.users"))
+	assert.Nil(meta.Table("invoices"))
+
+	invoices := meta.Table("billing.invoices")
+	assert.NotNil(invoices)
+	assert.Equal("billing", invoices.Schema())
+	assert.Equal(3, len(invoices.Columns()))
+
+	fks := invoices.ForeignKeys()
+	assert.Equal(1, len(fks))
+	assert.Equal(users, fks[0].ReferencedTable())
+
+	q := Select(invoices.C("total"), users.C("name")).JoinAuto(users)
+	assert.Nil(q.Error())
+	assert.Equal(
+		"SELECT billing.invoices.total, users.name FROM billing.invoices JOIN users ON billing.invoices.user_id = users.id",
+		q.String(),
+	)
+}
+
+func TestMetaSchemas(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	assert.Equal("public", m.DefaultSchema())
+	assert.Equal([]string{"public"}, m.Schemas())
+
+	users := m.NewTable("users")
+	assert.Equal("", users.Schema())
+	invoices := m.NewTable("billing.invoices")
+	assert.Equal("billing", invoices.Schema())
+	assert.Equal("invoices", invoices.Name())
+
+	// Creating an existing table returns the existing table
+	assert.Equal(invoices, m.NewTable("billing.invoices"))
+
+	assert.Equal(users, m.Table("users"))
+	assert.Equal(users, m.Table("public.users"))
+	assert.Equal(invoices, m.Table("billing.invoices"))
+	assert.Nil(m.Table("invoices"))
+	assert.Nil(m.Table("other.users"))
+
+	tables := m.Tables()
+	assert.Equal([]*Table{invoices, users}, tables)
+
+	m.SetSchemas("public", "billing")
+	assert.Equal([]string{"public", "billing"}, m.Schemas())
+
+	mm := NewMeta(DIALECT_MYSQL, "blog")
+	assert.Equal("blog", mm.DefaultSchema())
+	posts := mm.NewTable("blog.posts")
+	assert.Equal(posts, mm.Table("posts"))
+}
+
+func TestReflectScopeFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := &reflectScope{catalog: "db", schemas: []string{"public", "billing"}}
+
+	filter, qargs := rs.filter(DIALECT_MYSQL, "t.TABLE_SCHEMA")
+	assert.Equal("t.TABLE_SCHEMA IN (?, ?)", filter)
+	assert.Equal([]interface{}{"public", "billing"}, qargs)
+
+	filter, qargs = rs.filter(DIALECT_POSTGRESQL, "t.TABLE_SCHEMA")
+	assert.Equal("t.TABLE_SCHEMA IN ($2, $3)", filter)
+	assert.Equal([]interface{}{"db", "public", "billing"}, qargs)
 }
 
 func TestReflectErrors(t *testing.T) {
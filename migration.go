@@ -0,0 +1,404 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ERR_MIGRATION_NO_DIALECT = errors.New("Please specify a SQL dialect to generate a migration for.")
+	// Migrations render column definitions with the DDL builders, so this is
+	// the same error as ERR_DDL_UNKNOWN_TYPE
+	ERR_MIGRATION_UNKNOWN_TYPE = ERR_DDL_UNKNOWN_TYPE
+	ERR_MIGRATION_INVALID_NAME = errors.New("Migration file names may not contain path separators.")
+)
+
+// A Migration holds the SQL statements that move a database from one schema
+// to another and back again. See SchemaDiff.Migration().
+type Migration struct {
+	// The statements that apply the changes, in the order they must be
+	// executed
+	Up []string
+	// The statements that revert the changes, in the order they must be
+	// executed
+	Down []string
+	// Descriptions of the changes that were not included in Up, such as
+	// destructive changes when AllowDestructive() was not supplied
+	Skipped []string
+}
+
+type migrationOptions struct {
+	destructive bool
+}
+
+// A MigrationOption modifies how SchemaDiff.Migration() generates a
+// migration
+type MigrationOption func(*migrationOptions)
+
+// Includes statements that drop tables and columns, and with them any data
+// they hold, in the migration. Without this option, such changes are listed
+// in the Migration's Skipped field instead.
+func AllowDestructive() MigrationOption {
+	return func(o *migrationOptions) {
+		o.destructive = true
+	}
+}
+
+// Returns the migration's Up statements as a script, with each statement
+// terminated by a semicolon and a newline
+func (m *Migration) UpSQL() string {
+	return migrationScript(m.Up)
+}
+
+// Returns the migration's Down statements as a script, with each statement
+// terminated by a semicolon and a newline
+func (m *Migration) DownSQL() string {
+	return migrationScript(m.Down)
+}
+
+// Writes the migration's Up and Down scripts to the supplied directory as
+// <version>_<name>.up.sql and <version>_<name>.down.sql, which is the layout
+// most Go migration tools expect
+func (m *Migration) WriteFiles(dir string, version string, name string) error {
+	base := version + "_" + name
+	if strings.ContainsAny(base, `/\`) {
+		return ERR_MIGRATION_INVALID_NAME
+	}
+	up := filepath.Join(dir, base+".up.sql")
+	if err := os.WriteFile(up, []byte(m.UpSQL()), 0644); err != nil {
+		return err
+	}
+	down := filepath.Join(dir, base+".down.sql")
+	return os.WriteFile(down, []byte(m.DownSQL()), 0644)
+}
+
+func migrationScript(stmts []string) string {
+	var sb strings.Builder
+	for _, stmt := range stmts {
+		sb.WriteString(stmt)
+		sb.WriteString(";\n")
+	}
+	return sb.String()
+}
+
+// Returns the SQL statements, in the supplied dialect, that change the
+// schema described by the first Meta passed to Diff() into the schema
+// described by the second, along with the statements that revert those
+// changes.
+//
+// Statements are ordered so that dependencies are satisfied: foreign keys
+// are dropped before the tables, columns and indexes they rely on, and
+// tables are created before the foreign keys that reference them.
+//
+// Dropping tables and columns destroys data, so those statements are only
+// included in Up if the AllowDestructive() option is supplied. The Down
+// statements always revert every change in Up, which means dropping the
+// tables and columns that Up adds. Note that reverting a dropped table or
+// column recreates it, but cannot restore its data.
+//
+// Views and materialized views are not included, because their definitions
+// are not known.
+func (d *SchemaDiff) Migration(dialect Dialect, opts ...MigrationOption) (*Migration, error) {
+	if dialect != DIALECT_MYSQL && dialect != DIALECT_POSTGRESQL {
+		return nil, ERR_MIGRATION_NO_DIALECT
+	}
+	o := &migrationOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	m := &Migration{}
+	up := &migrationGenerator{ddlRenderer: ddlRenderer{dialect: dialect}, opts: o}
+	if err := up.generate(d); err != nil {
+		return nil, err
+	}
+	down := &migrationGenerator{
+		ddlRenderer: ddlRenderer{dialect: dialect},
+		opts:        &migrationOptions{destructive: true},
+	}
+	if err := down.generate(d.reverse()); err != nil {
+		return nil, err
+	}
+	m.Up = up.stmts
+	m.Down = down.stmts
+	m.Skipped = up.skipped
+	return m, nil
+}
+
+// Returns the diff describing the changes that revert this diff's changes
+func (d *SchemaDiff) reverse() *SchemaDiff {
+	res := &SchemaDiff{Tables: make([]*TableDiff, len(d.Tables))}
+	for x, td := range d.Tables {
+		rtd := &TableDiff{
+			Name:        td.Name,
+			Change:      td.Change.reverse(),
+			From:        td.To,
+			To:          td.From,
+			KindChanged: td.KindChanged,
+		}
+		for _, cd := range td.Columns {
+			rcd := *cd
+			rcd.Change = cd.Change.reverse()
+			rcd.From, rcd.To = cd.To, cd.From
+			rtd.Columns = append(rtd.Columns, &rcd)
+		}
+		for _, cd := range td.Constraints {
+			rtd.Constraints = append(rtd.Constraints, &ConstraintDiff{
+				Name: cd.Name, Change: cd.Change.reverse(), From: cd.To, To: cd.From,
+			})
+		}
+		for _, fd := range td.ForeignKeys {
+			rtd.ForeignKeys = append(rtd.ForeignKeys, &ForeignKeyDiff{
+				Name: fd.Name, Change: fd.Change.reverse(), From: fd.To, To: fd.From,
+			})
+		}
+		for _, id := range td.Indexes {
+			rtd.Indexes = append(rtd.Indexes, &IndexDiff{
+				Name: id.Name, Change: id.Change.reverse(), From: id.To, To: id.From,
+			})
+		}
+		res.Tables[x] = rtd
+	}
+	return res
+}
+
+func (c ChangeType) reverse() ChangeType {
+	switch c {
+	case CHANGE_TYPE_ADDED:
+		return CHANGE_TYPE_REMOVED
+	case CHANGE_TYPE_REMOVED:
+		return CHANGE_TYPE_ADDED
+	}
+	return c
+}
+
+// A migrationGenerator accumulates the statements of one direction of a
+// migration
+type migrationGenerator struct {
+	ddlRenderer
+	opts    *migrationOptions
+	stmts   []string
+	skipped []string
+}
+
+func (g *migrationGenerator) add(stmt string) {
+	g.stmts = append(g.stmts, stmt)
+}
+
+func (g *migrationGenerator) skip(format string, args ...interface{}) {
+	g.skipped = append(g.skipped, fmt.Sprintf(format, args...))
+}
+
+func (g *migrationGenerator) generate(d *SchemaDiff) error {
+	added := make([]*Table, 0)
+	removed := make([]*Table, 0)
+	changed := make([]*TableDiff, 0)
+	for _, td := range d.Tables {
+		t := td.To
+		if t == nil {
+			t = td.From
+		}
+		if td.KindChanged || t.kind != TABLE_KIND_TABLE {
+			g.skip("%s %s: view definitions are not known", tableKindReportNames[t.kind], td.Name)
+			continue
+		}
+		switch td.Change {
+		case CHANGE_TYPE_ADDED:
+			added = append(added, td.To)
+		case CHANGE_TYPE_REMOVED:
+			removed = append(removed, td.From)
+		default:
+			changed = append(changed, td)
+		}
+	}
+
+	// Drop foreign keys, then indexes and constraints, before the columns
+	// and tables they rely on are changed
+	for _, td := range changed {
+		for _, fd := range td.ForeignKeys {
+			if fd.Change != CHANGE_TYPE_ADDED {
+				g.add(g.alterTableSQL(td.From, g.dropForeignKeySQL(fd.From)))
+			}
+		}
+	}
+	for _, td := range changed {
+		for _, id := range td.Indexes {
+			if id.Change != CHANGE_TYPE_ADDED && !isConstraintIndex(td.From, id.From) {
+				g.add(g.dropIndexSQL(id.From))
+			}
+		}
+		for _, cd := range td.Constraints {
+			if cd.Change != CHANGE_TYPE_ADDED {
+				g.add(g.alterTableSQL(td.From, g.dropConstraintSQL(cd.From)))
+			}
+		}
+	}
+
+	// Create new tables, referenced tables first. Foreign keys that
+	// reference tables that are created later are added afterwards.
+	deferred := make([]*ForeignKey, 0)
+	created := make(map[*Table]bool, len(added))
+	isNew := make(map[*Table]bool, len(added))
+	for _, t := range added {
+		isNew[t] = true
+	}
+	for _, t := range sortTablesByDependency(added) {
+		inline := make([]*ForeignKey, 0, len(t.foreignKeys))
+		for _, fk := range t.foreignKeys {
+			if fk.refTable == t || created[fk.refTable] || !isNew[fk.refTable] {
+				inline = append(inline, fk)
+			} else {
+				deferred = append(deferred, fk)
+			}
+		}
+		stmts, err := g.createTableSQL(t, inline)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range stmts {
+			g.add(stmt)
+		}
+		created[t] = true
+	}
+
+	// Add, change and drop columns of existing tables
+	for _, td := range changed {
+		actions := make([]string, 0, len(td.Columns))
+		for _, cd := range td.Columns {
+			switch cd.Change {
+			case CHANGE_TYPE_ADDED:
+				def, err := g.columnDefinitionSQL(cd.To)
+				if err != nil {
+					return err
+				}
+				actions = append(actions, "ADD COLUMN "+def)
+			case CHANGE_TYPE_CHANGED:
+				changes, err := g.alterColumnSQL(cd)
+				if err != nil {
+					return err
+				}
+				actions = append(actions, changes...)
+			case CHANGE_TYPE_REMOVED:
+				if !g.opts.destructive {
+					g.skip("DROP COLUMN %s.%s", td.Name, cd.Name)
+					continue
+				}
+				actions = append(actions, "DROP COLUMN "+g.ident(cd.Name))
+			}
+		}
+		if len(actions) > 0 {
+			g.add(g.alterTableSQL(td.To, actions...))
+		}
+	}
+
+	// Add constraints, indexes and foreign keys to existing tables
+	for _, td := range changed {
+		for _, cd := range td.Constraints {
+			if cd.Change != CHANGE_TYPE_REMOVED {
+				g.add(g.alterTableSQL(td.To, "ADD "+g.constraintSQL(cd.To)))
+			}
+		}
+		for _, id := range td.Indexes {
+			if id.Change != CHANGE_TYPE_REMOVED && !isConstraintIndex(td.To, id.To) {
+				g.add(g.createIndexSQL(id.To, false))
+			}
+		}
+	}
+	for _, td := range changed {
+		for _, fd := range td.ForeignKeys {
+			if fd.Change != CHANGE_TYPE_REMOVED {
+				deferred = append(deferred, fd.To)
+			}
+		}
+	}
+	for _, fk := range deferred {
+		g.add(g.alterTableSQL(fk.tbl, "ADD "+g.foreignKeySQL(fk)))
+	}
+
+	// Drop removed tables, referencing tables first. Foreign keys that
+	// reference tables that are dropped earlier are dropped beforehand.
+	sorted := sortTablesByDependency(removed)
+	if g.opts.destructive {
+		position := make(map[*Table]int, len(sorted))
+		for x, t := range sorted {
+			position[t] = x
+		}
+		for x, t := range sorted {
+			for _, fk := range t.foreignKeys {
+				if y, ok := position[fk.refTable]; ok && y > x {
+					g.add(g.alterTableSQL(t, g.dropForeignKeySQL(fk)))
+				}
+			}
+		}
+	}
+	for x := len(sorted) - 1; x >= 0; x-- {
+		t := sorted[x]
+		if !g.opts.destructive {
+			g.skip("DROP TABLE %s", t.qualifiedName())
+			continue
+		}
+		g.add("DROP TABLE " + g.tableName(t))
+	}
+	return nil
+}
+
+// Returns the supplied tables ordered so that tables come after the tables
+// they reference. Tables that reference each other are ordered by name.
+func sortTablesByDependency(tables []*Table) []*Table {
+	sort.Slice(tables, func(x, y int) bool {
+		return tables[x].qualifiedName() < tables[y].qualifiedName()
+	})
+	inSet := make(map[*Table]bool, len(tables))
+	for _, t := range tables {
+		inSet[t] = true
+	}
+	res := make([]*Table, 0, len(tables))
+	visited := make(map[*Table]bool, len(tables))
+	var visit func(t *Table)
+	visit = func(t *Table) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for _, fk := range t.foreignKeys {
+			if inSet[fk.refTable] {
+				visit(fk.refTable)
+			}
+		}
+		res = append(res, t)
+	}
+	for _, t := range tables {
+		visit(t)
+	}
+	return res
+}
+
+// Returns the CREATE TABLE statement for the supplied table, including the
+// supplied foreign keys, followed by CREATE INDEX statements for the table's
+// indexes if they can't be declared in the CREATE TABLE statement
+func (g *migrationGenerator) createTableSQL(t *Table, fks []*ForeignKey) ([]string, error) {
+	elements, err := g.tableElementsSQL(t, fks, nil)
+	if err != nil {
+		return nil, err
+	}
+	stmts := []string{
+		"CREATE TABLE " + g.tableName(t) + " (\n    " +
+			strings.Join(elements, ",\n    ") + "\n)",
+	}
+	if g.dialect != DIALECT_MYSQL {
+		for _, i := range t.indexes {
+			if !isConstraintIndex(t, i) {
+				stmts = append(stmts, g.createIndexSQL(i, false))
+			}
+		}
+	}
+	return stmts, nil
+}
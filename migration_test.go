@@ -0,0 +1,265 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	_MIGRATION_FROM_DDL = `
+CREATE TABLE users (
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(100) NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'active',
+  legacy TEXT
+);
+CREATE TABLE old_stuff (id INT);
+CREATE INDEX users_email_idx ON users (email);
+`
+	_MIGRATION_TO_DDL = `
+CREATE TABLE users (
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(200),
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  tags TEXT[],
+  CONSTRAINT users_email_key UNIQUE (email)
+);
+CREATE TABLE articles (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  author_id INT REFERENCES users (id) ON DELETE CASCADE,
+  price NUMERIC(10,2)
+);
+CREATE INDEX articles_author_idx ON articles (author_id);
+`
+)
+
+func TestMigrationPostgreSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	from := loadTestDDL(t, DIALECT_POSTGRESQL, _MIGRATION_FROM_DDL)
+	to := loadTestDDL(t, DIALECT_POSTGRESQL, _MIGRATION_TO_DDL)
+
+	m, err := Diff(from, to).Migration(DIALECT_POSTGRESQL)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"DROP INDEX users_email_idx",
+		"CREATE TABLE articles (\n" +
+			"    id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,\n" +
+			"    author_id integer,\n" +
+			"    price numeric(10,2),\n" +
+			"    CONSTRAINT articles_pkey PRIMARY KEY (id),\n" +
+			"    CONSTRAINT articles_author_id_fkey FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE\n" +
+			")",
+		"CREATE INDEX articles_author_idx ON articles (author_id)",
+		"ALTER TABLE users ALTER COLUMN email TYPE character varying(200), " +
+			"ALTER COLUMN email DROP NOT NULL, " +
+			"ALTER COLUMN status SET DEFAULT 'pending', " +
+			"ADD COLUMN tags text[]",
+		"ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email)",
+	}, m.Up)
+	assert.Equal([]string{"DROP COLUMN users.legacy", "DROP TABLE old_stuff"}, m.Skipped)
+
+	// Down reverts every change, including those that Up skipped
+	assert.Equal([]string{
+		"ALTER TABLE users DROP CONSTRAINT users_email_key",
+		"CREATE TABLE old_stuff (\n    id integer\n)",
+		"ALTER TABLE users ALTER COLUMN email TYPE character varying(100), " +
+			"ALTER COLUMN email SET NOT NULL, " +
+			"ALTER COLUMN status SET DEFAULT 'active', " +
+			"ADD COLUMN legacy text, " +
+			"DROP COLUMN tags",
+		"CREATE INDEX users_email_idx ON users (email)",
+		"DROP TABLE articles",
+	}, m.Down)
+
+	m, err = Diff(from, to).Migration(DIALECT_POSTGRESQL, AllowDestructive())
+	assert.Nil(err)
+	assert.Equal(0, len(m.Skipped))
+	assert.Contains(m.Up[3], "DROP COLUMN legacy")
+	assert.Equal("DROP TABLE old_stuff", m.Up[len(m.Up)-1])
+}
+
+func TestMigrationMySQL(t *testing.T) {
+	assert := assert.New(t)
+
+	to := loadTestDDL(t, DIALECT_MYSQL, _MYSQL_DDL)
+	m, err := Diff(NewMeta(DIALECT_MYSQL, "test"), to).Migration(DIALECT_MYSQL)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"CREATE TABLE users (\n" +
+			"    id int NOT NULL,\n" +
+			"    email varchar(100) NOT NULL,\n" +
+			"    name varchar(100) NOT NULL,\n" +
+			"    is_author char(1) NOT NULL,\n" +
+			"    profile text,\n" +
+			"    created_on datetime NOT NULL,\n" +
+			"    updated_on datetime NOT NULL,\n" +
+			"    PRIMARY KEY (id),\n" +
+			"    CONSTRAINT email UNIQUE (email)\n" +
+			")",
+		"CREATE TABLE articles (\n" +
+			"    id int unsigned NOT NULL AUTO_INCREMENT,\n" +
+			"    title varchar(200) NOT NULL DEFAULT '',\n" +
+			"    content text NOT NULL,\n" +
+			"    created_by int NOT NULL,\n" +
+			"    price decimal(10,2),\n" +
+			"    published_on datetime,\n" +
+			"    `key` tinyint(1) NOT NULL DEFAULT 0,\n" +
+			"    PRIMARY KEY (id),\n" +
+			"    INDEX ix_title (title),\n" +
+			"    INDEX fk_users (created_by),\n" +
+			"    CONSTRAINT articles_ibfk_1 FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE\n" +
+			")",
+	}, m.Up)
+	// Referencing tables are dropped first
+	assert.Equal([]string{"DROP TABLE articles", "DROP TABLE users"}, m.Down)
+
+	changed := loadTestDDL(t, DIALECT_MYSQL, `
+CREATE TABLE users (
+  id INT NOT NULL,
+  email VARCHAR(100) NOT NULL,
+  name VARCHAR(100) NOT NULL DEFAULT 'anonymous',
+  is_author CHAR(1) NOT NULL,
+  profile TEXT NULL,
+  created_on DATETIME NOT NULL,
+  updated_on DATETIME NOT NULL,
+  PRIMARY KEY (id)
+);
+CREATE TABLE articles (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  title VARCHAR(200) NOT NULL DEFAULT '',
+  content TEXT NOT NULL,
+  created_by INT NOT NULL,
+  price DECIMAL(10,2) NULL,
+  published_on DATETIME NULL,
+  `+"`key`"+` BOOLEAN NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX ix_title (title(10)),
+  INDEX fk_users (created_by)
+);
+`)
+	m, err = Diff(to, changed).Migration(DIALECT_MYSQL)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"ALTER TABLE articles DROP FOREIGN KEY articles_ibfk_1",
+		"ALTER TABLE users DROP INDEX email",
+		"ALTER TABLE users MODIFY COLUMN name varchar(100) NOT NULL DEFAULT 'anonymous'",
+	}, m.Up)
+}
+
+func TestMigrationTableOrdering(t *testing.T) {
+	assert := assert.New(t)
+
+	// Tables that reference each other can't both be created with their
+	// foreign keys, so the foreign key to the later table is added afterwards
+	to := loadTestDDL(t, DIALECT_POSTGRESQL, `
+CREATE TABLE c (id INT PRIMARY KEY, b_id INT REFERENCES b (id));
+CREATE TABLE b (id INT PRIMARY KEY, a_id INT REFERENCES a (id));
+CREATE TABLE a (id INT PRIMARY KEY, c_id INT REFERENCES c (id), parent_id INT REFERENCES a (id));
+`)
+	m, err := Diff(NewMeta(DIALECT_POSTGRESQL, "test"), to).Migration(DIALECT_POSTGRESQL)
+	assert.Nil(err)
+	assert.Equal(4, len(m.Up))
+	assert.Contains(m.Up[0], "CREATE TABLE b ")
+	assert.Contains(m.Up[1], "CREATE TABLE c ")
+	assert.Contains(m.Up[2], "CREATE TABLE a ")
+	assert.Contains(m.Up[2], "REFERENCES c (id)")
+	assert.Contains(m.Up[2], "REFERENCES a (id)")
+	assert.Equal(
+		"ALTER TABLE b ADD CONSTRAINT b_a_id_fkey FOREIGN KEY (a_id) REFERENCES a (id)",
+		m.Up[3],
+	)
+	assert.Equal([]string{
+		"ALTER TABLE b DROP CONSTRAINT b_a_id_fkey",
+		"DROP TABLE a",
+		"DROP TABLE c",
+		"DROP TABLE b",
+	}, m.Down)
+}
+
+func TestMigrationQuoting(t *testing.T) {
+	assert := assert.New(t)
+
+	to := loadTestDDL(t, DIALECT_POSTGRESQL, `
+CREATE TABLE billing."Order" ("user" INT NOT NULL, total NUMERIC(10,2));
+`)
+	m, err := Diff(NewMeta(DIALECT_POSTGRESQL, "test"), to).Migration(DIALECT_POSTGRESQL)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"CREATE TABLE billing.\"Order\" (\n" +
+			"    \"user\" integer NOT NULL,\n" +
+			"    total numeric(10,2)\n" +
+			")",
+	}, m.Up)
+}
+
+func TestMigrationCrossDialect(t *testing.T) {
+	assert := assert.New(t)
+
+	// A column's raw type is specific to the dialect of its Meta, so other
+	// dialects use the column's SqlType
+	to := loadTestDDL(t, DIALECT_MYSQL, "CREATE TABLE t (a VARCHAR(20) NOT NULL, b DATETIME)")
+	m, err := Diff(NewMeta(DIALECT_POSTGRESQL, "test"), to).Migration(DIALECT_POSTGRESQL)
+	assert.Nil(err)
+	assert.Equal([]string{
+		"CREATE TABLE t (\n" +
+			"    a character varying(20) NOT NULL,\n" +
+			"    b timestamp without time zone\n" +
+			")",
+	}, m.Up)
+}
+
+func TestMigrationErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	d := Diff(NewMeta(DIALECT_MYSQL, "test"), NewMeta(DIALECT_MYSQL, "test"))
+	_, err := d.Migration(DIALECT_UNKNOWN)
+	assert.Equal(ERR_MIGRATION_NO_DIALECT, err)
+
+	// Columns added manually have no known data type
+	to := NewMeta(DIALECT_MYSQL, "test")
+	to.NewTable("users").NewColumn("id")
+	_, err = Diff(NewMeta(DIALECT_MYSQL, "test"), to).Migration(DIALECT_MYSQL)
+	assert.True(errors.Is(err, ERR_MIGRATION_UNKNOWN_TYPE))
+	assert.True(errors.Is(err, ERR_DDL_UNKNOWN_TYPE))
+
+	// Views are skipped
+	to = NewMeta(DIALECT_MYSQL, "test")
+	to.NewView("user_names")
+	m, err := Diff(NewMeta(DIALECT_MYSQL, "test"), to).Migration(DIALECT_MYSQL)
+	assert.Nil(err)
+	assert.Equal(0, len(m.Up))
+	assert.Equal([]string{"view user_names: view definitions are not known"}, m.Skipped)
+}
+
+func TestMigrationWriteFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := os.MkdirTemp("", "sqlb-migration")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	m := &Migration{
+		Up:   []string{"CREATE TABLE t (\n    id int\n)", "CREATE INDEX ix ON t (id)"},
+		Down: []string{"DROP TABLE t"},
+	}
+	assert.Nil(m.WriteFiles(dir, "20260101120000", "create_t"))
+
+	b, err := os.ReadFile(filepath.Join(dir, "20260101120000_create_t.up.sql"))
+	assert.Nil(err)
+	assert.Equal("CREATE TABLE t (\n    id int\n);\nCREATE INDEX ix ON t (id);\n", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, "20260101120000_create_t.down.sql"))
+	assert.Nil(err)
+	assert.Equal("DROP TABLE t;\n", string(b))
+
+	assert.Equal(ERR_MIGRATION_INVALID_NAME, m.WriteFiles(dir, "1", "../escape"))
+}
@@ -15,9 +15,8 @@ func (sc *sortColumn) argCount() int {
 }
 
 func (sc *sortColumn) size(scanner *sqlScanner) int {
-	reset := sc.p.disableAliasScan()
-	defer reset()
-	size := sc.p.size(scanner)
+	// Projections in the ORDER BY clause never output their aliases
+	size := sc.p.size(scanner.noAliases())
 	if sc.desc {
 		size += len(Symbols[SYM_DESC])
 	}
@@ -25,10 +24,9 @@ func (sc *sortColumn) size(scanner *sqlScanner) int {
 }
 
 func (sc *sortColumn) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
-	reset := sc.p.disableAliasScan()
-	defer reset()
 	bw := 0
-	bw += sc.p.scan(scanner, b[bw:], args, curArg)
+	// Projections in the ORDER BY clause never output their aliases
+	bw += sc.p.scan(scanner.noAliases(), b[bw:], args, curArg)
 	if sc.desc {
 		bw += copy(b[bw:], Symbols[SYM_DESC])
 	}
@@ -0,0 +1,373 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+var (
+	ERR_NO_DB                  = errors.New("Please pass a pointer to a sql.DB struct")
+	ERR_REFLECT_NO_DIALECT     = errors.New("Unable to determine the SQL dialect of the database. Use WithDialect() to supply it.")
+	ERR_REFLECT_NO_DATABASE    = errors.New("The connection has no current database.")
+	ERR_REFLECT_INVALID_FILTER = errors.New("Invalid table name pattern.")
+)
+
+// Options that control what ReflectContext() discovers
+type reflectOptions struct {
+	dialect  Dialect
+	schemas  []string
+	includes []string
+	excludes []string
+}
+
+// A ReflectOption modifies the behaviour of ReflectContext()
+type ReflectOption func(*reflectOptions)
+
+// Sets the SQL dialect of the database. If not supplied, the dialect of the
+// Meta is used, and if the Meta has no dialect, the dialect is determined from
+// the sql.DB's driver.
+func WithDialect(dialect Dialect) ReflectOption {
+	return func(o *reflectOptions) {
+		o.dialect = dialect
+	}
+}
+
+// Sets the schemas to discover tables in, overriding any schemas set with
+// Meta.SetSchemas()
+func WithSchemas(schemas ...string) ReflectOption {
+	return func(o *reflectOptions) {
+		o.schemas = schemas
+	}
+}
+
+// Only discover tables and views whose name matches one of the supplied
+// patterns. Patterns use the syntax of path.Match() and are matched against
+// both the table's name and its schema-qualified name, so "billing.*" matches
+// every table in the billing schema.
+func IncludeTables(patterns ...string) ReflectOption {
+	return func(o *reflectOptions) {
+		o.includes = append(o.includes, patterns...)
+	}
+}
+
+// Do not discover tables and views whose name matches one of the supplied
+// patterns. Exclusions take precedence over inclusions. See IncludeTables()
+// for the pattern syntax.
+func ExcludeTables(patterns ...string) ReflectOption {
+	return func(o *reflectOptions) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// Returns an error if any of the options' table name patterns is malformed
+func (o *reflectOptions) validate() error {
+	for _, patterns := range [][]string{o.includes, o.excludes} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%w Pattern: %s", ERR_REFLECT_INVALID_FILTER, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns whether a table with the supplied schema and name passes the
+// options' include and exclude patterns
+func (o *reflectOptions) matches(schema string, name string) bool {
+	qualified := schema + "." + name
+	matchAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			// Patterns have been validated already, so errors are impossible
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, qualified); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if matchAny(o.excludes) {
+		return false
+	}
+	return len(o.includes) == 0 || matchAny(o.includes)
+}
+
+// Returns the Dialect for the driver of the supplied sql.DB, or
+// DIALECT_UNKNOWN if the driver is not known
+func dialectFromDriver(db *sql.DB) Dialect {
+	switch db.Driver().(type) {
+	case *mysql.MySQLDriver:
+		return DIALECT_MYSQL
+	case *pq.Driver:
+		return DIALECT_POSTGRESQL
+	}
+	return DIALECT_UNKNOWN
+}
+
+// Populates the supplied Meta with the tables, views and columns of the
+// database. See ReflectContext().
+func Reflect(dialect Dialect, db *sql.DB, meta *Meta) error {
+	return ReflectContext(context.Background(), db, meta, WithDialect(dialect))
+}
+
+// Populates the supplied Meta with the tables, views, columns, constraints
+// and indexes of the database, running all queries with the supplied
+// context.
+//
+// ReflectContext may be called on a Meta that already contains tables, for
+// instance to pick up schema changes. Tables that are discovered are updated
+// in place, so pointers to existing Tables and Columns remain valid. Tables
+// that were previously discovered but no longer exist are removed. Tables
+// that were added with Meta.NewTable() and friends and do not exist in the
+// database are kept.
+//
+// Errors from the database are wrapped with a description of what was being
+// discovered and can be inspected with errors.Is() and errors.As().
+func ReflectContext(ctx context.Context, db *sql.DB, meta *Meta, opts ...ReflectOption) error {
+	if meta == nil {
+		return ERR_NO_META_STRUCT
+	}
+	if db == nil {
+		return ERR_NO_DB
+	}
+	o := &reflectOptions{
+		dialect: meta.dialect,
+		schemas: meta.schemas,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return err
+	}
+	dialect := o.dialect
+	if dialect == DIALECT_UNKNOWN {
+		dialect = dialectFromDriver(db)
+	}
+	if dialect == DIALECT_UNKNOWN {
+		return ERR_REFLECT_NO_DIALECT
+	}
+
+	schemaName, defaultSchema, err := getSchemaNames(ctx, dialect, db)
+	if err != nil {
+		return fmt.Errorf("Failed to determine current database: %w", err)
+	}
+	scope := &reflectScope{
+		catalog: schemaName,
+		schemas: o.schemas,
+	}
+	if len(scope.schemas) == 0 {
+		scope.schemas = []string{defaultSchema}
+	}
+
+	tables, err := fillTables(ctx, db, dialect, scope, o)
+	if err != nil {
+		return fmt.Errorf("Failed to reflect tables: %w", err)
+	}
+	steps := []struct {
+		what string
+		fill func(context.Context, *sql.DB, Dialect, *reflectScope, *map[string]*Table) error
+	}{
+		{"columns", fillTableColumns},
+		{"constraints", fillConstraints},
+		{"foreign keys", fillForeignKeys},
+		{"indexes", fillIndexes},
+	}
+	for _, step := range steps {
+		if err = step.fill(ctx, db, dialect, scope, &tables); err != nil {
+			return fmt.Errorf("Failed to reflect %s: %w", step.what, err)
+		}
+	}
+
+	// Only modify the Meta once everything has been successfully discovered
+	// so that a failure leaves the Meta untouched
+	meta.schemaName = schemaName
+	meta.defaultSchema = defaultSchema
+	meta.db = db
+	meta.dialect = dialect
+	meta.mergeReflected(tables, scope, o)
+	return nil
+}
+
+// Grabs information about the tables and views in the scope's schemas that
+// pass the options' table name patterns and returns a map of TableDef
+// descriptors keyed by reflectKey()
+func fillTables(ctx context.Context, db *sql.DB, dialect Dialect, scope *reflectScope, o *reflectOptions) (map[string]*Table, error) {
+	var qs string
+	switch dialect {
+	case DIALECT_MYSQL:
+		qs = `
+SELECT t.TABLE_SCHEMA, t.TABLE_NAME, t.TABLE_TYPE,
+ COALESCE(v.IS_UPDATABLE, 'NO')
+FROM INFORMATION_SCHEMA.TABLES AS t
+LEFT JOIN INFORMATION_SCHEMA.VIEWS AS v
+ ON v.TABLE_SCHEMA = t.TABLE_SCHEMA
+ AND v.TABLE_NAME = t.TABLE_NAME
+WHERE t.TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+AND %s
+ORDER BY t.TABLE_SCHEMA, t.TABLE_NAME
+`
+	case DIALECT_POSTGRESQL:
+		// Materialized views do not appear in PostgreSQL's information schema,
+		// so we need to go to the system catalogs for them. The schema filter
+		// is repeated in the second half of the UNION and reuses the same
+		// query arguments.
+		qs = `
+SELECT t.TABLE_SCHEMA::text, t.TABLE_NAME::text, t.TABLE_TYPE::text,
+ COALESCE(v.IS_UPDATABLE, 'NO')::text
+FROM INFORMATION_SCHEMA.TABLES AS t
+LEFT JOIN INFORMATION_SCHEMA.VIEWS AS v
+ ON v.TABLE_SCHEMA = t.TABLE_SCHEMA
+ AND v.TABLE_NAME = t.TABLE_NAME
+WHERE t.TABLE_CATALOG = $1
+AND %s
+AND t.TABLE_TYPE IN ('BASE TABLE', 'VIEW')
+UNION ALL
+SELECT mv.schemaname::text, mv.matviewname::text, 'MATERIALIZED VIEW', 'NO'
+FROM pg_catalog.pg_matviews AS mv
+WHERE $1 = CURRENT_DATABASE()
+AND %s
+ORDER BY 1, 2
+`
+	}
+	filter, qargs := scope.filter(dialect, "t.TABLE_SCHEMA")
+	if dialect == DIALECT_POSTGRESQL {
+		mvFilter, _ := scope.filter(dialect, "mv.schemaname")
+		qs = fmt.Sprintf(qs, filter, mvFilter)
+	} else {
+		qs = fmt.Sprintf(qs, filter)
+	}
+	rows, err := db.QueryContext(ctx, qs, qargs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make(map[string]*Table, 0)
+	for rows.Next() {
+		t := &Table{reflected: true}
+		var tableType string
+		var isUpdatable string
+		err = rows.Scan(&t.schema, &t.name, &tableType, &isUpdatable)
+		if err != nil {
+			return nil, err
+		}
+		if !o.matches(t.schema, t.name) {
+			continue
+		}
+		switch tableType {
+		case "VIEW":
+			t.kind = TABLE_KIND_VIEW
+			t.updatable = isUpdatable == "YES"
+		case "MATERIALIZED VIEW":
+			t.kind = TABLE_KIND_MATERIALIZED_VIEW
+		}
+		tables[reflectKey(t.schema, t.name)] = t
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// Merges freshly reflected TableDef descriptors into the Meta. A reflected
+// table that already exists in the Meta is merged into the existing Table,
+// reusing existing Columns with the same name, so that pointers held by
+// callers remain valid. Tables that were reflected before, fall within the
+// scope and options of this reflection and were not reflected this time have
+// been dropped from the database and are removed.
+func (m *Meta) mergeReflected(tables map[string]*Table, scope *reflectScope, o *reflectOptions) {
+	if m.tables == nil {
+		m.tables = make(map[string]*Table, len(tables))
+	}
+	// Map of freshly reflected objects to the objects that end up in the
+	// Meta, used to repoint references between tables
+	finalTables := make(map[*Table]*Table, len(tables))
+	finalCols := make(map[*Column]*Column, 0)
+	for _, t := range tables {
+		key := m.tableKey(t.schema, t.name)
+		existing, found := m.tables[key]
+		if !found {
+			t.meta = m
+			m.tables[key] = t
+			finalTables[t] = t
+			for _, c := range t.columns {
+				finalCols[c] = c
+			}
+			continue
+		}
+		existing.schema = t.schema
+		existing.kind = t.kind
+		existing.updatable = t.updatable
+		existing.reflected = true
+		cols := make([]*Column, len(t.columns))
+		for x, c := range t.columns {
+			final := c
+			for _, ec := range existing.columns {
+				if ec.name == c.name {
+					final = ec
+					break
+				}
+			}
+			final.tbl = existing
+			final.def = c.def
+			cols[x] = final
+			finalCols[c] = final
+		}
+		existing.columns = cols
+		existing.constraints = t.constraints
+		existing.foreignKeys = t.foreignKeys
+		existing.indexes = t.indexes
+		finalTables[t] = existing
+	}
+	remapCols := func(cols []*Column) {
+		for x, c := range cols {
+			if final, found := finalCols[c]; found {
+				cols[x] = final
+			}
+		}
+	}
+	for _, t := range finalTables {
+		for _, con := range t.constraints {
+			con.tbl = t
+			remapCols(con.columns)
+		}
+		for _, fk := range t.foreignKeys {
+			fk.tbl = t
+			if final, found := finalTables[fk.refTable]; found {
+				fk.refTable = final
+			}
+			remapCols(fk.columns)
+			remapCols(fk.refColumns)
+		}
+		for _, idx := range t.indexes {
+			idx.tbl = t
+			remapCols(idx.columns)
+		}
+	}
+
+	// Remove previously reflected tables that have been dropped
+	inScope := make(map[string]bool, len(scope.schemas))
+	for _, schema := range scope.schemas {
+		inScope[schema] = true
+	}
+	for key, t := range m.tables {
+		if !t.reflected || !inScope[t.schema] || !o.matches(t.schema, t.name) {
+			continue
+		}
+		if _, found := tables[reflectKey(t.schema, t.name)]; !found {
+			delete(m.tables, key)
+		}
+	}
+}
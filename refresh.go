@@ -0,0 +1,127 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	ERR_REFRESH_NO_TARGET         = errors.New("No target materialized view supplied.")
+	ERR_REFRESH_NOT_MATERIALIZED  = errors.New("Target is not a materialized view.")
+	ERR_REFRESH_CONCURRENT_NODATA = errors.New("CONCURRENTLY and WITH NO DATA may not be used together.")
+)
+
+type RefreshQuery struct {
+	e       error
+	stmt    *refreshStatement
+	scanner *sqlScanner
+}
+
+func (q *RefreshQuery) IsValid() bool {
+	return q.e == nil && q.stmt != nil
+}
+
+func (q *RefreshQuery) Error() error {
+	return q.e
+}
+
+// Returns the SQL string for the query. Safe for concurrent use by multiple
+// goroutines as long as the query is not being modified.
+func (q *RefreshQuery) String() string {
+	b, _ := q.scanner.appendSQL(nil, nil, q.stmt)
+	return string(b)
+}
+
+// Returns the SQL string for the query along with a new slice containing the
+// query arguments, which is always empty. Safe for concurrent use by multiple
+// goroutines as long as the query is not being modified.
+func (q *RefreshQuery) StringArgs() (string, []interface{}) {
+	b, args := q.scanner.appendSQL(nil, nil, q.stmt)
+	return string(b), args
+}
+
+// Appends the SQL string for the query to dst and the query arguments to
+// args, returning the extended slices
+func (q *RefreshQuery) AppendSQL(dst []byte, args []interface{}) ([]byte, []interface{}) {
+	return q.scanner.appendSQL(dst, args, q.stmt)
+}
+
+// Writes the SQL string for the query to the supplied io.Writer. Implements
+// the io.WriterTo interface.
+func (q *RefreshQuery) WriteTo(w io.Writer) (int64, error) {
+	return q.scanner.writeTo(w, q.stmt)
+}
+
+// Returns the SQL string for the query. A RefreshQuery has no query
+// arguments, so this is the same as String().
+func (q *RefreshQuery) Interpolated() string {
+	return q.String()
+}
+
+// Returns the statement's Fingerprint, which identifies the shape of the statement
+// independently of its argument values. Returns a zero Fingerprint if the
+// statement has an error.
+func (q *RefreshQuery) Fingerprint() Fingerprint {
+	if !q.IsValid() {
+		return Fingerprint{}
+	}
+	return fingerprintOf(q.scanner.dialect, q.stmt)
+}
+
+// Refresh the materialized view without locking out concurrent SELECTs
+// against the view. PostgreSQL requires a unique index on the materialized
+// view to refresh it concurrently.
+func (q *RefreshQuery) Concurrently() *RefreshQuery {
+	if q.e != nil {
+		return q
+	}
+	if q.stmt.noData {
+		q.e = ERR_REFRESH_CONCURRENT_NODATA
+		return q
+	}
+	q.stmt.concurrently = true
+	return q
+}
+
+// Empty the materialized view and leave it in an unscannable state instead
+// of executing the view's query
+func (q *RefreshQuery) WithNoData() *RefreshQuery {
+	if q.e != nil {
+		return q
+	}
+	if q.stmt.concurrently {
+		q.e = ERR_REFRESH_CONCURRENT_NODATA
+		return q
+	}
+	q.stmt.noData = true
+	return q
+}
+
+// Given a materialized view, returns a RefreshQuery that will produce a
+// REFRESH MATERIALIZED VIEW SQL statement
+func RefreshMaterializedView(t *Table) *RefreshQuery {
+	if t == nil {
+		return &RefreshQuery{e: ERR_REFRESH_NO_TARGET}
+	}
+	if t.kind != TABLE_KIND_MATERIALIZED_VIEW {
+		return &RefreshQuery{e: ERR_REFRESH_NOT_MATERIALIZED}
+	}
+
+	scanner := newSqlScanner(t.meta.dialect, defaultFormatOptions)
+	stmt := &refreshStatement{
+		view: t,
+	}
+	return &RefreshQuery{
+		stmt:    stmt,
+		scanner: scanner,
+	}
+}
+
+func (t *Table) Refresh() *RefreshQuery {
+	return RefreshMaterializedView(t)
+}
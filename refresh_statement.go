@@ -0,0 +1,42 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+// REFRESH MATERIALIZED VIEW [CONCURRENTLY] <view> [WITH NO DATA]
+
+type refreshStatement struct {
+	view         *Table
+	concurrently bool
+	noData       bool
+}
+
+func (s *refreshStatement) argCount() int {
+	return 0
+}
+
+func (s *refreshStatement) size(scanner *sqlScanner) int {
+	size := len(Symbols[SYM_REFRESH_MATERIALIZED_VIEW]) + s.view.nameSize()
+	if s.concurrently {
+		size += len(Symbols[SYM_CONCURRENTLY])
+	}
+	if s.noData {
+		size += len(Symbols[SYM_WITH_NO_DATA])
+	}
+	return size
+}
+
+func (s *refreshStatement) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
+	bw := copy(b, Symbols[SYM_REFRESH_MATERIALIZED_VIEW])
+	if s.concurrently {
+		bw += copy(b[bw:], Symbols[SYM_CONCURRENTLY])
+	}
+	// We don't add any table alias when outputting the view identifier
+	bw += s.view.scanName(b[bw:])
+	if s.noData {
+		bw += copy(b[bw:], Symbols[SYM_WITH_NO_DATA])
+	}
+	return bw
+}
@@ -0,0 +1,103 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	counts := m.NewMaterializedView("author_counts")
+	counts.NewColumn("author")
+	daily := m.NewMaterializedView("reports.daily_totals")
+	users := m.NewTable("users")
+	titles := m.NewView("article_titles")
+
+	tests := []struct {
+		name string
+		q    *RefreshQuery
+		qs   string
+		qe   error
+	}{
+		{
+			name: "Simple REFRESH",
+			q:    RefreshMaterializedView(counts),
+			qs:   "REFRESH MATERIALIZED VIEW author_counts",
+		},
+		{
+			name: "REFRESH CONCURRENTLY",
+			q:    counts.Refresh().Concurrently(),
+			qs:   "REFRESH MATERIALIZED VIEW CONCURRENTLY author_counts",
+		},
+		{
+			name: "REFRESH WITH NO DATA",
+			q:    counts.Refresh().WithNoData(),
+			qs:   "REFRESH MATERIALIZED VIEW author_counts WITH NO DATA",
+		},
+		{
+			name: "Schema-qualified view",
+			q:    RefreshMaterializedView(daily),
+			qs:   "REFRESH MATERIALIZED VIEW reports.daily_totals",
+		},
+		{
+			name: "Aliased view",
+			q:    RefreshMaterializedView(counts.As("c")),
+			qs:   "REFRESH MATERIALIZED VIEW author_counts",
+		},
+		{
+			name: "No target",
+			q:    RefreshMaterializedView(nil),
+			qe:   ERR_REFRESH_NO_TARGET,
+		},
+		{
+			name: "Table target",
+			q:    RefreshMaterializedView(users),
+			qe:   ERR_REFRESH_NOT_MATERIALIZED,
+		},
+		{
+			name: "View target",
+			q:    RefreshMaterializedView(titles),
+			qe:   ERR_REFRESH_NOT_MATERIALIZED,
+		},
+		{
+			name: "CONCURRENTLY on a query with an error",
+			q:    RefreshMaterializedView(nil).Concurrently(),
+			qe:   ERR_REFRESH_NO_TARGET,
+		},
+		{
+			name: "WITH NO DATA on a query with an error",
+			q:    users.Refresh().WithNoData(),
+			qe:   ERR_REFRESH_NOT_MATERIALIZED,
+		},
+		{
+			name: "WITH NO DATA and CONCURRENTLY keep the first error",
+			q:    titles.Refresh().WithNoData().Concurrently(),
+			qe:   ERR_REFRESH_NOT_MATERIALIZED,
+		},
+		{
+			name: "CONCURRENTLY and WITH NO DATA",
+			q:    counts.Refresh().Concurrently().WithNoData(),
+			qe:   ERR_REFRESH_CONCURRENT_NODATA,
+		},
+	}
+	for _, test := range tests {
+		if test.qe != nil {
+			assert.Equal(test.qe, test.q.Error(), test.name)
+			assert.False(test.q.IsValid(), test.name)
+			continue
+		}
+		assert.True(test.q.IsValid(), test.name)
+		qs, qargs := test.q.StringArgs()
+		assert.Equal(test.qs, qs, test.name)
+		assert.Equal(0, len(qargs), test.name)
+		assert.Equal(test.qs, test.q.Interpolated(), test.name)
+	}
+}
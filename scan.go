@@ -0,0 +1,445 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	ERR_SCAN_INVALID_DEST    = errors.New("Expected a pointer to a struct, a map[string]interface{} or a slice of either.")
+	ERR_SCAN_UNMAPPED_COLUMN = errors.New("Result column does not map to a struct field.")
+)
+
+type scanOptions struct {
+	strict bool
+}
+
+// A ScanOption modifies how SelectQuery.ScanAll() and SelectQuery.ScanOne()
+// bind result columns to their destination
+type ScanOption func(*scanOptions)
+
+// Returns ERR_SCAN_UNMAPPED_COLUMN if a result column does not map to a field
+// of the destination struct. Without this option, such columns are
+// discarded.
+func Strict() ScanOption {
+	return func(o *scanOptions) {
+		o.strict = true
+	}
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	mapType     = reflect.TypeOf(map[string]interface{}{})
+)
+
+// A resultColumn identifies a column of a query's results by its name, which
+// is the projection's alias if it has one, and by the alias or name of the
+// table the column is selected from, if known
+type resultColumn struct {
+	qualifier string
+	name      string
+}
+
+func (rc resultColumn) String() string {
+	if rc.qualifier == "" {
+		return rc.name
+	}
+	return rc.qualifier + "." + rc.name
+}
+
+// Returns the resultColumns for the query's projections. The supplied column
+// names, as reported by the database, are used for projections that are not
+// columns, such as unaliased functions.
+func (q *SelectQuery) resultColumns(names []string) []resultColumn {
+	res := make([]resultColumn, len(names))
+	for x, name := range names {
+		res[x].name = name
+	}
+	if len(q.sel.projs) != len(names) {
+		return res
+	}
+	for x, p := range q.sel.projs {
+		switch p := p.(type) {
+		case *Column:
+			res[x].qualifier = p.tbl.name
+			if p.tbl.alias != "" {
+				res[x].qualifier = p.tbl.alias
+			}
+			res[x].name = p.name
+			if p.alias != "" {
+				res[x].name = p.alias
+			}
+		case *derivedColumn:
+			res[x].qualifier = p.dt.alias
+			res[x].name = p.c.name
+			if p.c.alias != "" {
+				res[x].name = p.c.alias
+			}
+			if p.alias != "" {
+				res[x].name = p.alias
+			}
+		}
+	}
+	return res
+}
+
+// Returns whether values of the supplied type are scanned field by field
+// rather than as a single value
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(scannerType)
+}
+
+// A rowBinder supplies the destinations that the columns of each result row
+// are scanned into, for a struct or a map[string]interface{}
+type rowBinder struct {
+	isMap bool
+	// For maps, the key each column is stored under. Columns that share a
+	// name are stored under their qualified names, as in "users.id".
+	keys []string
+	// For structs, the index path of the field each column is scanned into,
+	// or nil if the column is discarded
+	fields [][]int
+	// For structs, the nested struct pointers that columns are scanned into
+	nested []*nestedBinding
+	// For structs, the column names used in errors
+	cols []resultColumn
+}
+
+// A nestedBinding is a pointer to a nested struct that result columns are
+// scanned into. The columns are scanned into temporaries so that the pointer
+// can be left nil when all of them are NULL, as for the right-hand table of
+// an OUTER JOIN with no matching row.
+type nestedBinding struct {
+	// The index path of the pointer field
+	index []int
+	// The result columns scanned into the nested struct's fields
+	cols []int
+}
+
+func newRowBinder(t reflect.Type, cols []resultColumn, opts *scanOptions) (*rowBinder, error) {
+	if t == mapType {
+		counts := make(map[string]int, len(cols))
+		for _, rc := range cols {
+			counts[rc.name]++
+		}
+		keys := make([]string, len(cols))
+		for x, rc := range cols {
+			keys[x] = rc.name
+			if counts[rc.name] > 1 {
+				keys[x] = rc.String()
+			}
+		}
+		return &rowBinder{isMap: true, keys: keys}, nil
+	}
+	fields, err := structFields(t)
+	if err != nil {
+		return nil, err
+	}
+	b := &rowBinder{fields: make([][]int, len(cols)), cols: cols}
+	for x, rc := range cols {
+		index, nestedIndex := bindField(t, fields, rc)
+		b.fields[x] = index
+		if index == nil && opts.strict {
+			return nil, fmt.Errorf("%w Column: %s", ERR_SCAN_UNMAPPED_COLUMN, rc)
+		}
+		if nestedIndex != nil && t.FieldByIndex(nestedIndex).Type.Kind() == reflect.Ptr {
+			b.addNested(nestedIndex, x)
+		}
+	}
+	return b, nil
+}
+
+func (b *rowBinder) addNested(index []int, col int) {
+	for _, nb := range b.nested {
+		if reflect.DeepEqual(nb.index, index) {
+			nb.cols = append(nb.cols, col)
+			return
+		}
+	}
+	b.nested = append(b.nested, &nestedBinding{index: index, cols: []int{col}})
+}
+
+// Returns the index path of the field that the supplied column is scanned
+// into, or nil if there is none. A column selected from a table is scanned
+// into the field of a nested struct whose column name matches the table's
+// alias or name, if the struct has such a field, and otherwise into the
+// field whose column name matches the column's. The second return value is
+// the index path of the nested struct field, if any.
+func bindField(t reflect.Type, fields []structField, rc resultColumn) ([]int, []int) {
+	if rc.qualifier != "" {
+		for _, f := range fields {
+			ft := t.FieldByIndex(f.index).Type
+			if f.column != rc.qualifier || !isNestedStruct(ft) {
+				continue
+			}
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			nested, err := structFields(ft)
+			if err != nil {
+				continue
+			}
+			for _, nf := range nested {
+				if nf.column == rc.name {
+					index := make([]int, 0, len(f.index)+len(nf.index))
+					index = append(index, f.index...)
+					return append(index, nf.index...), f.index
+				}
+			}
+		}
+	}
+	for _, f := range fields {
+		if f.column == rc.name && !isNestedStruct(t.FieldByIndex(f.index).Type) {
+			return f.index, nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the destinations for the columns of a row that is scanned into the
+// supplied addressable struct or map value
+func (b *rowBinder) targets(v reflect.Value) []interface{} {
+	if b.isMap {
+		targets := make([]interface{}, len(b.keys))
+		for x := range targets {
+			targets[x] = new(interface{})
+		}
+		return targets
+	}
+	targets := make([]interface{}, len(b.fields))
+	for x, index := range b.fields {
+		if index == nil {
+			targets[x] = new(interface{})
+			continue
+		}
+		targets[x] = fieldByIndexAlloc(v, index).Addr().Interface()
+	}
+	for _, nb := range b.nested {
+		for _, x := range nb.cols {
+			// Scanning into a pointer to a pointer to the field's type lets
+			// database/sql record NULL as a nil pointer
+			ft := v.Type().FieldByIndex(b.fields[x]).Type
+			targets[x] = reflect.New(reflect.PtrTo(ft)).Interface()
+		}
+	}
+	return targets
+}
+
+// Stores the scanned targets in the supplied map value or in the nested
+// struct pointers of the supplied struct value. Other struct fields are
+// scanned into directly.
+func (b *rowBinder) store(v reflect.Value, targets []interface{}) error {
+	if !b.isMap {
+		for _, nb := range b.nested {
+			if err := b.storeNested(v, nb, targets); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(mapType, len(b.keys)))
+	}
+	for x, key := range b.keys {
+		v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(targets[x]).Elem())
+	}
+	return nil
+}
+
+// Sets the nested struct pointer to nil if all of its columns are NULL and
+// otherwise allocates the struct and stores the columns in its fields
+func (b *rowBinder) storeNested(v reflect.Value, nb *nestedBinding, targets []interface{}) error {
+	allNull := true
+	for _, x := range nb.cols {
+		if !reflect.ValueOf(targets[x]).Elem().IsNil() {
+			allNull = false
+			break
+		}
+	}
+	if allNull {
+		p := fieldByIndexAlloc(v, nb.index)
+		p.Set(reflect.Zero(p.Type()))
+		return nil
+	}
+	for _, x := range nb.cols {
+		f := fieldByIndexAlloc(v, b.fields[x])
+		tv := reflect.ValueOf(targets[x]).Elem()
+		if !tv.IsNil() {
+			f.Set(tv.Elem())
+			continue
+		}
+		if !isNullable(f.Type()) {
+			return fmt.Errorf(
+				"Scan error on column %s: converting NULL to %s is unsupported",
+				b.cols[x], f.Type(),
+			)
+		}
+		f.Set(reflect.Zero(f.Type()))
+	}
+	return nil
+}
+
+// Returns whether a NULL can be scanned into a value of the supplied type
+func isNullable(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface ||
+		reflect.PtrTo(t).Implements(scannerType)
+}
+
+func (b *rowBinder) scan(rows *sql.Rows, v reflect.Value) error {
+	targets := b.targets(v)
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	return b.store(v, targets)
+}
+
+// Returns the field at the supplied index path, allocating any nil pointers
+// to structs along the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for x, i := range index {
+		if x > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// Returns whether a row may be scanned into a value of the supplied type
+func isScanDest(t reflect.Type) bool {
+	return t == mapType || t.Kind() == reflect.Struct
+}
+
+func newScanOptions(opts []ScanOption) *scanOptions {
+	o := &scanOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Runs the query using the supplied Executor and stores every result row in
+// the slice pointed to by dest, replacing its contents. dest may point to a
+// slice of structs, of pointers to structs or of map[string]interface{}.
+//
+// Struct fields are matched to result columns by the rules described for
+// Meta.RegisterStruct(). A column selected from a joined table is scanned
+// into a nested struct field whose column name is the table's alias or name,
+// if there is one. Scan NULLable columns into pointer or sql.Null* fields.
+//
+// Maps are keyed by the columns' names or aliases. Columns that share a name
+// are keyed by their qualified names, as in "users.id".
+func (q *SelectQuery) ScanAll(ctx context.Context, ex Executor, dest interface{}, opts ...ScanOption) (err error) {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return ERR_SCAN_INVALID_DEST
+	}
+	sv := dv.Elem()
+	et := sv.Type().Elem()
+	isPtr := et.Kind() == reflect.Ptr
+	if isPtr {
+		et = et.Elem()
+	}
+	if !isScanDest(et) {
+		return ERR_SCAN_INVALID_DEST
+	}
+	rows, done, err := startQuery(ctx, ex, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	n := int64(0)
+	defer func() {
+		done(n, err)
+	}()
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	b, err := newRowBinder(et, q.resultColumns(names), newScanOptions(opts))
+	if err != nil {
+		return err
+	}
+	res := reflect.MakeSlice(sv.Type(), 0, 0)
+	for rows.Next() {
+		ev := reflect.New(et)
+		if err := b.scan(rows, ev.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			res = reflect.Append(res, ev)
+		} else {
+			res = reflect.Append(res, ev.Elem())
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	sv.Set(res)
+	return nil
+}
+
+// Runs the query using the supplied Executor and stores the first result row
+// in the struct or map[string]interface{} pointed to by dest. Returns
+// sql.ErrNoRows if the query returns no rows. See ScanAll() for how columns
+// are matched to struct fields and map keys.
+func (q *SelectQuery) ScanOne(ctx context.Context, ex Executor, dest interface{}, opts ...ScanOption) (err error) {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || !isScanDest(dv.Elem().Type()) {
+		return ERR_SCAN_INVALID_DEST
+	}
+	rows, done, err := startQuery(ctx, ex, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	n := int64(0)
+	defer func() {
+		// Finding no rows is a result rather than a failure of the query, so
+		// hooks aren't told about sql.ErrNoRows
+		if err == sql.ErrNoRows {
+			done(n, nil)
+			return
+		}
+		done(n, err)
+	}()
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	b, err := newRowBinder(dv.Elem().Type(), q.resultColumns(names), newScanOptions(opts))
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := b.scan(rows, dv.Elem()); err != nil {
+		return err
+	}
+	n++
+	return rows.Close()
+}
@@ -0,0 +1,165 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAuthorRow struct {
+	ID   int64
+	Name *string
+}
+
+type testArticleRow struct {
+	ID     int64
+	Author int64
+	User   *testAuthorRow `db:"users"`
+}
+
+func TestScanAll(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles")
+	q := Select(articles.C("id"), articles.C("author"), users.C("id"), users.C("name"))
+
+	rec.columns = []string{"id", "author", "id", "name"}
+	rec.rows = [][]driver.Value{
+		{int64(1), int64(10), int64(10), "fred"},
+		{int64(2), int64(11), int64(11), nil},
+	}
+
+	// Columns from the users table are scanned into the nested struct
+	rows := []testArticleRow{{ID: 99}}
+	assert.Nil(q.ScanAll(ctx, db, &rows))
+	assert.Equal(2, len(rows))
+	assert.Equal(int64(1), rows[0].ID)
+	assert.Equal(int64(10), rows[0].Author)
+	assert.Equal(int64(10), rows[0].User.ID)
+	assert.Equal("fred", *rows[0].User.Name)
+	assert.Equal(int64(2), rows[1].ID)
+	assert.Nil(rows[1].User.Name)
+
+	ptrs := []*testArticleRow{}
+	assert.Nil(q.ScanAll(ctx, db, &ptrs))
+	assert.Equal(2, len(ptrs))
+	assert.Equal(int64(11), ptrs[1].User.ID)
+
+	// A nested struct pointer is left nil when all of its columns are NULL, as
+	// for an OUTER JOIN row with no match
+	oj := Select(articles.C("id"), articles.C("author"), users.C("id"), users.C("name")).
+		OuterJoin(users, Equal(articles.C("author"), users.C("id")))
+	rec.rows = [][]driver.Value{
+		{int64(1), int64(10), int64(10), "fred"},
+		{int64(3), int64(12), nil, nil},
+	}
+	rows = []testArticleRow{{User: &testAuthorRow{ID: 99}}, {User: &testAuthorRow{ID: 99}}}
+	assert.Nil(oj.ScanAll(ctx, db, &rows))
+	assert.Equal(2, len(rows))
+	assert.Equal(int64(10), rows[0].User.ID)
+	assert.Equal(int64(3), rows[1].ID)
+	assert.Equal(int64(12), rows[1].Author)
+	assert.Nil(rows[1].User)
+
+	// NULL is still rejected for a non-nullable field of a matched row
+	rec.rows = [][]driver.Value{{int64(4), int64(13), nil, "barney"}}
+	err := oj.ScanAll(ctx, db, &rows)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "converting NULL to int64")
+
+	// Without a nested struct, a column is scanned into the field matching its
+	// name or alias
+	type flatRow struct {
+		ID       int64
+		Author   int64
+		UserName sql.NullString
+	}
+	q = Select(articles.C("id"), articles.C("author"), users.C("name").As("user_name"))
+	rec.columns = []string{"id", "author", "user_name"}
+	rec.rows = [][]driver.Value{{int64(1), int64(10), nil}}
+	flat := []flatRow{}
+	assert.Nil(q.ScanAll(ctx, db, &flat))
+	assert.Equal([]flatRow{{ID: 1, Author: 10}}, flat)
+
+	// Columns that share a name are keyed by their qualified names in maps
+	q = Select(articles.C("id"), users.C("id"), users.C("name").As("user_name"))
+	rec.columns = []string{"id", "id", "user_name"}
+	rec.rows = [][]driver.Value{{int64(1), int64(10), "fred"}}
+	maps := []map[string]interface{}{}
+	assert.Nil(q.ScanAll(ctx, db, &maps))
+	assert.Equal([]map[string]interface{}{
+		{"articles.id": int64(1), "users.id": int64(10), "user_name": "fred"},
+	}, maps)
+
+	// Result columns that don't map to a field are discarded unless the scan
+	// is strict
+	type idRow struct {
+		ID int64
+	}
+	ids := []idRow{}
+	assert.Nil(q.ScanAll(ctx, db, &ids))
+	assert.Equal([]idRow{{ID: 10}}, ids)
+	err = q.ScanAll(ctx, db, &ids, Strict())
+	assert.True(errors.Is(err, ERR_SCAN_UNMAPPED_COLUMN))
+	assert.Contains(err.Error(), "users.user_name")
+
+	rec.rows = nil
+	assert.Nil(q.ScanAll(ctx, db, &ids))
+	assert.Equal(0, len(ids))
+
+	// Invalid destinations are rejected before contacting the database
+	nstmts := len(rec.stmts)
+	assert.Equal(ERR_SCAN_INVALID_DEST, q.ScanAll(ctx, db, ids))
+	assert.Equal(ERR_SCAN_INVALID_DEST, q.ScanAll(ctx, db, &idRow{}))
+	assert.Equal(ERR_SCAN_INVALID_DEST, q.ScanAll(ctx, db, &[]int64{}))
+	bad := Select().JoinAuto(users)
+	assert.Equal(ERR_JOIN_INVALID_NO_SELECT, bad.ScanAll(ctx, db, &ids))
+	assert.Equal(nstmts, len(rec.stmts))
+}
+
+func TestScanOne(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	q := Select(users).Where(Equal(users.C("id"), 10))
+
+	rec.columns = []string{"id", "name"}
+	rec.rows = [][]driver.Value{{int64(10), "fred"}, {int64(11), "barney"}}
+
+	var u testAuthorRow
+	assert.Nil(q.ScanOne(ctx, db, &u))
+	assert.Equal(int64(10), u.ID)
+	assert.Equal("fred", *u.Name)
+
+	var row map[string]interface{}
+	assert.Nil(q.ScanOne(ctx, db, &row))
+	assert.Equal(map[string]interface{}{"id": int64(10), "name": "fred"}, row)
+
+	rec.rows = nil
+	assert.Equal(sql.ErrNoRows, q.ScanOne(ctx, db, &u))
+
+	assert.Equal(ERR_SCAN_INVALID_DEST, q.ScanOne(ctx, db, u))
+	var id int64
+	assert.Equal(ERR_SCAN_INVALID_DEST, q.ScanOne(ctx, db, &id))
+	assert.Equal(ERR_EXEC_NO_EXECUTOR, q.ScanOne(ctx, nil, &u))
+}
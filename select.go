@@ -8,6 +8,7 @@ package sqlb
 import (
 	"errors"
 	"fmt"
+	"io"
 )
 
 var (
@@ -17,8 +18,6 @@ var (
 
 type SelectQuery struct {
 	e       error
-	b       []byte
-	args    []interface{}
 	sel     *selectStatement
 	scanner *sqlScanner
 }
@@ -31,28 +30,92 @@ func (q *SelectQuery) Error() error {
 	return q.e
 }
 
+// Returns the SQL string for the query. Safe for concurrent use by multiple
+// goroutines as long as the query is not being modified.
 func (q *SelectQuery) String() string {
-	sizes := q.scanner.size(q.sel)
-	if len(q.args) != sizes.ArgCount {
-		q.args = make([]interface{}, sizes.ArgCount)
-	}
-	if len(q.b) != sizes.BufferSize {
-		q.b = make([]byte, sizes.BufferSize)
-	}
-	q.scanner.scan(q.b, q.args, q.sel)
-	return string(q.b)
+	b, _ := q.scanner.appendSQL(nil, nil, q.sel)
+	return string(b)
 }
 
+// Returns the SQL string for the query along with a new slice containing the
+// query arguments. Safe for concurrent use by multiple goroutines as long as
+// the query is not being modified.
 func (q *SelectQuery) StringArgs() (string, []interface{}) {
-	sizes := q.scanner.size(q.sel)
-	if len(q.args) != sizes.ArgCount {
-		q.args = make([]interface{}, sizes.ArgCount)
+	b, args := q.scanner.appendSQL(nil, nil, q.sel)
+	return string(b), args
+}
+
+// Renders the query's SQL string once and returns a QueryTemplate that can
+// be bound to values for the query's named params (see Param()) any number
+// of times. Returns the query's error, if any.
+func (q *SelectQuery) Compile() (*QueryTemplate, error) {
+	if q.e != nil {
+		return nil, q.e
 	}
-	if len(q.b) != sizes.BufferSize {
-		q.b = make([]byte, sizes.BufferSize)
+	qs, qargs := q.StringArgs()
+	return newQueryTemplate(qs, qargs), nil
+}
+
+// Appends the SQL string for the query to dst and the query arguments to
+// args, returning the extended slices. dst and args are only reallocated if
+// they lack the capacity to hold the query, so callers on hot paths can avoid
+// allocations by reusing buffers, e.g. from a sync.Pool.
+func (q *SelectQuery) AppendSQL(dst []byte, args []interface{}) ([]byte, []interface{}) {
+	return q.scanner.appendSQL(dst, args, q.sel)
+}
+
+// Writes the SQL string for the query to the supplied io.Writer. Implements
+// the io.WriterTo interface.
+func (q *SelectQuery) WriteTo(w io.Writer) (int64, error) {
+	return q.scanner.writeTo(w, q.sel)
+}
+
+// Returns the SQL string for the query with all query arguments inlined as
+// dialect-specific SQL literals. This is useful for debugging and logging
+// but the returned string should NOT be executed against a database. Use
+// StringArgs() and the database driver's parameter binding instead.
+func (q *SelectQuery) Interpolated() string {
+	qs, args := q.StringArgs()
+	return interpolate(q.scanner.dialect, qs, args)
+}
+
+// Returns the query's Fingerprint, which identifies the shape of the query
+// independently of its argument values. Returns a zero Fingerprint if the
+// query has an error.
+func (q *SelectQuery) Fingerprint() Fingerprint {
+	if !q.IsValid() {
+		return Fingerprint{}
 	}
-	q.scanner.scan(q.b, q.args, q.sel)
-	return string(q.b), q.args
+	return fingerprintOf(q.scanner.dialect, q.sel)
+}
+
+// Adds a comment that will be output before the SQL statement
+func (q *SelectQuery) Comment(text string) *SelectQuery {
+	q.sel.comments = addLeadingComment(q.sel.comments, text)
+	return q
+}
+
+// Adds a comment that will be output after the SQL statement
+func (q *SelectQuery) TrailingComment(text string) *SelectQuery {
+	q.sel.comments = addTrailingComment(q.sel.comments, text)
+	return q
+}
+
+// Adds a sqlcommenter-style key='value' tag to the comment that is output
+// after the SQL statement. Setting a key that already exists replaces the
+// key's value.
+func (q *SelectQuery) Tag(key string, value string) *SelectQuery {
+	q.sel.comments = addTag(q.sel.comments, key, value)
+	return q
+}
+
+// Adds an optimizer hint, e.g. "INDEX(users ix_email)", to the SELECT
+// statement. For MySQL, hints are output directly after the SELECT keyword.
+// For PostgreSQL, hints are output in a comment at the start of the
+// statement, which is where the pg_hint_plan extension looks for them.
+func (q *SelectQuery) Hint(hint string) *SelectQuery {
+	q.sel.comments = addHint(q.sel.comments, hint)
+	return q
 }
 
 func (q *SelectQuery) Where(e *Expression) *SelectQuery {
@@ -152,6 +215,60 @@ func (q *SelectQuery) OuterJoin(right interface{}, on *Expression) *SelectQuery
 	return q.doJoin(JOIN_OUTER, rightSel, on)
 }
 
+// Join to a supplied table, deriving the ON expression from the single foreign
+// key that relates the table to a table already in the SelectQuery. For
+// example, given a foreign key from articles.author to users.id, the
+// following:
+//
+// Select(articles).JoinAuto(users)
+//
+// is equivalent to:
+//
+// Select(articles).Join(users, Equal(articles.C("author"), users.C("id")))
+//
+// Aliased tables created with Table.As() may be used on either side. If no
+// foreign key or more than one foreign key relates the table to the tables in
+// the SelectQuery, SelectQuery.e will be set to an error.
+func (q *SelectQuery) JoinAuto(right *Table) *SelectQuery {
+	return q.doJoinAuto(JOIN_INNER, right)
+}
+
+// Same as JoinAuto() but produces a LEFT JOIN
+func (q *SelectQuery) OuterJoinAuto(right *Table) *SelectQuery {
+	return q.doJoinAuto(JOIN_OUTER, right)
+}
+
+func (q *SelectQuery) doJoinAuto(jt joinType, right *Table) *SelectQuery {
+	if q.sel == nil || len(q.sel.selections) == 0 {
+		q.e = ERR_JOIN_INVALID_NO_SELECT
+		return q
+	}
+	tables := make([]*Table, 0, len(q.sel.selections)+len(q.sel.joins))
+	addTable := func(sel selection) {
+		if t, ok := sel.(*Table); ok && t != right {
+			for _, existing := range tables {
+				if existing == t {
+					return
+				}
+			}
+			tables = append(tables, t)
+		}
+	}
+	for _, sel := range q.sel.selections {
+		addTable(sel)
+	}
+	for _, j := range q.sel.joins {
+		addTable(j.left)
+		addTable(j.right)
+	}
+	on, err := inferJoin(tables, right)
+	if err != nil {
+		q.e = err
+		return q
+	}
+	return q.doJoin(jt, right, on)
+}
+
 // Join to a supplied selection with the supplied ON expression. If the SelectQuery
 // does not yet contain a selectStatement OR if the supplied ON expression does
 // not reference any selection that is found in the SelectQuery's selectStatement, then
@@ -258,10 +375,7 @@ func (q *SelectQuery) doJoin(
 }
 
 func Select(items ...interface{}) *SelectQuery {
-	scanner := &sqlScanner{
-		dialect: DIALECT_UNKNOWN,
-		format:  defaultFormatOptions,
-	}
+	scanner := newSqlScanner(DIALECT_UNKNOWN, defaultFormatOptions)
 	sq := &SelectQuery{
 		scanner: scanner,
 	}
@@ -327,13 +441,13 @@ func Select(items ...interface{}) *SelectQuery {
 		case *Column:
 			v := item.(*Column)
 			// Set scanner's dialect based on supplied meta's dialect
-			sq.scanner.dialect = v.tbl.meta.dialect
+			sq.scanner.setDialect(v.tbl.meta.dialect)
 			sel.projs = append(sel.projs, v)
 			selectionMap[v.tbl] = true
 		case *Table:
 			v := item.(*Table)
 			// Set scanner's dialect based on supplied meta's dialect
-			sq.scanner.dialect = v.meta.dialect
+			sq.scanner.setDialect(v.meta.dialect)
 			for _, c := range v.projections() {
 				addToProjections(sel, c)
 			}
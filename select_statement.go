@@ -14,6 +14,7 @@ type selectStatement struct {
 	having     *havingClause
 	orderBy    *orderByClause
 	limit      *limitClause
+	comments   *commentClause
 }
 
 func (s *selectStatement) argCount() int {
@@ -47,6 +48,11 @@ func (s *selectStatement) argCount() int {
 
 func (s *selectStatement) size(scanner *sqlScanner) int {
 	size := len(Symbols[SYM_SELECT])
+	if s.comments != nil {
+		size += s.comments.leadingSize(scanner)
+		size += s.comments.inlineSize(scanner)
+		size += s.comments.trailingSize(scanner)
+	}
 	nprojs := len(s.projs)
 	for _, p := range s.projs {
 		size += p.size(scanner)
@@ -84,7 +90,13 @@ func (s *selectStatement) size(scanner *sqlScanner) int {
 
 func (s *selectStatement) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	bw := 0
+	if s.comments != nil {
+		bw += s.comments.scanLeading(scanner, b[bw:])
+	}
 	bw += copy(b[bw:], Symbols[SYM_SELECT])
+	if s.comments != nil {
+		bw += s.comments.scanInline(scanner, b[bw:])
+	}
 	nprojs := len(s.projs)
 	for x, p := range s.projs {
 		bw += p.scan(scanner, b[bw:], args, curArg)
@@ -121,6 +133,9 @@ func (s *selectStatement) scan(scanner *sqlScanner, b []byte, args []interface{}
 	if s.limit != nil {
 		bw += s.limit.scan(scanner, b[bw:], args, curArg)
 	}
+	if s.comments != nil {
+		bw += s.comments.scanTrailing(scanner, b[bw:])
+	}
 	return bw
 }
 
@@ -6,7 +6,9 @@
 package sqlb
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -497,3 +499,158 @@ func TestSelectQueryErrors(t *testing.T) {
 	assert.False(q.IsValid())
 	assert.NotNil(q.Error())
 }
+
+// Rendering a shared SelectQuery from many goroutines at once must always
+// produce the same SQL string and arguments. Run with -race to verify that
+// rendering does not mutate shared state.
+func TestSelectQueryConcurrentRender(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles").As("a")
+	colUserId := users.C("id")
+	colUserName := users.C("name").As("user_name")
+	colArticleAuthor := articles.C("author")
+
+	q := Select(
+		colUserName,
+		Count(articles).As("num_articles"),
+		Max(colArticleAuthor).As("max_author"),
+	).Join(
+		articles, Equal(colUserId, colArticleAuthor),
+	).Where(
+		Equal(Trim(colUserName), "foo"),
+	).GroupBy(colUserName).OrderBy(colUserName.Desc()).Limit(10)
+
+	expqs := "SELECT users.name AS user_name, COUNT(*) AS num_articles, MAX(a.author) AS max_author FROM users JOIN articles AS a ON users.id = a.author WHERE TRIM(users.name) = ? GROUP BY users.name ORDER BY users.name DESC LIMIT ?"
+	expqargs := []interface{}{"foo", 10}
+
+	var wg sync.WaitGroup
+	nworkers := 8
+	results := make(chan string, nworkers*200)
+	for w := 0; w < nworkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf []byte
+			var bufArgs []interface{}
+			for x := 0; x < 100; x++ {
+				qs, qargs := q.StringArgs()
+				if fmt.Sprint(qargs) != fmt.Sprint(expqargs) {
+					qs = fmt.Sprintf("unexpected args: %v", qargs)
+				}
+				results <- qs
+				buf, bufArgs = q.AppendSQL(buf[:0], bufArgs[:0])
+				results <- string(buf)
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	for qs := range results {
+		assert.Equal(expqs, qs)
+	}
+}
+
+func TestSelectJoinAuto(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	articles := m.Table("articles")
+	articleStates := m.Table("article_states")
+	userProfiles := m.Table("user_profiles")
+	colUserId := users.C("id")
+
+	articles.NewForeignKey(
+		"fk_author",
+		[]*Column{articles.C("author")},
+		[]*Column{colUserId},
+	)
+	articles.NewForeignKey(
+		"fk_state",
+		[]*Column{articles.C("state")},
+		[]*Column{articleStates.C("id")},
+	)
+	userProfiles.NewForeignKey(
+		"fk_user",
+		[]*Column{userProfiles.C("user")},
+		[]*Column{colUserId},
+	)
+
+	// A self-referencing foreign key
+	emps := m.NewTable("employees")
+	emps.NewColumn("id")
+	emps.NewColumn("manager")
+	emps.NewForeignKey(
+		"fk_manager",
+		[]*Column{emps.C("manager")},
+		[]*Column{emps.C("id")},
+	)
+	e := emps.As("e")
+	mgr := emps.As("m")
+
+	tests := []struct {
+		name  string
+		q     *SelectQuery
+		qs    string
+		qargs []interface{}
+		qe    error
+	}{
+		{
+			name: "Referencing table joined to referenced table",
+			q:    Select(articles.C("id"), users.C("name")).JoinAuto(users),
+			qs:   "SELECT articles.id, users.name FROM articles JOIN users ON articles.author = users.id",
+		},
+		{
+			name: "Referenced table joined to referencing table",
+			q:    Select(users.C("name"), articles.C("id")).OuterJoinAuto(articles),
+			qs:   "SELECT users.name, articles.id FROM users LEFT JOIN articles ON articles.author = users.id",
+		},
+		{
+			name: "Aliased tables",
+			q:    Select(articles.As("a").C("id")).JoinAuto(users.As("u")),
+			qs:   "SELECT a.id FROM articles AS a JOIN users AS u ON a.author = u.id",
+		},
+		{
+			name: "Multiple joins",
+			q: Select(articles.C("id")).JoinAuto(users).
+				JoinAuto(userProfiles),
+			qs: "SELECT articles.id FROM articles JOIN users ON articles.author = users.id JOIN user_profiles ON user_profiles.user = users.id",
+		},
+		{
+			name: "Self-referencing foreign key",
+			q:    Select(e.C("id"), mgr.C("id")).JoinAuto(mgr),
+			qs:   "SELECT e.id, m.id FROM employees AS e JOIN employees AS m ON e.manager = m.id",
+		},
+		{
+			name: "No select",
+			q:    Select().JoinAuto(users),
+			qe:   ERR_JOIN_INVALID_NO_SELECT,
+		},
+		{
+			name: "No foreign key",
+			q:    Select(articleStates).JoinAuto(users),
+			qe:   ERR_JOIN_AUTO_NO_FOREIGN_KEY,
+		},
+		{
+			name: "Multiple foreign keys",
+			q:    Select(articles.C("id"), userProfiles.C("id")).JoinAuto(users),
+			qe:   ERR_JOIN_AUTO_MULTIPLE_FOREIGN_KEYS,
+		},
+	}
+	for _, test := range tests {
+		if test.qe != nil {
+			assert.True(errors.Is(test.q.Error(), test.qe), test.name)
+			continue
+		}
+		assert.Nil(test.q.Error(), test.name)
+		qs, _ := test.q.StringArgs()
+		assert.Equal(test.qs, qs, test.name)
+	}
+
+	// Errors name the candidate foreign keys
+	q := Select(articles.C("id"), userProfiles.C("id")).JoinAuto(users)
+	assert.Contains(q.Error().Error(), "articles.fk_author, user_profiles.fk_user")
+}
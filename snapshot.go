@@ -0,0 +1,513 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The version of the schema snapshot document written by Meta.MarshalJSON().
+// The version is incremented whenever the document changes in a way that
+// older versions of sqlb cannot read.
+const SNAPSHOT_VERSION = 1
+
+var (
+	ERR_SNAPSHOT_VERSION = errors.New("Unsupported schema snapshot version.")
+	ERR_SNAPSHOT_INVALID = errors.New("Invalid schema snapshot.")
+)
+
+var (
+	// Snapshots store names rather than the numeric values of enumerated types
+	// so that documents remain readable and stay valid if new values are added
+	// to the enumerations
+	dialectSnapshotNames = map[Dialect]string{
+		DIALECT_UNKNOWN:    "",
+		DIALECT_MYSQL:      "mysql",
+		DIALECT_POSTGRESQL: "postgresql",
+	}
+	sqlTypeSnapshotNames = map[SqlType]string{
+		SQL_TYPE_CHAR:      "char",
+		SQL_TYPE_INT:       "int",
+		SQL_TYPE_FLOAT:     "float",
+		SQL_TYPE_DECIMAL:   "decimal",
+		SQL_TYPE_VARCHAR:   "varchar",
+		SQL_TYPE_TEXT:      "text",
+		SQL_TYPE_BINARY:    "binary",
+		SQL_TYPE_SMALLINT:  "smallint",
+		SQL_TYPE_BIGINT:    "bigint",
+		SQL_TYPE_BOOLEAN:   "boolean",
+		SQL_TYPE_DATE:      "date",
+		SQL_TYPE_TIME:      "time",
+		SQL_TYPE_TIMESTAMP: "timestamp",
+		SQL_TYPE_JSON:      "json",
+		SQL_TYPE_UNKNOWN:   "unknown",
+	}
+	tableKindSnapshotNames = map[TableKind]string{
+		TABLE_KIND_TABLE:             "table",
+		TABLE_KIND_VIEW:              "view",
+		TABLE_KIND_MATERIALIZED_VIEW: "materialized_view",
+	}
+	constraintTypeSnapshotNames = map[ConstraintType]string{
+		CONSTRAINT_TYPE_PRIMARY_KEY: "primary_key",
+		CONSTRAINT_TYPE_UNIQUE:      "unique",
+	}
+)
+
+// metaSnapshot is the top-level schema snapshot document
+type metaSnapshot struct {
+	Version       int             `json:"version"`
+	Dialect       string          `json:"dialect,omitempty"`
+	Database      string          `json:"database,omitempty"`
+	DefaultSchema string          `json:"default_schema,omitempty"`
+	Schemas       []string        `json:"schemas,omitempty"`
+	Tables        []tableSnapshot `json:"tables"`
+}
+
+type tableSnapshot struct {
+	Schema      string               `json:"schema,omitempty"`
+	Name        string               `json:"name"`
+	Kind        string               `json:"kind,omitempty"`
+	Updatable   bool                 `json:"updatable,omitempty"`
+	Columns     []columnSnapshot     `json:"columns"`
+	Constraints []constraintSnapshot `json:"constraints,omitempty"`
+	ForeignKeys []foreignKeySnapshot `json:"foreign_keys,omitempty"`
+	Indexes     []indexSnapshot      `json:"indexes,omitempty"`
+}
+
+type columnSnapshot struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type,omitempty"`
+	RawType       string  `json:"raw_type,omitempty"`
+	Length        int     `json:"length,omitempty"`
+	Precision     int     `json:"precision,omitempty"`
+	Scale         int     `json:"scale,omitempty"`
+	Nullable      bool    `json:"nullable"`
+	Default       *string `json:"default,omitempty"`
+	AutoIncrement bool    `json:"auto_increment,omitempty"`
+}
+
+type constraintSnapshot struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Columns []string `json:"columns"`
+}
+
+type foreignKeySnapshot struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	// The referenced table's name, qualified with its schema if the schema
+	// is not the default schema, as accepted by Meta.Table()
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+type indexSnapshot struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique,omitempty"`
+	Columns []string `json:"columns"`
+}
+
+// Reads a schema snapshot document written by Meta.MarshalJSON() from the
+// supplied io.Reader and returns a new Meta describing the tables, columns,
+// keys and indexes in the snapshot. This allows a snapshot of a database's
+// schema to be checked into a repository and loaded at startup instead of
+// calling Reflect().
+//
+// Snapshots are JSON only. Projects that keep their configuration in YAML can
+// convert a YAML snapshot to JSON with a YAML library before calling
+// LoadMeta(); sqlb doesn't depend on one.
+func LoadMeta(r io.Reader) (*Meta, error) {
+	m := &Meta{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Returns a versioned JSON document describing the Meta's tables, columns,
+// keys and indexes. Tables are ordered by schema-qualified name, so the
+// output for a given schema is stable and suitable for checking into a
+// repository. The document may be read with LoadMeta().
+func (m *Meta) MarshalJSON() ([]byte, error) {
+	doc := metaSnapshot{
+		Version:       SNAPSHOT_VERSION,
+		Dialect:       dialectSnapshotNames[m.dialect],
+		Database:      m.schemaName,
+		DefaultSchema: m.defaultSchema,
+		Schemas:       m.schemas,
+		Tables:        make([]tableSnapshot, 0, len(m.tables)),
+	}
+	for _, t := range m.Tables() {
+		doc.Tables = append(doc.Tables, t.snapshot())
+	}
+	return json.Marshal(doc)
+}
+
+// Replaces the Meta's tables with those described by a schema snapshot
+// document written by Meta.MarshalJSON()
+func (m *Meta) UnmarshalJSON(b []byte) error {
+	doc := metaSnapshot{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.Version < 1 || doc.Version > SNAPSHOT_VERSION {
+		return fmt.Errorf("%w Version: %d", ERR_SNAPSHOT_VERSION, doc.Version)
+	}
+	dialect, found := dialectFromSnapshotName(doc.Dialect)
+	if !found {
+		return fmt.Errorf("%w Unknown dialect: %s", ERR_SNAPSHOT_INVALID, doc.Dialect)
+	}
+	res := &Meta{
+		db:            m.db,
+		dialect:       dialect,
+		schemaName:    doc.Database,
+		defaultSchema: doc.DefaultSchema,
+		schemas:       doc.Schemas,
+		tables:        make(map[string]*Table, len(doc.Tables)),
+	}
+	// Create all tables and columns before any keys, as foreign keys may
+	// reference tables that appear later in the document
+	tables := make([]*Table, len(doc.Tables))
+	for x, ts := range doc.Tables {
+		t, err := res.loadTableSnapshot(ts)
+		if err != nil {
+			return err
+		}
+		tables[x] = t
+	}
+	for x, ts := range doc.Tables {
+		if err := res.loadKeySnapshots(tables[x], ts); err != nil {
+			return err
+		}
+	}
+	*m = *res
+	for _, t := range m.tables {
+		t.meta = m
+	}
+	return nil
+}
+
+// Returns a JSON document describing the table's columns, keys and indexes,
+// in the same form as the table's entry in the document written by
+// Meta.MarshalJSON()
+func (t *Table) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.snapshot())
+}
+
+// Replaces the table's name, columns, keys and indexes with those described
+// by a JSON document written by Table.MarshalJSON().
+//
+// Foreign keys are resolved against the tables of the table's Meta, so a
+// table whose foreign keys reference other tables must belong to the Meta
+// holding those tables, for instance a table returned by Meta.NewTable(). The
+// table is registered in its Meta under the name in the document. A table
+// without a Meta, such as a new(Table), is given a Meta of its own that has
+// no SQL dialect. Use LoadMeta() to load a whole schema.
+func (t *Table) UnmarshalJSON(b []byte) error {
+	ts := tableSnapshot{}
+	if err := json.Unmarshal(b, &ts); err != nil {
+		return err
+	}
+	m := t.meta
+	if m == nil {
+		m = &Meta{tables: make(map[string]*Table, 1)}
+	}
+	key := m.tableKey(ts.Schema, ts.Name)
+	if other, exists := m.tables[key]; exists && other != t {
+		return fmt.Errorf("%w Duplicate table: %s", ERR_SNAPSHOT_INVALID, key)
+	}
+	saved := *t
+	oldKey := ""
+	if t.meta != nil && m.tables[m.tableKey(t.schema, t.name)] == t {
+		oldKey = m.tableKey(t.schema, t.name)
+	}
+	if err := m.setTableSnapshot(t, ts); err != nil {
+		return err
+	}
+	if oldKey != "" {
+		delete(m.tables, oldKey)
+	}
+	m.tables[key] = t
+	if err := m.loadKeySnapshots(t, ts); err != nil {
+		// Put the table back as it was
+		delete(m.tables, key)
+		*t = saved
+		if oldKey != "" {
+			m.tables[oldKey] = t
+		}
+		return err
+	}
+	return nil
+}
+
+// Returns a JSON document describing the column's name and definition, in
+// the same form as the column's entry in the document written by
+// Meta.MarshalJSON()
+func (c *Column) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.snapshot())
+}
+
+// Sets the column's name and definition from a JSON document written by
+// Column.MarshalJSON(). The column's table is not changed.
+func (c *Column) UnmarshalJSON(b []byte) error {
+	cs := columnSnapshot{}
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return err
+	}
+	def, err := cs.def()
+	if err != nil {
+		return err
+	}
+	c.name = cs.Name
+	c.def = def
+	return nil
+}
+
+func (t *Table) snapshot() tableSnapshot {
+	ts := tableSnapshot{
+		Schema:    t.schema,
+		Name:      t.name,
+		Kind:      tableKindSnapshotNames[t.kind],
+		Updatable: t.updatable,
+		Columns:   make([]columnSnapshot, len(t.columns)),
+	}
+	for x, c := range t.columns {
+		ts.Columns[x] = c.snapshot()
+	}
+	for _, con := range t.constraints {
+		ts.Constraints = append(ts.Constraints, constraintSnapshot{
+			Name:    con.name,
+			Type:    constraintTypeSnapshotNames[con.ctype],
+			Columns: columnNames(con.columns),
+		})
+	}
+	for _, fk := range t.foreignKeys {
+		fks := foreignKeySnapshot{
+			Name:              fk.name,
+			Columns:           columnNames(fk.columns),
+			ReferencedColumns: columnNames(fk.refColumns),
+			OnDelete:          fk.onDelete.String(),
+			OnUpdate:          fk.onUpdate.String(),
+		}
+		if fk.refTable != nil {
+			fks.ReferencedTable = fk.refTable.qualifiedName()
+		}
+		ts.ForeignKeys = append(ts.ForeignKeys, fks)
+	}
+	for _, i := range t.indexes {
+		ts.Indexes = append(ts.Indexes, indexSnapshot{
+			Name:    i.name,
+			Unique:  i.unique,
+			Columns: columnNames(i.columns),
+		})
+	}
+	return ts
+}
+
+func (c *Column) snapshot() columnSnapshot {
+	cs := columnSnapshot{
+		Name:          c.name,
+		RawType:       c.def.rawType,
+		Length:        c.def.length,
+		Precision:     c.def.precision,
+		Scale:         c.def.scale,
+		Nullable:      c.def.nullable,
+		Default:       c.def.defaultExpr,
+		AutoIncrement: c.def.autoIncrement,
+	}
+	if c.def.rawType != "" {
+		cs.Type = sqlTypeSnapshotNames[c.def.sqlType]
+	}
+	return cs
+}
+
+// Returns a columnDef from the column's snapshot. If the snapshot has no
+// type, the type is determined from the raw type. The column's primary key
+// ordinal is set when the table's constraints are loaded.
+func (cs columnSnapshot) def() (columnDef, error) {
+	def := columnDef{
+		rawType:       cs.RawType,
+		length:        cs.Length,
+		precision:     cs.Precision,
+		scale:         cs.Scale,
+		nullable:      cs.Nullable,
+		defaultExpr:   cs.Default,
+		autoIncrement: cs.AutoIncrement,
+	}
+	if cs.Type != "" {
+		st, found := sqlTypeFromSnapshotName(cs.Type)
+		if !found {
+			return def, fmt.Errorf(
+				"%w Unknown type for column %s: %s",
+				ERR_SNAPSHOT_INVALID, cs.Name, cs.Type,
+			)
+		}
+		def.sqlType = st
+	} else if cs.RawType != "" {
+		def.sqlType = sqlTypeFromRaw(cs.RawType)
+	}
+	return def, nil
+}
+
+// Creates a table and its columns from the supplied snapshot
+func (m *Meta) loadTableSnapshot(ts tableSnapshot) (*Table, error) {
+	if ts.Name == "" {
+		return nil, fmt.Errorf("%w Table has no name.", ERR_SNAPSHOT_INVALID)
+	}
+	key := m.tableKey(ts.Schema, ts.Name)
+	if _, exists := m.tables[key]; exists {
+		return nil, fmt.Errorf("%w Duplicate table: %s", ERR_SNAPSHOT_INVALID, key)
+	}
+	t := &Table{}
+	if err := m.setTableSnapshot(t, ts); err != nil {
+		return nil, err
+	}
+	m.tables[key] = t
+	return t, nil
+}
+
+// Replaces the supplied table's name, kind and columns with those in the
+// supplied snapshot and removes its keys and indexes. The table is left
+// unchanged if the snapshot is invalid.
+func (m *Meta) setTableSnapshot(t *Table, ts tableSnapshot) error {
+	if ts.Name == "" {
+		return fmt.Errorf("%w Table has no name.", ERR_SNAPSHOT_INVALID)
+	}
+	kind := TABLE_KIND_TABLE
+	if ts.Kind != "" {
+		var found bool
+		if kind, found = tableKindFromSnapshotName(ts.Kind); !found {
+			return fmt.Errorf(
+				"%w Unknown kind for table %s: %s",
+				ERR_SNAPSHOT_INVALID, m.tableKey(ts.Schema, ts.Name), ts.Kind,
+			)
+		}
+	}
+	cols := make([]*Column, len(ts.Columns))
+	for x, cs := range ts.Columns {
+		def, err := cs.def()
+		if err != nil {
+			return err
+		}
+		cols[x] = &Column{name: cs.Name, tbl: t, def: def}
+	}
+	*t = Table{
+		meta:      m,
+		schema:    ts.Schema,
+		name:      ts.Name,
+		kind:      kind,
+		updatable: ts.Updatable,
+		columns:   cols,
+	}
+	return nil
+}
+
+// Creates the constraints, foreign keys and indexes of a table from the
+// supplied snapshot
+func (m *Meta) loadKeySnapshots(t *Table, ts tableSnapshot) error {
+	for _, cs := range ts.Constraints {
+		cols, err := snapshotColumns(t, cs.Columns)
+		if err != nil {
+			return err
+		}
+		switch cs.Type {
+		case constraintTypeSnapshotNames[CONSTRAINT_TYPE_PRIMARY_KEY]:
+			t.NewPrimaryKey(cs.Name, cols...)
+		case constraintTypeSnapshotNames[CONSTRAINT_TYPE_UNIQUE]:
+			t.NewUniqueConstraint(cs.Name, cols...)
+		default:
+			return fmt.Errorf(
+				"%w Unknown type for constraint %s: %s",
+				ERR_SNAPSHOT_INVALID, cs.Name, cs.Type,
+			)
+		}
+	}
+	for _, fks := range ts.ForeignKeys {
+		cols, err := snapshotColumns(t, fks.Columns)
+		if err != nil {
+			return err
+		}
+		refTable := m.Table(fks.ReferencedTable)
+		if refTable == nil {
+			return fmt.Errorf(
+				"%w Foreign key %s references unknown table: %s",
+				ERR_SNAPSHOT_INVALID, fks.Name, fks.ReferencedTable,
+			)
+		}
+		refCols, err := snapshotColumns(refTable, fks.ReferencedColumns)
+		if err != nil {
+			return err
+		}
+		fk := t.NewForeignKey(fks.Name, cols, refCols)
+		fk.refTable = refTable
+		fk.onDelete = referentialActionFromRule(fks.OnDelete)
+		fk.onUpdate = referentialActionFromRule(fks.OnUpdate)
+	}
+	for _, is := range ts.Indexes {
+		cols, err := snapshotColumns(t, is.Columns)
+		if err != nil {
+			return err
+		}
+		t.NewIndex(is.Name, is.Unique, cols...)
+	}
+	return nil
+}
+
+// Returns the columns of the supplied table with the supplied names
+func snapshotColumns(t *Table, names []string) ([]*Column, error) {
+	cols := make([]*Column, len(names))
+	for x, name := range names {
+		c := t.C(name)
+		if c == nil {
+			return nil, fmt.Errorf(
+				"%w Unknown column: %s.%s", ERR_SNAPSHOT_INVALID, t.name, name,
+			)
+		}
+		cols[x] = c
+	}
+	return cols, nil
+}
+
+// Returns the names of the supplied columns
+func columnNames(cols []*Column) []string {
+	res := make([]string, len(cols))
+	for x, c := range cols {
+		res[x] = c.name
+	}
+	return res
+}
+
+func dialectFromSnapshotName(name string) (Dialect, bool) {
+	for d, n := range dialectSnapshotNames {
+		if n == name {
+			return d, true
+		}
+	}
+	return DIALECT_UNKNOWN, false
+}
+
+func sqlTypeFromSnapshotName(name string) (SqlType, bool) {
+	for st, n := range sqlTypeSnapshotNames {
+		if n == name {
+			return st, true
+		}
+	}
+	return SQL_TYPE_UNKNOWN, false
+}
+
+func tableKindFromSnapshotName(name string) (TableKind, bool) {
+	for k, n := range tableKindSnapshotNames {
+		if n == name {
+			return k, true
+		}
+	}
+	return TABLE_KIND_TABLE, false
+}
@@ -0,0 +1,235 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetaSnapshotRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, dialect := range []Dialect{DIALECT_MYSQL, DIALECT_POSTGRESQL} {
+		ddl := _MYSQL_DDL
+		if dialect == DIALECT_POSTGRESQL {
+			ddl = _POSTGRESQL_DDL
+		}
+		m := NewMeta(dialect, "test")
+		m.SetSchemas("public", "billing")
+		assert.Nil(LoadDDL(dialect, strings.NewReader(ddl), m))
+		m.NewView("user_names").NewColumn("name")
+
+		b, err := json.Marshal(m)
+		assert.Nil(err)
+
+		loaded, err := LoadMeta(bytes.NewReader(b))
+		assert.Nil(err)
+		assert.EqualValues(dialect, loaded.dialect)
+		assert.Equal(m.DefaultSchema(), loaded.DefaultSchema())
+		assert.Equal(m.Schemas(), loaded.Schemas())
+		assert.Equal(len(m.Tables()), len(loaded.Tables()))
+
+		// A second round trip produces an identical document
+		b2, err := json.Marshal(loaded)
+		assert.Nil(err)
+		assert.Equal(string(b), string(b2))
+
+		for _, orig := range m.Tables() {
+			name := orig.qualifiedName()
+			lt := loaded.Table(name)
+			assert.NotNil(lt, name)
+			assert.Equal(loaded, lt.meta)
+			assert.Equal(orig.Kind(), lt.Kind())
+			for x, c := range orig.Columns() {
+				lc := lt.Columns()[x]
+				assert.Equal(lt, lc.Table())
+				assert.Equal(c.def, lc.def, name+"."+c.name)
+			}
+			assert.Equal(len(orig.Constraints()), len(lt.Constraints()))
+			assert.Equal(len(orig.Indexes()), len(lt.Indexes()))
+			for x, fk := range orig.ForeignKeys() {
+				lfk := lt.ForeignKeys()[x]
+				assert.Equal(fk.Name(), lfk.Name())
+				assert.Equal(fk.OnDelete(), lfk.OnDelete())
+				assert.Equal(fk.OnUpdate(), lfk.OnUpdate())
+				// Referenced tables and columns are those of the loaded Meta
+				assert.Equal(loaded, lfk.ReferencedTable().meta)
+				assert.Equal(fk.ReferencedTable().Name(), lfk.ReferencedTable().Name())
+				for y, rc := range lfk.ReferencedColumns() {
+					assert.Equal(lfk.ReferencedTable(), rc.Table())
+					assert.Equal(fk.ReferencedColumns()[y].Name(), rc.Name())
+				}
+			}
+		}
+	}
+}
+
+func TestMetaSnapshotDocument(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	users := m.NewTable("users")
+	id := users.NewColumn("id")
+	id.def = columnDef{sqlType: SQL_TYPE_INT, rawType: "integer"}
+	users.NewPrimaryKey("users_pkey", id)
+
+	b, err := json.Marshal(m)
+	assert.Nil(err)
+	exp := `{"version":1,"dialect":"postgresql","database":"test",` +
+		`"default_schema":"public","tables":[{"name":"users","kind":"table",` +
+		`"columns":[{"name":"id","type":"int","raw_type":"integer","nullable":false}],` +
+		`"constraints":[{"name":"users_pkey","type":"primary_key","columns":["id"]}]}]}`
+	assert.Equal(exp, string(b))
+
+	loaded, err := LoadMeta(strings.NewReader(exp))
+	assert.Nil(err)
+	assert.True(loaded.Table("users").C("id").IsPrimaryKey())
+	assert.Equal(SQL_TYPE_INT, loaded.Table("users").C("id").Type())
+}
+
+func TestTableSnapshotRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	m.SetSchemas("public", "billing")
+	assert.Nil(LoadDDL(DIALECT_POSTGRESQL, strings.NewReader(_POSTGRESQL_DDL), m))
+	users, err := json.Marshal(m.Table("users"))
+	assert.Nil(err)
+	invoices, err := json.Marshal(m.Table("billing.invoices"))
+	assert.Nil(err)
+
+	// A table without a Meta gets one of its own
+	lu := &Table{}
+	assert.Nil(json.Unmarshal(users, lu))
+	assert.Equal("users", lu.Name())
+	assert.Equal(lu, lu.meta.Table("users"))
+	assert.True(lu.C("id").IsPrimaryKey())
+	assert.Equal(lu, lu.C("id").Table())
+	b, err := json.Marshal(lu)
+	assert.Nil(err)
+	assert.Equal(string(users), string(b))
+
+	// Foreign keys can't be resolved without the referenced table, and the
+	// table is left unchanged
+	li := &Table{}
+	err = json.Unmarshal(invoices, li)
+	assert.True(errors.Is(err, ERR_SNAPSHOT_INVALID))
+	assert.Equal("", li.Name())
+
+	// Foreign keys are resolved against the tables of the table's Meta, and
+	// the table is registered under its new name
+	lm := NewMeta(DIALECT_POSTGRESQL, "test")
+	lm.SetSchemas("public", "billing")
+	assert.Nil(json.Unmarshal(users, lm.NewTable("placeholder")))
+	assert.Nil(lm.Table("placeholder"))
+	li = lm.NewTable("invoices")
+	assert.Nil(json.Unmarshal(invoices, li))
+	assert.Equal(li, lm.Table("billing.invoices"))
+	assert.Nil(lm.Table("invoices"))
+	fk := li.ForeignKeys()[0]
+	assert.Equal(lm.Table("users"), fk.ReferencedTable())
+	assert.Equal(lm.Table("users").C("id"), fk.ReferencedColumns()[0])
+	b, err = json.Marshal(li)
+	assert.Nil(err)
+	assert.Equal(string(invoices), string(b))
+
+	// A table can't take the name of another table in its Meta
+	other := lm.NewTable("other")
+	err = json.Unmarshal(users, other)
+	assert.True(errors.Is(err, ERR_SNAPSHOT_INVALID))
+	assert.Equal(other, lm.Table("other"))
+}
+
+func TestColumnSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	d := "0"
+	c := &Column{name: "count", def: columnDef{
+		sqlType:     SQL_TYPE_BIGINT,
+		rawType:     "bigint",
+		defaultExpr: &d,
+	}}
+	b, err := json.Marshal(c)
+	assert.Nil(err)
+	assert.Equal(
+		`{"name":"count","type":"bigint","raw_type":"bigint","nullable":false,"default":"0"}`,
+		string(b),
+	)
+
+	lc := &Column{}
+	assert.Nil(json.Unmarshal(b, lc))
+	assert.Equal("count", lc.Name())
+	assert.Equal(c.def, lc.def)
+
+	// Columns without a known type round-trip as SQL_TYPE_UNKNOWN
+	b, err = json.Marshal(&Column{name: "manual"})
+	assert.Nil(err)
+	assert.Equal(`{"name":"manual","nullable":false}`, string(b))
+	assert.Nil(json.Unmarshal(b, lc))
+	assert.Equal(SQL_TYPE_UNKNOWN, lc.Type())
+}
+
+func TestLoadMetaErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		doc  string
+		exp  error
+	}{
+		{
+			name: "Missing version",
+			doc:  `{"tables":[]}`,
+			exp:  ERR_SNAPSHOT_VERSION,
+		},
+		{
+			name: "Future version",
+			doc:  `{"version":99,"tables":[]}`,
+			exp:  ERR_SNAPSHOT_VERSION,
+		},
+		{
+			name: "Unknown dialect",
+			doc:  `{"version":1,"dialect":"oracle","tables":[]}`,
+			exp:  ERR_SNAPSHOT_INVALID,
+		},
+		{
+			name: "Unknown column type",
+			doc:  `{"version":1,"tables":[{"name":"t","columns":[{"name":"c","type":"blob"}]}]}`,
+			exp:  ERR_SNAPSHOT_INVALID,
+		},
+		{
+			name: "Duplicate table",
+			doc:  `{"version":1,"tables":[{"name":"t","columns":[]},{"name":"t","columns":[]}]}`,
+			exp:  ERR_SNAPSHOT_INVALID,
+		},
+		{
+			name: "Constraint on unknown column",
+			doc: `{"version":1,"tables":[{"name":"t","columns":[],` +
+				`"constraints":[{"name":"pk","type":"primary_key","columns":["id"]}]}]}`,
+			exp: ERR_SNAPSHOT_INVALID,
+		},
+		{
+			name: "Foreign key to unknown table",
+			doc: `{"version":1,"tables":[{"name":"t","columns":[{"name":"c"}],` +
+				`"foreign_keys":[{"name":"fk","columns":["c"],` +
+				`"referenced_table":"u","referenced_columns":["id"]}]}]}`,
+			exp: ERR_SNAPSHOT_INVALID,
+		},
+	}
+	for _, test := range tests {
+		_, err := LoadMeta(strings.NewReader(test.doc))
+		assert.True(errors.Is(err, test.exp), "%s: %v", test.name, err)
+	}
+
+	_, err := LoadMeta(strings.NewReader("not json"))
+	assert.NotNil(err)
+}
@@ -0,0 +1,342 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// The number of statements a StmtCache holds if NewStmtCache() is given a
+// size less than 1
+const _STMT_CACHE_DEFAULT_SIZE = 256
+
+// StmtCacheStats holds counters for a StmtCache's activity
+type StmtCacheStats struct {
+	// The number of queries that ran on an already prepared statement
+	Hits uint64
+	// The number of queries whose statement had to be prepared
+	Misses uint64
+	// The number of statements closed to make room for others
+	Evictions uint64
+	// The number of statements currently prepared
+	Len int
+}
+
+// A StmtCache prepares each distinct SQL string it is asked to run once and
+// runs later queries with the same SQL string on the prepared *sql.Stmt. The
+// least recently used statements are closed when the cache is full.
+//
+// The statements are prepared on a *sql.DB, which prepares them again on
+// another connection if their connection is closed and defers closing an
+// evicted statement until the rows of its queries are closed.
+//
+// A StmtCache is an Executor, so queries may be run with it directly:
+//
+//	cache := sqlb.NewStmtCache(db, 100)
+//	defer cache.Close()
+//	rows, err := q.Query(ctx, cache)
+//
+// A StmtCache is safe for concurrent use by multiple goroutines.
+type StmtCache struct {
+	db   *sql.DB
+	size int
+	mu   sync.Mutex
+	// The cached statements, most recently used first
+	lru     *list.List
+	entries map[string]*list.Element
+	closed  bool
+	stats   StmtCacheStats
+}
+
+type stmtCacheEntry struct {
+	qs   string
+	stmt *sql.Stmt
+	// The number of queries running on the statement. An evicted statement
+	// is closed once no queries are running on it.
+	refs    int
+	evicted bool
+}
+
+// Returns a StmtCache that prepares statements using the supplied *sql.DB and
+// holds at most size prepared statements
+func NewStmtCache(db *sql.DB, size int) *StmtCache {
+	if size < 1 {
+		size = _STMT_CACHE_DEFAULT_SIZE
+	}
+	return &StmtCache{
+		db:      db,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Returns a snapshot of the cache's counters
+func (c *StmtCache) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
+
+// Closes all of the cache's prepared statements. Statements that queries are
+// running on are closed when the queries finish. Queries run with the cache
+// after Close() are run without preparing a statement.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Back())
+	}
+	return nil
+}
+
+// Returns the cached statement for the supplied SQL string, or nil if it is
+// not cached, and counts a hit or a miss. Returns true as its second value if
+// the cache is closed. The caller must call release() with a returned entry
+// once its query has run.
+func (c *StmtCache) lookup(qs string) (*stmtCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, true
+	}
+	if el, found := c.entries[qs]; found {
+		c.stats.Hits++
+		return c.useLocked(el), false
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+// Returns the cached statement for the supplied SQL string, preparing it if
+// necessary, or nil if the cache is closed. The caller must call release()
+// with the returned entry once its query has run.
+func (c *StmtCache) acquire(ctx context.Context, qs string) (*stmtCacheEntry, error) {
+	e, closed := c.lookup(qs)
+	if e != nil || closed {
+		return e, nil
+	}
+
+	// We prepare the statement without holding the lock so that a slow PREPARE
+	// doesn't hold up queries on other statements
+	stmt, err := c.db.PrepareContext(ctx, qs)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		stmt.Close()
+		return nil, nil
+	}
+	if el, found := c.entries[qs]; found {
+		// Another goroutine prepared the same statement in the meantime
+		stmt.Close()
+		return c.useLocked(el), nil
+	}
+	e = &stmtCacheEntry{qs: qs, stmt: stmt, refs: 1}
+	c.entries[qs] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.removeLocked(c.lru.Back())
+		c.stats.Evictions++
+	}
+	return e, nil
+}
+
+func (c *StmtCache) useLocked(el *list.Element) *stmtCacheEntry {
+	c.lru.MoveToFront(el)
+	e := el.Value.(*stmtCacheEntry)
+	e.refs++
+	return e
+}
+
+// Removes the entry from the cache, closing its statement unless queries are
+// still running on it
+func (c *StmtCache) removeLocked(el *list.Element) {
+	e := el.Value.(*stmtCacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.qs)
+	e.evicted = true
+	if e.refs == 0 {
+		e.stmt.Close()
+	}
+}
+
+// Marks a query on the entry's statement as finished
+func (c *StmtCache) release(e *stmtCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.refs--
+	if e.evicted && e.refs == 0 {
+		e.stmt.Close()
+	}
+}
+
+// Runs the query on the cached statement for the supplied SQL string.
+// Implements the Executor interface.
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return c.db.ExecContext(ctx, query, args...)
+	}
+	res, err := e.stmt.ExecContext(ctx, args...)
+	c.release(e)
+	return res, err
+}
+
+// Runs the query on the cached statement for the supplied SQL string.
+// Implements the Executor interface.
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	e, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	// The entry may be released before the caller reads the rows because
+	// database/sql defers closing the statement until its rows are closed
+	rows, err := e.stmt.QueryContext(ctx, args...)
+	c.release(e)
+	return rows, err
+}
+
+// Runs the query on the cached statement for the supplied SQL string. If the
+// statement can't be prepared, the returned row's Err() and Scan() return the
+// error. Implements the Executor interface.
+func (c *StmtCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	e, err := c.acquire(ctx, query)
+	if err != nil {
+		return errRow(ctx, err)
+	}
+	if e == nil {
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	row := e.stmt.QueryRowContext(ctx, args...)
+	c.release(e)
+	return row
+}
+
+// A database/sql driver and connector whose connections fail with an error
+type errConnector struct {
+	err error
+}
+
+func (c errConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c errConnector) Open(string) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return c
+}
+
+// Returns a *sql.Row whose Err() and Scan() return the supplied error. A
+// *sql.Row can only be created by running a query, so the query is run on a
+// database whose connections fail with the error.
+func errRow(ctx context.Context, err error) *sql.Row {
+	db := sql.OpenDB(errConnector{err: err})
+	defer db.Close()
+	return db.QueryRowContext(ctx, "")
+}
+
+// Returns an Executor that runs queries in the supplied transaction on the
+// cache's prepared statements, which are bound to the transaction with
+// sql.Tx.StmtContext(). Statements that are not cached are prepared in the
+// transaction rather than added to the cache, so that the transaction's
+// queries don't need a second connection. The transaction's statements are
+// prepared or bound once per SQL string and are closed by database/sql when
+// the transaction is committed or rolled back.
+func (c *StmtCache) Tx(tx *sql.Tx) Executor {
+	return &stmtCacheTx{
+		c:     c,
+		tx:    tx,
+		stmts: make(map[string]*sql.Stmt),
+	}
+}
+
+type stmtCacheTx struct {
+	c     *StmtCache
+	tx    *sql.Tx
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// Returns the transaction's statement for the supplied SQL string, or nil if
+// the cache is closed
+func (t *stmtCacheTx) stmt(ctx context.Context, qs string) (*sql.Stmt, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if stmt, found := t.stmts[qs]; found {
+		return stmt, nil
+	}
+	e, closed := t.c.lookup(qs)
+	if closed {
+		return nil, nil
+	}
+	var stmt *sql.Stmt
+	if e != nil {
+		// The transaction's statement depends on the cached statement, so
+		// database/sql defers closing the cached statement until the
+		// transaction ends even if it is evicted before then
+		stmt = t.tx.StmtContext(ctx, e.stmt)
+		t.c.release(e)
+	} else {
+		var err error
+		if stmt, err = t.tx.PrepareContext(ctx, qs); err != nil {
+			return nil, err
+		}
+	}
+	t.stmts[qs] = stmt
+	return stmt, nil
+}
+
+func (t *stmtCacheTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := t.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return t.tx.ExecContext(ctx, query, args...)
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (t *stmtCacheTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := t.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return t.tx.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (t *stmtCacheTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, err := t.stmt(ctx, query)
+	if err != nil {
+		return errRow(ctx, err)
+	}
+	if stmt == nil {
+		return t.tx.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
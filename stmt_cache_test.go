@@ -0,0 +1,231 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmtCache(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	// Keep a single connection so that each statement is prepared once
+	db.SetMaxOpenConns(1)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	byID := Select(users.C("id")).Where(Equal(users.C("id"), 1))
+	byName := Select(users.C("id")).Where(Equal(users.C("name"), "fred"))
+	insert := users.Insert(map[string]interface{}{"name": "fred"})
+
+	cache := NewStmtCache(db, 2)
+	for x := 0; x < 3; x++ {
+		_, err := insert.Exec(ctx, cache)
+		assert.Nil(err)
+		var id int64
+		assert.Nil(byID.QueryRow(ctx, cache).Scan(&id))
+	}
+	assert.Equal(2, rec.prepares)
+	assert.Equal(StmtCacheStats{Hits: 4, Misses: 2, Len: 2}, cache.Stats())
+
+	// Preparing a third statement evicts the least recently used one
+	rows, err := byName.Query(ctx, cache)
+	assert.Nil(err)
+	assert.Nil(rows.Close())
+	assert.Equal(StmtCacheStats{Hits: 4, Misses: 3, Evictions: 1, Len: 2}, cache.Stats())
+	assert.Equal(1, rec.closes)
+	_, err = insert.Exec(ctx, cache)
+	assert.Nil(err)
+	assert.Equal(StmtCacheStats{Hits: 4, Misses: 4, Evictions: 2, Len: 2}, cache.Stats())
+	assert.Equal(4, rec.prepares)
+
+	// Queries with errors never reach the cache
+	_, err = users.Insert(nil).Exec(ctx, cache)
+	assert.Equal(ERR_INSERT_NO_VALUES, err)
+	assert.Equal(uint64(4), cache.Stats().Misses)
+
+	// After Close(), queries run without preparing a statement in the cache
+	assert.Nil(cache.Close())
+	assert.Equal(0, cache.Stats().Len)
+	assert.Equal(4, rec.closes)
+	_, err = insert.Exec(ctx, cache)
+	assert.Nil(err)
+	assert.Equal(0, cache.Stats().Len)
+}
+
+func TestStmtCacheEvictInUse(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	cache := NewStmtCache(db, 1)
+	e, err := cache.acquire(ctx, "SELECT 1")
+	assert.Nil(err)
+	_, err = cache.ExecContext(ctx, "SELECT 2")
+	assert.Nil(err)
+
+	// The statement in use was evicted but is only closed once released
+	assert.Equal(0, rec.closes)
+	assert.True(e.evicted)
+	_, err = e.stmt.ExecContext(ctx)
+	assert.Nil(err)
+	cache.release(e)
+	assert.Equal(1, rec.closes)
+}
+
+func TestStmtCacheConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, _ := testRecordingDB(t)
+	defer db.Close()
+
+	queries := []string{"SELECT 1", "SELECT 2", "SELECT 3"}
+	cache := NewStmtCache(db, 2)
+	var wg sync.WaitGroup
+	for x := 0; x < 8; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			for y := 0; y < 50; y++ {
+				_, err := cache.ExecContext(ctx, queries[(x+y)%len(queries)])
+				assert.Nil(err)
+			}
+		}(x)
+	}
+	wg.Wait()
+	stats := cache.Stats()
+	assert.Equal(uint64(400), stats.Hits+stats.Misses)
+	assert.Equal(2, stats.Len)
+	assert.Nil(cache.Close())
+}
+
+func TestStmtCacheEvictWithOpenRows(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	rec.rows = [][]driver.Value{{int64(1)}, {int64(2)}}
+
+	cache := NewStmtCache(db, 1)
+	rows, err := cache.QueryContext(ctx, "SELECT n FROM t")
+	assert.Nil(err)
+	assert.True(rows.Next())
+	_, err = cache.ExecContext(ctx, "DELETE FROM t")
+	assert.Nil(err)
+	assert.Equal(uint64(1), cache.Stats().Evictions)
+
+	// The evicted statement is only closed once its rows are closed
+	assert.Equal(0, rec.closes)
+	var n int64
+	assert.Nil(rows.Scan(&n))
+	assert.True(rows.Next())
+	assert.Nil(rows.Scan(&n))
+	assert.Equal(int64(2), n)
+	assert.Nil(rows.Close())
+	assert.Equal(1, rec.closes)
+}
+
+func TestStmtCacheConnClosed(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	// Close each connection once its query has run
+	db.SetMaxIdleConns(0)
+
+	// A statement whose connection is closed is prepared again on another
+	// connection
+	cache := NewStmtCache(db, 10)
+	_, err := cache.ExecContext(ctx, "SELECT 1")
+	assert.Nil(err)
+	prepares := rec.prepares
+	_, err = cache.ExecContext(ctx, "SELECT 1")
+	assert.Nil(err)
+	assert.Equal(prepares+1, rec.prepares)
+	assert.Equal(StmtCacheStats{Hits: 1, Misses: 1, Len: 1}, cache.Stats())
+}
+
+func TestStmtCachePrepareError(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	errPrepare := errors.New("prepare failed")
+	rec.prepareErr = errPrepare
+
+	// The error from preparing the statement is returned without running
+	// the query unprepared
+	cache := NewStmtCache(db, 10)
+	row := cache.QueryRowContext(ctx, "SELECT 1")
+	assert.Equal(errPrepare, row.Err())
+	var n int64
+	assert.Equal(errPrepare, row.Scan(&n))
+	assert.Equal(1, rec.prepares)
+	assert.Equal(0, len(rec.stmts))
+	assert.Equal(StmtCacheStats{Misses: 1}, cache.Stats())
+
+	_, err := cache.QueryContext(ctx, "SELECT 1")
+	assert.Equal(errPrepare, err)
+}
+
+func TestStmtCacheTx(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	insert := users.Insert(map[string]interface{}{"name": "fred"})
+
+	cache := NewStmtCache(db, 10)
+	_, err := insert.Exec(ctx, cache)
+	assert.Nil(err)
+
+	err = WithTx(ctx, db, nil, func(tx *Tx) error {
+		ex := cache.Tx(tx.Tx)
+		for x := 0; x < 3; x++ {
+			if _, err := insert.Exec(ctx, ex); err != nil {
+				return err
+			}
+		}
+		var n int64
+		return Select(users.C("id")).QueryRow(ctx, ex).Scan(&n)
+	})
+	assert.Nil(err)
+	// The statement prepared outside the transaction is reused on the same
+	// connection. The statement prepared in the transaction is not cached.
+	assert.Equal(2, rec.prepares)
+	assert.Equal(StmtCacheStats{Hits: 1, Misses: 2, Len: 1}, cache.Stats())
+	assert.Equal([]string{
+		"INSERT INTO users (name) VALUES (?)",
+		"BEGIN",
+		"INSERT INTO users (name) VALUES (?)",
+		"INSERT INTO users (name) VALUES (?)",
+		"INSERT INTO users (name) VALUES (?)",
+		"SELECT users.id FROM users",
+		"COMMIT",
+	}, rec.stmts)
+	assert.Nil(cache.Close())
+}
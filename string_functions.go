@@ -60,12 +60,6 @@ func (f *trimFunc) from() selection {
 	return f.sel
 }
 
-func (f *trimFunc) disableAliasScan() func() {
-	origAlias := f.alias
-	f.alias = ""
-	return func() { f.alias = origAlias }
-}
-
 func (f *trimFunc) As(alias string) *trimFunc {
 	aliased := &trimFunc{
 		sel:      f.sel,
@@ -133,7 +127,7 @@ func trimFuncScanMySQL(f *trimFunc, scanner *sqlScanner, b []byte, args []interf
 			bw += copy(b[bw:], Symbols[SYM_TRIM])
 			bw += copy(b[bw:], Symbols[SYM_LEADING])
 			bw += copy(b[bw:], " ")
-			bw += scanInterpolationMarker(DIALECT_MYSQL, b[bw:], *curArg)
+			bw += scanner.scanMarker(b[bw:], *curArg)
 			args[*curArg] = f.chars
 			*curArg++
 			bw += copy(b[bw:], " ")
@@ -147,7 +141,7 @@ func trimFuncScanMySQL(f *trimFunc, scanner *sqlScanner, b []byte, args []interf
 			bw += copy(b[bw:], Symbols[SYM_TRIM])
 			bw += copy(b[bw:], Symbols[SYM_TRAILING])
 			bw += copy(b[bw:], " ")
-			bw += scanInterpolationMarker(DIALECT_MYSQL, b[bw:], *curArg)
+			bw += scanner.scanMarker(b[bw:], *curArg)
 			args[*curArg] = f.chars
 			*curArg++
 			bw += copy(b[bw:], " ")
@@ -159,7 +153,7 @@ func trimFuncScanMySQL(f *trimFunc, scanner *sqlScanner, b []byte, args []interf
 			bw += copy(b[bw:], Symbols[SYM_TRIM])
 		} else {
 			bw += copy(b[bw:], Symbols[SYM_TRIM])
-			bw += scanInterpolationMarker(DIALECT_MYSQL, b[bw:], *curArg)
+			bw += scanner.scanMarker(b[bw:], *curArg)
 			args[*curArg] = f.chars
 			*curArg++
 			bw += copy(b[bw:], " ")
@@ -214,7 +208,7 @@ func trimFuncScanPostgreSQL(f *trimFunc, scanner *sqlScanner, b []byte, args []i
 		bw += copy(b[bw:], Symbols[SYM_LEADING])
 		if f.chars != "" {
 			bw += copy(b[bw:], " ")
-			bw += scanInterpolationMarker(DIALECT_POSTGRESQL, b[bw:], *curArg)
+			bw += scanner.scanMarker(b[bw:], *curArg)
 			args[*curArg] = f.chars
 			*curArg++
 		}
@@ -226,7 +220,7 @@ func trimFuncScanPostgreSQL(f *trimFunc, scanner *sqlScanner, b []byte, args []i
 		bw += copy(b[bw:], Symbols[SYM_TRAILING])
 		if f.chars != "" {
 			bw += copy(b[bw:], " ")
-			bw += scanInterpolationMarker(DIALECT_POSTGRESQL, b[bw:], *curArg)
+			bw += scanner.scanMarker(b[bw:], *curArg)
 			args[*curArg] = f.chars
 			*curArg++
 		}
@@ -238,7 +232,7 @@ func trimFuncScanPostgreSQL(f *trimFunc, scanner *sqlScanner, b []byte, args []i
 		bw += trimFuncScanSubject(f, scanner, b[bw:], args, curArg)
 		if f.chars != "" {
 			bw += copy(b[bw:], Symbols[SYM_COMMA_WS])
-			bw += scanInterpolationMarker(DIALECT_POSTGRESQL, b[bw:], *curArg)
+			bw += scanner.scanMarker(b[bw:], *curArg)
 			args[*curArg] = f.chars
 			*curArg++
 		}
@@ -250,13 +244,8 @@ func trimFuncScanPostgreSQL(f *trimFunc, scanner *sqlScanner, b []byte, args []i
 func trimFuncScanSubject(f *trimFunc, scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	// We need to disable alias output for elements that are
 	// projections. We don't want to output, for example,
-	// "ON users.id AS user_id = TRIM(articles.author)"
-	switch f.subject.(type) {
-	case projection:
-		reset := f.subject.(projection).disableAliasScan()
-		defer reset()
-	}
-	return f.subject.scan(scanner, b, args, curArg)
+	// "TRIM(articles.author AS author)"
+	return f.subject.scan(scanner.noAliases(), b, args, curArg)
 }
 
 func (f *trimFunc) size(scanner *sqlScanner) int {
@@ -270,14 +259,9 @@ func (f *trimFunc) size(scanner *sqlScanner) int {
 	size += len(Symbols[SYM_RPAREN])
 	// We need to disable alias output for elements that are
 	// projections. We don't want to output, for example,
-	// "ON users.id AS user_id = TRIM(articles.author)"
-	switch f.subject.(type) {
-	case projection:
-		reset := f.subject.(projection).disableAliasScan()
-		defer reset()
-	}
-	size += f.subject.size(scanner)
-	if f.alias != "" {
+	// "TRIM(articles.author AS author)"
+	size += f.subject.size(scanner.noAliases())
+	if f.alias != "" && !scanner.disableAliases {
 		size += len(Symbols[SYM_AS]) + len(f.alias)
 	}
 	return size
@@ -292,7 +276,7 @@ func (f *trimFunc) scan(scanner *sqlScanner, b []byte, args []interface{}, curAr
 		bw += trimFuncScanMySQL(f, scanner, b[bw:], args, curArg)
 	}
 	bw += copy(b[bw:], Symbols[SYM_RPAREN])
-	if f.alias != "" {
+	if f.alias != "" && !scanner.disableAliases {
 		bw += copy(b[bw:], Symbols[SYM_AS])
 		bw += copy(b[bw:], f.alias)
 	}
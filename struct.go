@@ -0,0 +1,323 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	ERR_STRUCT_INVALID          = errors.New("Expected a struct or a non-nil pointer to a struct.")
+	ERR_STRUCT_NO_COLUMNS       = errors.New("The struct has no fields mapped to columns.")
+	ERR_STRUCT_DUPLICATE_COLUMN = errors.New("More than one struct field maps to the same column.")
+	ERR_STRUCT_NO_PRIMARY_KEY   = errors.New("No struct field maps to a primary key column.")
+)
+
+// A structField describes an exported field of a struct, possibly promoted
+// from an embedded struct, that maps to a column.
+//
+// Fields map to columns with a `db` struct tag of the form
+// `db:"<column>[,pk][,omitempty]"`:
+//
+//	type User struct {
+//		ID        int64     `db:"id,pk,omitempty"`
+//		Email     string    `db:"email"`
+//		CreatedOn time.Time // maps to the "created_on" column
+//		Password  string    `db:"-"` // not mapped
+//	}
+//
+// A field without a tag, or whose tag has an empty column name, maps to the
+// field name converted to snake_case. The "pk" option marks the column as
+// part of the primary key, in field order. The "omitempty" option leaves the
+// column out of INSERT and UPDATE statements when the field holds its type's
+// zero value, which lets the database assign defaults and AUTO_INCREMENT
+// values. The fields of embedded structs are flattened into the embedding
+// struct unless the embedded field is tagged with a column name.
+type structField struct {
+	column    string
+	index     []int
+	pk        bool
+	omitEmpty bool
+}
+
+// Cache of the structFields for each struct type, keyed by reflect.Type
+var structFieldsCache sync.Map
+
+// Returns the structFields for the supplied struct type, in field order
+func structFields(st reflect.Type) ([]structField, error) {
+	if cached, ok := structFieldsCache.Load(st); ok {
+		return cached.([]structField), nil
+	}
+	fields := make([]structField, 0, st.NumField())
+	if err := appendStructFields(&fields, st, nil); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ERR_STRUCT_NO_COLUMNS
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if seen[f.column] {
+			return nil, fmt.Errorf("%w Column: %s", ERR_STRUCT_DUPLICATE_COLUMN, f.column)
+		}
+		seen[f.column] = true
+	}
+	structFieldsCache.Store(st, fields)
+	return fields, nil
+}
+
+func appendStructFields(fields *[]structField, st reflect.Type, index []int) error {
+	for x := 0; x < st.NumField(); x++ {
+		sf := st.Field(x)
+		tag, hasTag := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		name := strings.TrimSpace(opts[0])
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = x
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := appendStructFields(fields, ft, fieldIndex); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+		f := structField{column: name, index: fieldIndex}
+		if hasTag {
+			for _, opt := range opts[1:] {
+				switch strings.TrimSpace(opt) {
+				case "pk":
+					f.pk = true
+				case "omitempty":
+					f.omitEmpty = true
+				}
+			}
+		}
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+// Returns the value of the field in the supplied struct value, and whether
+// the field holds its type's zero value. A field promoted through a nil
+// embedded pointer has a nil value.
+func (f structField) value(sv reflect.Value) (interface{}, bool) {
+	v := sv
+	for x, i := range f.index {
+		if x > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, true
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v.Interface(), v.IsZero()
+}
+
+// Returns the struct value held by, or pointed to by, the supplied variable
+func structValue(v interface{}) (reflect.Value, error) {
+	sv := reflect.ValueOf(v)
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			return reflect.Value{}, ERR_STRUCT_INVALID
+		}
+		sv = sv.Elem()
+	}
+	if sv.Kind() != reflect.Struct {
+		return reflect.Value{}, ERR_STRUCT_INVALID
+	}
+	return sv, nil
+}
+
+// Converts a Go identifier such as "CreatedOn" or "UserID" to snake_case, as
+// in "created_on" or "user_id"
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for x, r := range runes {
+		if unicode.IsUpper(r) && x > 0 {
+			prev := runes[x-1]
+			nextLower := x+1 < len(runes) && unicode.IsLower(runes[x+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Creates a Table with the given name, or returns the existing Table with
+// that name, and adds a column for each field of the supplied struct, or
+// pointer to a struct, that maps to a column. Columns the table already has
+// are left as they are. If the table has no primary key, the columns of the
+// fields tagged "pk" become its primary key. See structField for how fields
+// map to columns.
+func (m *Meta) RegisterStruct(name string, v interface{}) (*Table, error) {
+	sv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := structFields(sv.Type())
+	if err != nil {
+		return nil, err
+	}
+	t := m.NewTable(name)
+	pk := make([]*Column, 0)
+	for _, f := range fields {
+		c := t.NewColumn(f.column)
+		if f.pk {
+			pk = append(pk, c)
+		}
+	}
+	if len(pk) > 0 && len(t.PrimaryKey()) == 0 {
+		pkName := t.name + "_pkey"
+		if m.dialect == DIALECT_MYSQL {
+			pkName = "PRIMARY"
+		}
+		t.NewPrimaryKey(pkName, pk...)
+	}
+	return t, nil
+}
+
+// The columns and values read from a struct value by Table.structValues()
+type structValues struct {
+	// The columns and values to write to the table. Fields tagged
+	// "omitempty" that hold a zero value are left out.
+	columns []*Column
+	values  []interface{}
+	// The columns and values of the fields that map to the table's primary
+	// key, whether or not they are written
+	pkColumns []*Column
+	pkValues  []interface{}
+}
+
+// Reads the columns and values of the supplied struct, or pointer to a
+// struct. Returns the supplied unknown error, wrapped, if a field maps to a
+// column the table does not have.
+func (t *Table) structValues(v interface{}, unknown error) (*structValues, error) {
+	sv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := structFields(sv.Type())
+	if err != nil {
+		return nil, err
+	}
+	res := &structValues{
+		columns: make([]*Column, 0, len(fields)),
+		values:  make([]interface{}, 0, len(fields)),
+	}
+	for _, f := range fields {
+		c := t.C(f.column)
+		if c == nil {
+			return nil, fmt.Errorf("%w Column: %s", unknown, f.column)
+		}
+		val, zero := f.value(sv)
+		if f.pk || c.IsPrimaryKey() {
+			res.pkColumns = append(res.pkColumns, c)
+			res.pkValues = append(res.pkValues, val)
+		}
+		if f.omitEmpty && zero {
+			continue
+		}
+		res.columns = append(res.columns, c)
+		res.values = append(res.values, val)
+	}
+	return res, nil
+}
+
+// Returns an InsertQuery that will produce an INSERT SQL statement for the
+// fields of the supplied struct, or pointer to a struct. Columns are listed
+// in field order. See structField for how fields map to columns.
+func (t *Table) InsertStruct(v interface{}) *InsertQuery {
+	if t.IsReadOnly() {
+		return &InsertQuery{e: ERR_INSERT_READ_ONLY}
+	}
+	sv, err := t.structValues(v, ERR_INSERT_UNKNOWN_COLUMN)
+	if err != nil {
+		return &InsertQuery{e: err}
+	}
+	if len(sv.columns) == 0 {
+		return &InsertQuery{e: ERR_INSERT_NO_VALUES}
+	}
+	scanner := newSqlScanner(t.meta.dialect, defaultFormatOptions)
+	stmt := &insertStatement{
+		table:   t,
+		columns: sv.columns,
+		values:  sv.values,
+	}
+	return &InsertQuery{
+		stmt:    stmt,
+		scanner: scanner,
+	}
+}
+
+// Returns an UpdateQuery that will produce an UPDATE SQL statement setting
+// the columns of the supplied struct's fields, or pointer to a struct's
+// fields, for the row identified by its primary key fields. A field maps to
+// the primary key if it is tagged "pk" or if its column is part of the
+// table's primary key. Primary key columns are not SET. See structField for
+// how fields map to columns.
+func (t *Table) UpdateStruct(v interface{}) *UpdateQuery {
+	if t.IsReadOnly() {
+		return &UpdateQuery{e: ERR_UPDATE_READ_ONLY}
+	}
+	sv, err := t.structValues(v, ERR_UPDATE_UNKNOWN_COLUMN)
+	if err != nil {
+		return &UpdateQuery{e: err}
+	}
+	if len(sv.pkColumns) == 0 {
+		return &UpdateQuery{e: ERR_STRUCT_NO_PRIMARY_KEY}
+	}
+	cols := make([]*Column, 0, len(sv.columns))
+	vals := make([]interface{}, 0, len(sv.values))
+	for x, c := range sv.columns {
+		if containsColumn(sv.pkColumns, c) {
+			continue
+		}
+		cols = append(cols, c)
+		vals = append(vals, sv.values[x])
+	}
+	if len(cols) == 0 {
+		return &UpdateQuery{e: ERR_UPDATE_NO_VALUES}
+	}
+	scanner := newSqlScanner(t.meta.dialect, defaultFormatOptions)
+	stmt := &updateStatement{
+		table:   t,
+		columns: cols,
+		values:  vals,
+	}
+	q := &UpdateQuery{
+		stmt:    stmt,
+		scanner: scanner,
+	}
+	for x, c := range sv.pkColumns {
+		q.Where(Equal(c, sv.pkValues[x]))
+	}
+	return q
+}
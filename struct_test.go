@@ -0,0 +1,158 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTimestamps struct {
+	CreatedOn time.Time
+	UpdatedOn *time.Time `db:"updated_on,omitempty"`
+}
+
+type testAudit struct {
+	ChangedBy string
+}
+
+type testUser struct {
+	ID     int64  `db:"id,pk,omitempty"`
+	Email  string `db:"email"`
+	Name   string `db:",omitempty"`
+	Secret string `db:"-"`
+	notes  string
+	testTimestamps
+	*testAudit
+}
+
+func TestRegisterStruct(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	users, err := m.RegisterStruct("users", &testUser{})
+	assert.Nil(err)
+	assert.Equal(users, m.Table("users"))
+
+	names := make([]string, 0)
+	for _, c := range users.Columns() {
+		names = append(names, c.Name())
+	}
+	assert.Equal([]string{"id", "email", "name", "created_on", "updated_on", "changed_by"}, names)
+	assert.Equal([]*Column{users.C("id")}, users.PrimaryKey())
+	assert.Equal("users_pkey", users.Constraints()[0].Name())
+
+	// Registering a struct against a known table keeps its columns and key
+	_, err = m.RegisterStruct("users", struct {
+		Email    string `db:"email,pk"`
+		Nickname string
+	}{})
+	assert.Nil(err)
+	assert.Equal(7, len(users.Columns()))
+	assert.Equal([]*Column{users.C("id")}, users.PrimaryKey())
+
+	_, err = m.RegisterStruct("bad", 1)
+	assert.Equal(ERR_STRUCT_INVALID, err)
+	_, err = m.RegisterStruct("bad", (*testUser)(nil))
+	assert.Equal(ERR_STRUCT_INVALID, err)
+	_, err = m.RegisterStruct("bad", struct{ a int }{})
+	assert.Equal(ERR_STRUCT_NO_COLUMNS, err)
+	_, err = m.RegisterStruct("bad", struct {
+		A int `db:"x"`
+		B int `db:"x"`
+	}{})
+	assert.True(errors.Is(err, ERR_STRUCT_DUPLICATE_COLUMN))
+	assert.Nil(m.Table("bad"))
+}
+
+func TestInsertStruct(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_MYSQL, "test")
+	users, err := m.RegisterStruct("users", testUser{})
+	assert.Nil(err)
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	u := &testUser{
+		Email:          "fred@example.com",
+		Secret:         "hunter2",
+		testTimestamps: testTimestamps{CreatedOn: created},
+		testAudit:      &testAudit{ChangedBy: "admin"},
+	}
+	q := users.InsertStruct(u)
+	assert.Nil(q.Error())
+	qs, qargs := q.StringArgs()
+	assert.Equal("INSERT INTO users (email, created_on, changed_by) VALUES (?, ?, ?)", qs)
+	assert.Equal([]interface{}{"fred@example.com", created, "admin"}, qargs)
+
+	// Fields promoted through a nil embedded pointer are NULL
+	u = &testUser{ID: 42, Email: "barney@example.com", Name: "Barney"}
+	qs, qargs = users.InsertStruct(*u).StringArgs()
+	assert.Equal("INSERT INTO users (id, email, name, created_on, changed_by) VALUES (?, ?, ?, ?, ?)", qs)
+	assert.Equal([]interface{}{int64(42), "barney@example.com", "Barney", time.Time{}, nil}, qargs)
+
+	q = users.InsertStruct(struct{ Unknown int }{})
+	assert.True(errors.Is(q.Error(), ERR_INSERT_UNKNOWN_COLUMN))
+	q = users.InsertStruct(struct {
+		ID int64 `db:"id,omitempty"`
+	}{})
+	assert.Equal(ERR_INSERT_NO_VALUES, q.Error())
+	q = users.InsertStruct("users")
+	assert.Equal(ERR_STRUCT_INVALID, q.Error())
+	q = m.NewView("user_names").InsertStruct(u)
+	assert.Equal(ERR_INSERT_READ_ONLY, q.Error())
+}
+
+func TestUpdateStruct(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	users, err := m.RegisterStruct("users", testUser{})
+	assert.Nil(err)
+
+	u := testUser{ID: 42, Email: "fred@example.com", testAudit: &testAudit{ChangedBy: "admin"}}
+	q := users.UpdateStruct(&u)
+	assert.Nil(q.Error())
+	qs, qargs := q.StringArgs()
+	assert.Equal("UPDATE users SET email = $1, created_on = $2, changed_by = $3 WHERE users.id = $4", qs)
+	assert.Equal([]interface{}{"fred@example.com", time.Time{}, "admin", int64(42)}, qargs)
+
+	// A field whose column is in the table's primary key identifies the row
+	// without a "pk" option
+	qs, qargs = users.UpdateStruct(struct {
+		ID   int64
+		Name string
+	}{ID: 7, Name: "Wilma"}).StringArgs()
+	assert.Equal("UPDATE users SET name = $1 WHERE users.id = $2", qs)
+	assert.Equal([]interface{}{"Wilma", int64(7)}, qargs)
+
+	q = users.UpdateStruct(struct{ Email string }{})
+	assert.Equal(ERR_STRUCT_NO_PRIMARY_KEY, q.Error())
+	q = users.UpdateStruct(struct{ ID int64 }{ID: 1})
+	assert.Equal(ERR_UPDATE_NO_VALUES, q.Error())
+	q = users.UpdateStruct(struct{ Unknown int }{})
+	assert.True(errors.Is(q.Error(), ERR_UPDATE_UNKNOWN_COLUMN))
+}
+
+func TestSnakeCase(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]string{
+		"ID":         "id",
+		"Email":      "email",
+		"CreatedOn":  "created_on",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Address2":   "address2",
+		"V2Name":     "v2_name",
+	}
+	for name, exp := range tests {
+		assert.Equal(exp, snakeCase(name), name)
+	}
+}
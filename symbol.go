@@ -5,6 +5,8 @@
 //
 package sqlb
 
+import "strings"
+
 type Symbol int
 type scanInfo []Symbol
 
@@ -100,6 +102,12 @@ const (
 	SYM_UNIT_DAY_MINUTE
 	SYM_UNIT_DAY_HOUR
 	SYM_UNIT_YEAR_MONTH
+	SYM_COMMENT_START
+	SYM_COMMENT_END
+	SYM_HINT_START
+	SYM_REFRESH_MATERIALIZED_VIEW
+	SYM_CONCURRENTLY
+	SYM_WITH_NO_DATA
 	SYM_PLACEHOLDER = 9999999999
 )
 
@@ -113,6 +121,14 @@ const (
 	SQL_TYPE_VARCHAR
 	SQL_TYPE_TEXT
 	SQL_TYPE_BINARY
+	SQL_TYPE_SMALLINT
+	SQL_TYPE_BIGINT
+	SQL_TYPE_BOOLEAN
+	SQL_TYPE_DATE
+	SQL_TYPE_TIME
+	SQL_TYPE_TIMESTAMP
+	SQL_TYPE_JSON
+	SQL_TYPE_UNKNOWN
 )
 
 var (
@@ -127,6 +143,72 @@ var (
 	}
 )
 
+var (
+	// Maps the lowercased data type names reported by MySQL and PostgreSQL in
+	// INFORMATION_SCHEMA.COLUMNS.DATA_TYPE to a SqlType
+	rawTypeToSqlType = map[string]SqlType{
+		"char":                        SQL_TYPE_CHAR,
+		"character":                   SQL_TYPE_CHAR,
+		"bpchar":                      SQL_TYPE_CHAR,
+		"varchar":                     SQL_TYPE_VARCHAR,
+		"character varying":           SQL_TYPE_VARCHAR,
+		"text":                        SQL_TYPE_TEXT,
+		"tinytext":                    SQL_TYPE_TEXT,
+		"mediumtext":                  SQL_TYPE_TEXT,
+		"longtext":                    SQL_TYPE_TEXT,
+		"int":                         SQL_TYPE_INT,
+		"integer":                     SQL_TYPE_INT,
+		"mediumint":                   SQL_TYPE_INT,
+		"int4":                        SQL_TYPE_INT,
+		"serial":                      SQL_TYPE_INT,
+		"tinyint":                     SQL_TYPE_SMALLINT,
+		"smallint":                    SQL_TYPE_SMALLINT,
+		"int2":                        SQL_TYPE_SMALLINT,
+		"smallserial":                 SQL_TYPE_SMALLINT,
+		"bigint":                      SQL_TYPE_BIGINT,
+		"int8":                        SQL_TYPE_BIGINT,
+		"bigserial":                   SQL_TYPE_BIGINT,
+		"float":                       SQL_TYPE_FLOAT,
+		"double":                      SQL_TYPE_FLOAT,
+		"real":                        SQL_TYPE_FLOAT,
+		"double precision":            SQL_TYPE_FLOAT,
+		"float4":                      SQL_TYPE_FLOAT,
+		"float8":                      SQL_TYPE_FLOAT,
+		"decimal":                     SQL_TYPE_DECIMAL,
+		"numeric":                     SQL_TYPE_DECIMAL,
+		"binary":                      SQL_TYPE_BINARY,
+		"varbinary":                   SQL_TYPE_BINARY,
+		"tinyblob":                    SQL_TYPE_BINARY,
+		"blob":                        SQL_TYPE_BINARY,
+		"mediumblob":                  SQL_TYPE_BINARY,
+		"longblob":                    SQL_TYPE_BINARY,
+		"bytea":                       SQL_TYPE_BINARY,
+		"bool":                        SQL_TYPE_BOOLEAN,
+		"boolean":                     SQL_TYPE_BOOLEAN,
+		"date":                        SQL_TYPE_DATE,
+		"time":                        SQL_TYPE_TIME,
+		"time without time zone":      SQL_TYPE_TIME,
+		"time with time zone":         SQL_TYPE_TIME,
+		"datetime":                    SQL_TYPE_TIMESTAMP,
+		"timestamp":                   SQL_TYPE_TIMESTAMP,
+		"timestamp without time zone": SQL_TYPE_TIMESTAMP,
+		"timestamp with time zone":    SQL_TYPE_TIMESTAMP,
+		"timestamptz":                 SQL_TYPE_TIMESTAMP,
+		"json":                        SQL_TYPE_JSON,
+		"jsonb":                       SQL_TYPE_JSON,
+	}
+)
+
+// Returns the SqlType for a data type name as reported by the database
+// server, or SQL_TYPE_UNKNOWN if the data type name is not known
+func sqlTypeFromRaw(rawType string) SqlType {
+	st, found := rawTypeToSqlType[strings.ToLower(strings.TrimSpace(rawType))]
+	if !found {
+		return SQL_TYPE_UNKNOWN
+	}
+	return st
+}
+
 type IntervalUnit int
 
 const (
@@ -180,95 +262,101 @@ var (
 
 var (
 	Symbols = map[Symbol][]byte{
-		SYM_QUEST_MARK:              []byte("?"),
-		SYM_SPACE:                   []byte(" "),
-		SYM_DOLLAR:                  []byte("$"),
-		SYM_PERIOD:                  []byte("."),
-		SYM_AS:                      []byte(" AS "),
-		SYM_COMMA_WS:                []byte(", "),
-		SYM_SELECT:                  []byte("SELECT "),
-		SYM_FROM:                    []byte("FROM "),
-		SYM_JOIN:                    []byte("JOIN "),
-		SYM_LEFT_JOIN:               []byte("LEFT JOIN "),
-		SYM_CROSS_JOIN:              []byte("CROSS JOIN "),
-		SYM_ON:                      []byte(" ON "),
-		SYM_WHERE:                   []byte("WHERE "),
-		SYM_GROUP_BY:                []byte("GROUP BY "),
-		SYM_HAVING:                  []byte("HAVING "),
-		SYM_ORDER_BY:                []byte("ORDER BY "),
-		SYM_DESC:                    []byte(" DESC"),
-		SYM_LIMIT:                   []byte("LIMIT "),
-		SYM_OFFSET:                  []byte(" OFFSET "),
-		SYM_INSERT:                  []byte("INSERT INTO "),
-		SYM_VALUES:                  []byte(") VALUES ("),
-		SYM_DELETE:                  []byte("DELETE FROM "),
-		SYM_UPDATE:                  []byte("UPDATE "),
-		SYM_SET:                     []byte(" SET "),
-		SYM_LPAREN:                  []byte("("),
-		SYM_RPAREN:                  []byte(")"),
-		SYM_IN:                      []byte(" IN ("),
-		SYM_AND:                     []byte(" AND "),
-		SYM_OR:                      []byte(" OR "),
-		SYM_EQUAL:                   []byte(" = "),
-		SYM_NEQUAL:                  []byte(" != "),
-		SYM_BETWEEN:                 []byte(" BETWEEN "),
-		SYM_IS_NULL:                 []byte(" IS NULL"),
-		SYM_IS_NOT_NULL:             []byte(" IS NOT NULL"),
-		SYM_GREATER:                 []byte(" > "),
-		SYM_GREATER_EQUAL:           []byte(" >= "),
-		SYM_LESS:                    []byte(" < "),
-		SYM_LESS_EQUAL:              []byte(" <= "),
-		SYM_MAX:                     []byte("MAX("),
-		SYM_MIN:                     []byte("MIN("),
-		SYM_SUM:                     []byte("SUM("),
-		SYM_AVG:                     []byte("AVG("),
-		SYM_COUNT_STAR:              []byte("COUNT(*)"),
-		SYM_COUNT_DISTINCT:          []byte("COUNT(DISTINCT "),
-		SYM_CAST:                    []byte("CAST("),
-		SYM_BTRIM:                   []byte("BTRIM("),
-		SYM_TRIM:                    []byte("TRIM("),
-		SYM_LTRIM:                   []byte("LTRIM("),
-		SYM_RTRIM:                   []byte("RTRIM("),
-		SYM_LEADING:                 []byte("LEADING"),
-		SYM_TRAILING:                []byte("TRAILING"),
-		SYM_BOTH:                    []byte("BOTH"),
-		SYM_CHAR_LENGTH:             []byte("CHAR_LENGTH("),
-		SYM_BIT_LENGTH:              []byte("BIT_LENGTH("),
-		SYM_ASCII:                   []byte("ASCII("),
-		SYM_REVERSE:                 []byte("REVERSE("),
-		SYM_CONCAT:                  []byte("CONCAT("),
-		SYM_CONCAT_WS:               []byte("CONCAT_WS("),
-		SYM_NOW:                     []byte("NOW()"),
-		SYM_CURRENT_TIMESTAMP:       []byte("CURRENT_TIMESTAMP()"),
-		SYM_CURRENT_TIME:            []byte("CURRENT_TIME()"),
-		SYM_CURRENT_DATE:            []byte("CURRENT_DATE()"),
-		SYM_EXTRACT:                 []byte("EXTRACT("),
-		SYM_TYPE_CHAR:               []byte("CHAR"),
-		SYM_TYPE_VARCHAR:            []byte("VARCHAR"),
-		SYM_TYPE_TEXT:               []byte("TEXT"),
-		SYM_TYPE_INT:                []byte("INT"),
-		SYM_TYPE_FLOAT:              []byte("FLOAT"),
-		SYM_TYPE_DECIMAL:            []byte("DECIMAL"),
-		SYM_TYPE_BINARY:             []byte("BINARY"),
-		SYM_UNIT_MICROSECOND:        []byte("MICROSECOND"),
-		SYM_UNIT_SECOND:             []byte("SECOND"),
-		SYM_UNIT_MINUTE:             []byte("MINUTE"),
-		SYM_UNIT_HOUR:               []byte("HOST"),
-		SYM_UNIT_DAY:                []byte("DAY"),
-		SYM_UNIT_WEEK:               []byte("WEEK"),
-		SYM_UNIT_MONTH:              []byte("MONTH"),
-		SYM_UNIT_QUARTER:            []byte("QUARTER"),
-		SYM_UNIT_YEAR:               []byte("YEAR"),
-		SYM_UNIT_SECOND_MICROSECOND: []byte("SECOND_MICROSECOND"),
-		SYM_UNIT_MINUTE_MICROSECOND: []byte("MINUTE_MICROSECOND"),
-		SYM_UNIT_MINUTE_SECOND:      []byte("MINUTE_SECOND"),
-		SYM_UNIT_HOUR_MICROSECOND:   []byte("HOUR_MICROSECOND"),
-		SYM_UNIT_HOUR_SECOND:        []byte("HOUR_SECOND"),
-		SYM_UNIT_HOUR_MINUTE:        []byte("HOUR_MINUTE"),
-		SYM_UNIT_DAY_MICROSECOND:    []byte("DAY_MICROSECOND"),
-		SYM_UNIT_DAY_SECOND:         []byte("DAY_SECOND"),
-		SYM_UNIT_DAY_MINUTE:         []byte("DAY_MINUTE"),
-		SYM_UNIT_DAY_HOUR:           []byte("DAY_HOUR"),
-		SYM_UNIT_YEAR_MONTH:         []byte("YEAR_MONTH"),
+		SYM_QUEST_MARK:                []byte("?"),
+		SYM_SPACE:                     []byte(" "),
+		SYM_DOLLAR:                    []byte("$"),
+		SYM_PERIOD:                    []byte("."),
+		SYM_AS:                        []byte(" AS "),
+		SYM_COMMA_WS:                  []byte(", "),
+		SYM_SELECT:                    []byte("SELECT "),
+		SYM_FROM:                      []byte("FROM "),
+		SYM_JOIN:                      []byte("JOIN "),
+		SYM_LEFT_JOIN:                 []byte("LEFT JOIN "),
+		SYM_CROSS_JOIN:                []byte("CROSS JOIN "),
+		SYM_ON:                        []byte(" ON "),
+		SYM_WHERE:                     []byte("WHERE "),
+		SYM_GROUP_BY:                  []byte("GROUP BY "),
+		SYM_HAVING:                    []byte("HAVING "),
+		SYM_ORDER_BY:                  []byte("ORDER BY "),
+		SYM_DESC:                      []byte(" DESC"),
+		SYM_LIMIT:                     []byte("LIMIT "),
+		SYM_OFFSET:                    []byte(" OFFSET "),
+		SYM_INSERT:                    []byte("INSERT INTO "),
+		SYM_VALUES:                    []byte(") VALUES ("),
+		SYM_DELETE:                    []byte("DELETE FROM "),
+		SYM_UPDATE:                    []byte("UPDATE "),
+		SYM_SET:                       []byte(" SET "),
+		SYM_LPAREN:                    []byte("("),
+		SYM_RPAREN:                    []byte(")"),
+		SYM_IN:                        []byte(" IN ("),
+		SYM_AND:                       []byte(" AND "),
+		SYM_OR:                        []byte(" OR "),
+		SYM_EQUAL:                     []byte(" = "),
+		SYM_NEQUAL:                    []byte(" != "),
+		SYM_BETWEEN:                   []byte(" BETWEEN "),
+		SYM_IS_NULL:                   []byte(" IS NULL"),
+		SYM_IS_NOT_NULL:               []byte(" IS NOT NULL"),
+		SYM_GREATER:                   []byte(" > "),
+		SYM_GREATER_EQUAL:             []byte(" >= "),
+		SYM_LESS:                      []byte(" < "),
+		SYM_LESS_EQUAL:                []byte(" <= "),
+		SYM_MAX:                       []byte("MAX("),
+		SYM_MIN:                       []byte("MIN("),
+		SYM_SUM:                       []byte("SUM("),
+		SYM_AVG:                       []byte("AVG("),
+		SYM_COUNT_STAR:                []byte("COUNT(*)"),
+		SYM_COUNT_DISTINCT:            []byte("COUNT(DISTINCT "),
+		SYM_CAST:                      []byte("CAST("),
+		SYM_BTRIM:                     []byte("BTRIM("),
+		SYM_TRIM:                      []byte("TRIM("),
+		SYM_LTRIM:                     []byte("LTRIM("),
+		SYM_RTRIM:                     []byte("RTRIM("),
+		SYM_LEADING:                   []byte("LEADING"),
+		SYM_TRAILING:                  []byte("TRAILING"),
+		SYM_BOTH:                      []byte("BOTH"),
+		SYM_CHAR_LENGTH:               []byte("CHAR_LENGTH("),
+		SYM_BIT_LENGTH:                []byte("BIT_LENGTH("),
+		SYM_ASCII:                     []byte("ASCII("),
+		SYM_REVERSE:                   []byte("REVERSE("),
+		SYM_CONCAT:                    []byte("CONCAT("),
+		SYM_CONCAT_WS:                 []byte("CONCAT_WS("),
+		SYM_NOW:                       []byte("NOW()"),
+		SYM_CURRENT_TIMESTAMP:         []byte("CURRENT_TIMESTAMP()"),
+		SYM_CURRENT_TIME:              []byte("CURRENT_TIME()"),
+		SYM_CURRENT_DATE:              []byte("CURRENT_DATE()"),
+		SYM_EXTRACT:                   []byte("EXTRACT("),
+		SYM_TYPE_CHAR:                 []byte("CHAR"),
+		SYM_TYPE_VARCHAR:              []byte("VARCHAR"),
+		SYM_TYPE_TEXT:                 []byte("TEXT"),
+		SYM_TYPE_INT:                  []byte("INT"),
+		SYM_TYPE_FLOAT:                []byte("FLOAT"),
+		SYM_TYPE_DECIMAL:              []byte("DECIMAL"),
+		SYM_TYPE_BINARY:               []byte("BINARY"),
+		SYM_UNIT_MICROSECOND:          []byte("MICROSECOND"),
+		SYM_UNIT_SECOND:               []byte("SECOND"),
+		SYM_UNIT_MINUTE:               []byte("MINUTE"),
+		SYM_UNIT_HOUR:                 []byte("HOST"),
+		SYM_UNIT_DAY:                  []byte("DAY"),
+		SYM_UNIT_WEEK:                 []byte("WEEK"),
+		SYM_UNIT_MONTH:                []byte("MONTH"),
+		SYM_UNIT_QUARTER:              []byte("QUARTER"),
+		SYM_UNIT_YEAR:                 []byte("YEAR"),
+		SYM_UNIT_SECOND_MICROSECOND:   []byte("SECOND_MICROSECOND"),
+		SYM_UNIT_MINUTE_MICROSECOND:   []byte("MINUTE_MICROSECOND"),
+		SYM_UNIT_MINUTE_SECOND:        []byte("MINUTE_SECOND"),
+		SYM_UNIT_HOUR_MICROSECOND:     []byte("HOUR_MICROSECOND"),
+		SYM_UNIT_HOUR_SECOND:          []byte("HOUR_SECOND"),
+		SYM_UNIT_HOUR_MINUTE:          []byte("HOUR_MINUTE"),
+		SYM_UNIT_DAY_MICROSECOND:      []byte("DAY_MICROSECOND"),
+		SYM_UNIT_DAY_SECOND:           []byte("DAY_SECOND"),
+		SYM_UNIT_DAY_MINUTE:           []byte("DAY_MINUTE"),
+		SYM_UNIT_DAY_HOUR:             []byte("DAY_HOUR"),
+		SYM_UNIT_YEAR_MONTH:           []byte("YEAR_MONTH"),
+		SYM_COMMENT_START:             []byte("/* "),
+		SYM_COMMENT_END:               []byte(" */"),
+		SYM_HINT_START:                []byte("/*+ "),
+		SYM_REFRESH_MATERIALIZED_VIEW: []byte("REFRESH MATERIALIZED VIEW "),
+		SYM_CONCURRENTLY:              []byte("CONCURRENTLY "),
+		SYM_WITH_NO_DATA:              []byte(" WITH NO DATA"),
 	}
 )
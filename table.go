@@ -5,11 +5,32 @@
 //
 package sqlb
 
+import "sort"
+
+type TableKind int
+
+const (
+	TABLE_KIND_TABLE TableKind = iota
+	TABLE_KIND_VIEW
+	TABLE_KIND_MATERIALIZED_VIEW
+)
+
 type Table struct {
-	alias   string
-	meta    *Meta
-	name    string
-	columns []*Column
+	alias  string
+	meta   *Meta
+	schema string
+	name   string
+	kind   TableKind
+	// Whether rows may be inserted, updated and deleted through a view. Always
+	// false for materialized views and ignored for tables.
+	updatable bool
+	// Whether the table was discovered by Reflect() as opposed to being
+	// added manually with Meta.NewTable()
+	reflected   bool
+	columns     []*Column
+	constraints []*Constraint
+	foreignKeys []*ForeignKey
+	indexes     []*Index
 }
 
 // Return a pointer to a Column with a name or alias matching the supplied
@@ -23,6 +44,301 @@ func (t *Table) C(name string) *Column {
 	return nil
 }
 
+// Returns the name of the table
+func (t *Table) Name() string {
+	return t.name
+}
+
+// Returns whether the selection is a table, a view or a materialized view
+func (t *Table) Kind() TableKind {
+	return t.kind
+}
+
+// Returns whether the selection is a view or a materialized view
+func (t *Table) IsView() bool {
+	return t.kind != TABLE_KIND_TABLE
+}
+
+// Returns whether rows may not be inserted, updated or deleted through the
+// selection. Tables are never read-only. Views are read-only unless they are
+// updatable, and materialized views are always read-only.
+func (t *Table) IsReadOnly() bool {
+	switch t.kind {
+	case TABLE_KIND_VIEW:
+		return !t.updatable
+	case TABLE_KIND_MATERIALIZED_VIEW:
+		return true
+	}
+	return false
+}
+
+// Sets whether rows may be inserted, updated and deleted through a view. Has
+// no effect on tables and materialized views.
+func (t *Table) SetUpdatable(updatable bool) *Table {
+	t.updatable = updatable
+	return t
+}
+
+// Returns the name of the schema the table belongs to, or "" if the table was
+// created without a schema
+func (t *Table) Schema() string {
+	return t.schema
+}
+
+// Returns the name of the schema that the table's name must be qualified with
+// when output in SQL, or "" if the table is in its Meta's default schema
+func (t *Table) qualifier() string {
+	if t.schema == "" || (t.meta != nil && t.schema == t.meta.defaultSchema) {
+		return ""
+	}
+	return t.schema
+}
+
+// Returns the table's name, qualified with its schema name if the table is
+// not in its Meta's default schema, as accepted by Meta.Table()
+func (t *Table) qualifiedName() string {
+	if q := t.qualifier(); q != "" {
+		return q + "." + t.name
+	}
+	return t.name
+}
+
+// Returns the number of bytes needed to output the table's name, qualified
+// with its schema name if needed
+func (t *Table) nameSize() int {
+	if q := t.qualifier(); q != "" {
+		return len(q) + len(Symbols[SYM_PERIOD]) + len(t.name)
+	}
+	return len(t.name)
+}
+
+// Writes the table's name, qualified with its schema name if needed
+func (t *Table) scanName(b []byte) int {
+	bw := 0
+	if q := t.qualifier(); q != "" {
+		bw += copy(b, q)
+		bw += copy(b[bw:], Symbols[SYM_PERIOD])
+	}
+	bw += copy(b[bw:], t.name)
+	return bw
+}
+
+// Returns the alias of the table, or "" if the table is not aliased
+func (t *Table) Alias() string {
+	return t.alias
+}
+
+// Returns the table's columns
+func (t *Table) Columns() []*Column {
+	return t.columns
+}
+
+// Returns the columns that make up the table's primary key, in key order, or
+// an empty slice if the table's primary key is not known
+func (t *Table) PrimaryKey() []*Column {
+	res := make([]*Column, 0)
+	for _, c := range t.columns {
+		if c.def.pkOrdinal > 0 {
+			res = append(res, c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].def.pkOrdinal < res[j].def.pkOrdinal
+	})
+	return res
+}
+
+// Returns the table's PRIMARY KEY and UNIQUE constraints
+func (t *Table) Constraints() []*Constraint {
+	return t.constraints
+}
+
+// Returns the table's foreign keys
+func (t *Table) ForeignKeys() []*ForeignKey {
+	return t.foreignKeys
+}
+
+// Returns the table's secondary indexes
+func (t *Table) Indexes() []*Index {
+	return t.indexes
+}
+
+// Creates and returns a PRIMARY KEY constraint on the supplied columns, which
+// are marked as the table's primary key in the order supplied
+func (t *Table) NewPrimaryKey(name string, columns ...*Column) *Constraint {
+	for x, c := range columns {
+		c.def.pkOrdinal = x + 1
+	}
+	return t.newConstraint(CONSTRAINT_TYPE_PRIMARY_KEY, name, columns)
+}
+
+// Creates and returns a UNIQUE constraint on the supplied columns
+func (t *Table) NewUniqueConstraint(name string, columns ...*Column) *Constraint {
+	return t.newConstraint(CONSTRAINT_TYPE_UNIQUE, name, columns)
+}
+
+func (t *Table) newConstraint(ctype ConstraintType, name string, columns []*Column) *Constraint {
+	c := &Constraint{
+		name:    name,
+		ctype:   ctype,
+		tbl:     t,
+		columns: columns,
+	}
+	t.constraints = append(t.constraints, c)
+	return c
+}
+
+// Creates and returns a foreign key from the supplied columns of this table
+// to the supplied columns of a referenced table. The referenced table is the
+// table of the first referenced column. The foreign key's referential
+// actions default to REFERENTIAL_ACTION_NO_ACTION.
+func (t *Table) NewForeignKey(name string, columns []*Column, refColumns []*Column) *ForeignKey {
+	fk := &ForeignKey{
+		name:       name,
+		tbl:        t,
+		columns:    columns,
+		refColumns: refColumns,
+	}
+	if len(refColumns) > 0 {
+		fk.refTable = refColumns[0].tbl
+	}
+	t.foreignKeys = append(t.foreignKeys, fk)
+	return fk
+}
+
+// Creates and returns a secondary index on the supplied columns
+func (t *Table) NewIndex(name string, unique bool, columns ...*Column) *Index {
+	i := &Index{
+		name:    name,
+		tbl:     t,
+		unique:  unique,
+		columns: columns,
+	}
+	t.indexes = append(t.indexes, i)
+	return i
+}
+
+// Returns the index with the supplied name, or nil if the table has no such
+// index
+func (t *Table) index(name string) *Index {
+	for _, i := range t.indexes {
+		if i.name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// Returns the table's UNIQUE constraint with the supplied name, or nil
+func (t *Table) uniqueConstraint(name string) *Constraint {
+	for _, c := range t.constraints {
+		if c.ctype == CONSTRAINT_TYPE_UNIQUE && c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Returns whether the supplied foreign key is one of the table's foreign keys
+func (t *Table) hasForeignKey(fk *ForeignKey) bool {
+	for _, f := range t.foreignKeys {
+		if f == fk {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the column with the supplied name along with the constraints,
+// foreign keys and indexes that include it
+func (t *Table) dropColumn(name string) {
+	c := t.C(name)
+	if c == nil {
+		return
+	}
+	cols := make([]*Column, 0, len(t.columns))
+	for _, tc := range t.columns {
+		if tc != c {
+			cols = append(cols, tc)
+		}
+	}
+	t.columns = cols
+	cons := make([]*Constraint, 0, len(t.constraints))
+	for _, con := range t.constraints {
+		if !containsColumn(con.columns, c) {
+			cons = append(cons, con)
+		} else if con.ctype == CONSTRAINT_TYPE_PRIMARY_KEY {
+			for _, pc := range con.columns {
+				pc.def.pkOrdinal = 0
+			}
+		}
+	}
+	t.constraints = cons
+	fks := make([]*ForeignKey, 0, len(t.foreignKeys))
+	for _, fk := range t.foreignKeys {
+		if !containsColumn(fk.columns, c) {
+			fks = append(fks, fk)
+		}
+	}
+	t.foreignKeys = fks
+	idxs := make([]*Index, 0, len(t.indexes))
+	for _, i := range t.indexes {
+		if !containsColumn(i.columns, c) {
+			idxs = append(idxs, i)
+		}
+	}
+	t.indexes = idxs
+}
+
+// Removes the constraint or foreign key with the supplied name. Dropping a
+// UNIQUE constraint also drops the unique index that implements it.
+func (t *Table) dropConstraint(name string) {
+	cons := make([]*Constraint, 0, len(t.constraints))
+	for _, con := range t.constraints {
+		if con.name != name {
+			cons = append(cons, con)
+			continue
+		}
+		switch con.ctype {
+		case CONSTRAINT_TYPE_PRIMARY_KEY:
+			for _, c := range con.columns {
+				c.def.pkOrdinal = 0
+			}
+		case CONSTRAINT_TYPE_UNIQUE:
+			t.dropIndex(name)
+		}
+	}
+	t.constraints = cons
+	fks := make([]*ForeignKey, 0, len(t.foreignKeys))
+	for _, fk := range t.foreignKeys {
+		if fk.name != name {
+			fks = append(fks, fk)
+		}
+	}
+	t.foreignKeys = fks
+}
+
+// Removes the index with the supplied name
+func (t *Table) dropIndex(name string) {
+	idxs := make([]*Index, 0, len(t.indexes))
+	for _, i := range t.indexes {
+		if i.name != name {
+			idxs = append(idxs, i)
+		}
+	}
+	t.indexes = idxs
+}
+
+// Returns whether the supplied column is one of the supplied columns
+func containsColumn(cols []*Column, c *Column) bool {
+	for _, cc := range cols {
+		if cc == c {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Table) NewColumn(name string) *Column {
 	c := t.C(name)
 	if c != nil {
@@ -49,7 +365,7 @@ func (t *Table) argCount() int {
 }
 
 func (t *Table) size(scanner *sqlScanner) int {
-	size := len(t.name)
+	size := t.nameSize()
 	if t.alias != "" {
 		size += len(Symbols[SYM_AS]) + len(t.alias)
 	}
@@ -57,7 +373,7 @@ func (t *Table) size(scanner *sqlScanner) int {
 }
 
 func (t *Table) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
-	bw := copy(b, t.name)
+	bw := t.scanName(b)
 	if t.alias != "" {
 		bw += copy(b[bw:], Symbols[SYM_AS])
 		bw += copy(b[bw:], t.alias)
@@ -67,16 +383,25 @@ func (t *Table) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *
 
 func (t *Table) As(alias string) *Table {
 	cols := make([]*Column, len(t.columns))
+	// The aliased table shares the constraints, foreign keys and indexes of
+	// the original table, which refer to the original table's columns
 	tbl := &Table{
-		alias: alias,
-		name:  t.name,
-		meta:  t.meta,
+		alias:       alias,
+		schema:      t.schema,
+		name:        t.name,
+		kind:        t.kind,
+		updatable:   t.updatable,
+		meta:        t.meta,
+		constraints: t.constraints,
+		foreignKeys: t.foreignKeys,
+		indexes:     t.indexes,
 	}
 	for x, c := range t.columns {
 		cols[x] = &Column{
 			alias: c.alias,
 			name:  c.name,
 			tbl:   tbl,
+			def:   c.def,
 		}
 	}
 	tbl.columns = cols
@@ -0,0 +1,432 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ERR_DDL_NO_TARGET     = errors.New("No target table or index supplied.")
+	ERR_DDL_NO_DIALECT    = errors.New("The target's Meta must have a SQL dialect to generate DDL for.")
+	ERR_DDL_NOT_A_TABLE   = errors.New("Target is a view, not a table.")
+	ERR_DDL_UNKNOWN_TYPE  = errors.New("Unable to generate DDL for a column with an unknown data type.")
+	ERR_DDL_WRONG_TABLE   = errors.New("Received a column, constraint or foreign key of another table.")
+	ERR_DDL_NO_ACTIONS    = errors.New("No ALTER TABLE actions supplied.")
+	ERR_DDL_UNSUPPORTED   = errors.New("The SQL dialect does not support the requested clause.")
+	ERR_DDL_EMPTY_CHECK   = errors.New("CHECK constraints require an expression.")
+	ERR_DDL_CHECK_NO_NAME = errors.New("Dropping a CHECK constraint requires its name.")
+)
+
+// A ddlQuery implements the Query interface for the DDL statement builders.
+// DDL statements have no query arguments.
+type ddlQuery struct {
+	e       error
+	el      *ddlElement
+	scanner *sqlScanner
+}
+
+func newDDLQuery(dialect Dialect, stmt ddlStatement) ddlQuery {
+	return ddlQuery{
+		el:      &ddlElement{stmt: stmt},
+		scanner: newSqlScanner(dialect, defaultFormatOptions),
+	}
+}
+
+func (q *ddlQuery) renderer() *ddlRenderer {
+	return &ddlRenderer{dialect: q.scanner.dialect}
+}
+
+func (q *ddlQuery) IsValid() bool {
+	return q.e == nil && q.el != nil
+}
+
+func (q *ddlQuery) Error() error {
+	return q.e
+}
+
+// Returns the SQL string for the statement. Safe for concurrent use by
+// multiple goroutines as long as the statement is not being modified.
+func (q *ddlQuery) String() string {
+	b, _ := q.scanner.appendSQL(nil, nil, q.el)
+	return string(b)
+}
+
+// Returns the SQL string for the statement along with a new slice containing
+// the query arguments, which is always empty. Safe for concurrent use by
+// multiple goroutines as long as the statement is not being modified.
+func (q *ddlQuery) StringArgs() (string, []interface{}) {
+	b, args := q.scanner.appendSQL(nil, nil, q.el)
+	return string(b), args
+}
+
+// Appends the SQL string for the statement to dst and the query arguments to
+// args, returning the extended slices
+func (q *ddlQuery) AppendSQL(dst []byte, args []interface{}) ([]byte, []interface{}) {
+	return q.scanner.appendSQL(dst, args, q.el)
+}
+
+// Writes the SQL string for the statement to the supplied io.Writer.
+// Implements the io.WriterTo interface.
+func (q *ddlQuery) WriteTo(w io.Writer) (int64, error) {
+	return q.scanner.writeTo(w, q.el)
+}
+
+// Returns the SQL string for the statement. A DDL statement has no query
+// arguments, so this is the same as String().
+func (q *ddlQuery) Interpolated() string {
+	return q.String()
+}
+
+// Returns the statement's Fingerprint, which identifies the shape of the statement
+// independently of its argument values. Returns a zero Fingerprint if the
+// statement has an error.
+func (q *ddlQuery) Fingerprint() Fingerprint {
+	if !q.IsValid() {
+		return Fingerprint{}
+	}
+	return fingerprintOf(q.scanner.dialect, q.el)
+}
+
+// Returns the error for a DDL statement targeting the supplied table, or nil
+// if DDL can be generated for the table
+func checkDDLTable(t *Table) error {
+	if t == nil {
+		return ERR_DDL_NO_TARGET
+	}
+	if t.meta == nil || t.meta.dialect == DIALECT_UNKNOWN {
+		return ERR_DDL_NO_DIALECT
+	}
+	if t.kind != TABLE_KIND_TABLE {
+		return ERR_DDL_NOT_A_TABLE
+	}
+	return nil
+}
+
+// Returns whether the supplied tables are the same table of the same Meta,
+// possibly aliased
+func isSameTable(a *Table, b *Table) bool {
+	return a != nil && b != nil && a.meta == b.meta &&
+		a.qualifiedName() == b.qualifiedName()
+}
+
+type CreateTableQuery struct {
+	ddlQuery
+	stmt *createTableStatement
+}
+
+// Only create the table if a table with the same name doesn't exist
+func (q *CreateTableQuery) IfNotExists() *CreateTableQuery {
+	if q.e != nil {
+		return q
+	}
+	q.stmt.ifNotExists = true
+	return q
+}
+
+// Adds a CHECK constraint with the supplied name and boolean expression to
+// the table. If name is "", the database server names the constraint.
+func (q *CreateTableQuery) Check(name string, expr string) *CreateTableQuery {
+	if q.e != nil {
+		return q
+	}
+	if expr == "" {
+		q.e = ERR_DDL_EMPTY_CHECK
+		return q
+	}
+	q.stmt.checks = append(q.stmt.checks, &checkConstraint{name: name, expr: expr})
+	return q
+}
+
+// Given a table, returns a CreateTableQuery that will produce a CREATE TABLE
+// SQL statement with the table's columns, primary key, UNIQUE constraints and
+// foreign keys. For MySQL, the table's indexes are also declared in the
+// statement. For PostgreSQL, use CreateIndex() to create them.
+//
+// Each column must have a known data type, either because the table was
+// reflected or loaded from DDL or because the data type was set with
+// Column.SetType(). Auto-incrementing columns are output as AUTO_INCREMENT
+// columns for MySQL and as SERIAL or GENERATED BY DEFAULT AS IDENTITY columns
+// for PostgreSQL.
+func CreateTable(t *Table) *CreateTableQuery {
+	if err := checkDDLTable(t); err != nil {
+		return &CreateTableQuery{ddlQuery: ddlQuery{e: err}}
+	}
+	stmt := &createTableStatement{table: t}
+	q := &CreateTableQuery{
+		ddlQuery: newDDLQuery(t.meta.dialect, stmt),
+		stmt:     stmt,
+	}
+	if _, err := stmt.render(q.renderer()); err != nil {
+		q.e = err
+	}
+	return q
+}
+
+func (t *Table) Create() *CreateTableQuery {
+	return CreateTable(t)
+}
+
+type AlterTableQuery struct {
+	ddlQuery
+	stmt *alterTableStatement
+}
+
+func (q *AlterTableQuery) addAction(action string) *AlterTableQuery {
+	q.stmt.actions = append(q.stmt.actions, action)
+	return q
+}
+
+// Returns whether the query can take another action for the supplied
+// column, constraint or foreign key table, recording the query's error if
+// not
+func (q *AlterTableQuery) accepts(t *Table) bool {
+	if q.e != nil {
+		return false
+	}
+	if !isSameTable(q.stmt.table, t) {
+		q.e = ERR_DDL_WRONG_TABLE
+		return false
+	}
+	return true
+}
+
+// Adds the supplied column, with its current definition, to the table
+func (q *AlterTableQuery) AddColumn(c *Column) *AlterTableQuery {
+	if !q.accepts(c.tbl) {
+		return q
+	}
+	def, err := q.renderer().columnDefinitionSQL(c)
+	if err != nil {
+		q.e = err
+		return q
+	}
+	return q.addAction("ADD COLUMN " + def)
+}
+
+// Changes the definition of the supplied column to its current definition:
+// its data type, nullability and default
+func (q *AlterTableQuery) AlterColumn(c *Column) *AlterTableQuery {
+	if !q.accepts(c.tbl) {
+		return q
+	}
+	actions, err := q.renderer().alterColumnSQL(&ColumnDiff{
+		Name:            c.name,
+		Change:          CHANGE_TYPE_CHANGED,
+		To:              c,
+		TypeChanged:     true,
+		NullableChanged: true,
+		DefaultChanged:  true,
+	})
+	if err != nil {
+		q.e = err
+		return q
+	}
+	for _, action := range actions {
+		q.addAction(action)
+	}
+	return q
+}
+
+// Drops the supplied column from the table
+func (q *AlterTableQuery) DropColumn(c *Column) *AlterTableQuery {
+	if !q.accepts(c.tbl) {
+		return q
+	}
+	return q.addAction("DROP COLUMN " + q.renderer().ident(c.name))
+}
+
+// Adds the supplied PRIMARY KEY or UNIQUE constraint to the table
+func (q *AlterTableQuery) AddConstraint(c *Constraint) *AlterTableQuery {
+	if !q.accepts(c.tbl) {
+		return q
+	}
+	return q.addAction("ADD " + q.renderer().constraintSQL(c))
+}
+
+// Drops the supplied PRIMARY KEY or UNIQUE constraint from the table
+func (q *AlterTableQuery) DropConstraint(c *Constraint) *AlterTableQuery {
+	if !q.accepts(c.tbl) {
+		return q
+	}
+	return q.addAction(q.renderer().dropConstraintSQL(c))
+}
+
+// Adds the supplied foreign key to the table
+func (q *AlterTableQuery) AddForeignKey(fk *ForeignKey) *AlterTableQuery {
+	if !q.accepts(fk.tbl) {
+		return q
+	}
+	return q.addAction("ADD " + q.renderer().foreignKeySQL(fk))
+}
+
+// Drops the supplied foreign key from the table
+func (q *AlterTableQuery) DropForeignKey(fk *ForeignKey) *AlterTableQuery {
+	if !q.accepts(fk.tbl) {
+		return q
+	}
+	return q.addAction(q.renderer().dropForeignKeySQL(fk))
+}
+
+// Adds a CHECK constraint with the supplied name and boolean expression to
+// the table. If name is "", the database server names the constraint.
+func (q *AlterTableQuery) AddCheck(name string, expr string) *AlterTableQuery {
+	if q.e != nil {
+		return q
+	}
+	if expr == "" {
+		q.e = ERR_DDL_EMPTY_CHECK
+		return q
+	}
+	return q.addAction("ADD " + q.renderer().checkSQL(&checkConstraint{name: name, expr: expr}))
+}
+
+// Drops the CHECK constraint with the supplied name from the table
+func (q *AlterTableQuery) DropCheck(name string) *AlterTableQuery {
+	if q.e != nil {
+		return q
+	}
+	if name == "" {
+		q.e = ERR_DDL_CHECK_NO_NAME
+		return q
+	}
+	return q.addAction(q.renderer().dropCheckSQL(name))
+}
+
+// Returns the query's error. An ALTER TABLE statement without any actions is
+// not valid.
+func (q *AlterTableQuery) Error() error {
+	if q.e == nil && len(q.stmt.actions) == 0 {
+		return ERR_DDL_NO_ACTIONS
+	}
+	return q.e
+}
+
+func (q *AlterTableQuery) IsValid() bool {
+	return q.Error() == nil
+}
+
+// Given a table, returns an AlterTableQuery that will produce an ALTER TABLE
+// SQL statement with the actions added to the query, in the order they were
+// added. At least one action must be added.
+func AlterTable(t *Table) *AlterTableQuery {
+	if err := checkDDLTable(t); err != nil {
+		return &AlterTableQuery{
+			ddlQuery: ddlQuery{e: err},
+			stmt:     &alterTableStatement{table: t},
+		}
+	}
+	stmt := &alterTableStatement{table: t}
+	return &AlterTableQuery{
+		ddlQuery: newDDLQuery(t.meta.dialect, stmt),
+		stmt:     stmt,
+	}
+}
+
+func (t *Table) Alter() *AlterTableQuery {
+	return AlterTable(t)
+}
+
+type DropTableQuery struct {
+	ddlQuery
+	stmt *dropTableStatement
+}
+
+// Don't return an error if the table doesn't exist
+func (q *DropTableQuery) IfExists() *DropTableQuery {
+	if q.e != nil {
+		return q
+	}
+	q.stmt.ifExists = true
+	return q
+}
+
+// Drop the objects that depend on the table, such as views and the foreign
+// keys of other tables that reference it. MySQL accepts but ignores CASCADE.
+func (q *DropTableQuery) Cascade() *DropTableQuery {
+	if q.e != nil {
+		return q
+	}
+	q.stmt.cascade = true
+	return q
+}
+
+// Given a table, returns a DropTableQuery that will produce a DROP TABLE SQL
+// statement
+func DropTable(t *Table) *DropTableQuery {
+	if err := checkDDLTable(t); err != nil {
+		return &DropTableQuery{ddlQuery: ddlQuery{e: err}}
+	}
+	stmt := &dropTableStatement{table: t}
+	return &DropTableQuery{
+		ddlQuery: newDDLQuery(t.meta.dialect, stmt),
+		stmt:     stmt,
+	}
+}
+
+func (t *Table) Drop() *DropTableQuery {
+	return DropTable(t)
+}
+
+type TruncateQuery struct {
+	ddlQuery
+}
+
+// Given a table, returns a TruncateQuery that will produce a TRUNCATE TABLE
+// SQL statement, which deletes all of the table's rows
+func Truncate(t *Table) *TruncateQuery {
+	if err := checkDDLTable(t); err != nil {
+		return &TruncateQuery{ddlQuery: ddlQuery{e: err}}
+	}
+	return &TruncateQuery{
+		ddlQuery: newDDLQuery(t.meta.dialect, &truncateStatement{table: t}),
+	}
+}
+
+func (t *Table) Truncate() *TruncateQuery {
+	return Truncate(t)
+}
+
+type CreateIndexQuery struct {
+	ddlQuery
+	stmt *createIndexStatement
+}
+
+// Only create the index if an index with the same name doesn't exist. Only
+// supported by PostgreSQL.
+func (q *CreateIndexQuery) IfNotExists() *CreateIndexQuery {
+	if q.e != nil {
+		return q
+	}
+	if q.scanner.dialect != DIALECT_POSTGRESQL {
+		q.e = fmt.Errorf("%w Clause: CREATE INDEX IF NOT EXISTS", ERR_DDL_UNSUPPORTED)
+		return q
+	}
+	q.stmt.ifNotExists = true
+	return q
+}
+
+// Given an index, returns a CreateIndexQuery that will produce a CREATE
+// [UNIQUE] INDEX SQL statement
+func CreateIndex(i *Index) *CreateIndexQuery {
+	if i == nil {
+		return &CreateIndexQuery{ddlQuery: ddlQuery{e: ERR_DDL_NO_TARGET}}
+	}
+	if err := checkDDLTable(i.tbl); err != nil {
+		return &CreateIndexQuery{ddlQuery: ddlQuery{e: err}}
+	}
+	stmt := &createIndexStatement{index: i}
+	return &CreateIndexQuery{
+		ddlQuery: newDDLQuery(i.tbl.meta.dialect, stmt),
+		stmt:     stmt,
+	}
+}
+
+func (i *Index) Create() *CreateIndexQuery {
+	return CreateIndex(i)
+}
@@ -0,0 +1,547 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CREATE TABLE [IF NOT EXISTS] <table> (<column definition>, ... [, <table constraint>, ...])
+// ALTER TABLE <table> <action>, ...
+// DROP TABLE [IF EXISTS] <table> [CASCADE]
+// TRUNCATE TABLE <table>
+// CREATE [UNIQUE] INDEX [IF NOT EXISTS] <index> ON <table> (<column>, ...)
+
+// A ddlStatement renders a DDL statement in the dialect of the supplied
+// renderer. DDL statements have no query arguments.
+type ddlStatement interface {
+	render(r *ddlRenderer) (string, error)
+}
+
+// A ddlElement allows a ddlStatement to be scanned like the DML statements
+type ddlElement struct {
+	stmt ddlStatement
+}
+
+func (e *ddlElement) argCount() int {
+	return 0
+}
+
+func (e *ddlElement) size(scanner *sqlScanner) int {
+	qs, _ := e.stmt.render(&ddlRenderer{dialect: scanner.dialect})
+	return len(qs)
+}
+
+func (e *ddlElement) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
+	qs, _ := e.stmt.render(&ddlRenderer{dialect: scanner.dialect})
+	return copy(b, qs)
+}
+
+// A CHECK constraint, which is only known to the CREATE TABLE and ALTER TABLE
+// statements that add it
+type checkConstraint struct {
+	name string
+	expr string
+}
+
+type createTableStatement struct {
+	table       *Table
+	ifNotExists bool
+	checks      []*checkConstraint
+}
+
+func (s *createTableStatement) render(r *ddlRenderer) (string, error) {
+	elements, err := r.tableElementsSQL(s.table, s.table.foreignKeys, s.checks)
+	if err != nil {
+		return "", err
+	}
+	res := "CREATE TABLE "
+	if s.ifNotExists {
+		res += "IF NOT EXISTS "
+	}
+	return res + r.tableName(s.table) + " (" + strings.Join(elements, ", ") + ")", nil
+}
+
+// The actions of an ALTER TABLE statement are rendered when they are added
+type alterTableStatement struct {
+	table   *Table
+	actions []string
+}
+
+func (s *alterTableStatement) render(r *ddlRenderer) (string, error) {
+	return r.alterTableSQL(s.table, s.actions...), nil
+}
+
+type dropTableStatement struct {
+	table    *Table
+	ifExists bool
+	cascade  bool
+}
+
+func (s *dropTableStatement) render(r *ddlRenderer) (string, error) {
+	res := "DROP TABLE "
+	if s.ifExists {
+		res += "IF EXISTS "
+	}
+	res += r.tableName(s.table)
+	if s.cascade {
+		res += " CASCADE"
+	}
+	return res, nil
+}
+
+type truncateStatement struct {
+	table *Table
+}
+
+func (s *truncateStatement) render(r *ddlRenderer) (string, error) {
+	return "TRUNCATE TABLE " + r.tableName(s.table), nil
+}
+
+type createIndexStatement struct {
+	index       *Index
+	ifNotExists bool
+}
+
+func (s *createIndexStatement) render(r *ddlRenderer) (string, error) {
+	return r.createIndexSQL(s.index, s.ifNotExists), nil
+}
+
+// A ddlRenderer renders the parts of DDL statements in a SQL dialect. It is
+// shared by the DDL statement builders and SchemaDiff.Migration().
+type ddlRenderer struct {
+	dialect Dialect
+}
+
+// Returns whether the supplied index implements one of the table's UNIQUE
+// constraints, in which case it is created and dropped with the constraint
+func isConstraintIndex(t *Table, i *Index) bool {
+	return t.uniqueConstraint(i.name) != nil
+}
+
+func (r *ddlRenderer) alterTableSQL(t *Table, actions ...string) string {
+	return "ALTER TABLE " + r.tableName(t) + " " + strings.Join(actions, ", ")
+}
+
+// Returns the column definitions and table constraints of a CREATE TABLE
+// statement for the supplied table, including the supplied foreign keys and
+// CHECK constraints
+func (r *ddlRenderer) tableElementsSQL(t *Table, fks []*ForeignKey, checks []*checkConstraint) ([]string, error) {
+	res := make([]string, 0, len(t.columns)+len(t.constraints)+len(fks)+len(checks))
+	for _, c := range t.columns {
+		def, err := r.columnDefinitionSQL(c)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, def)
+	}
+	for _, c := range t.constraints {
+		res = append(res, r.constraintSQL(c))
+	}
+	// MySQL creates an index for a foreign key unless one exists when the
+	// foreign key is created, so MySQL indexes are declared in the CREATE
+	// TABLE statement, before the foreign keys. PostgreSQL indexes can only be
+	// created with CREATE INDEX.
+	if r.dialect == DIALECT_MYSQL {
+		for _, i := range t.indexes {
+			if isConstraintIndex(t, i) {
+				continue
+			}
+			def := "INDEX " + r.ident(i.name) + " " + r.columnList(i.columns)
+			if i.unique {
+				def = "UNIQUE " + def
+			}
+			res = append(res, def)
+		}
+	}
+	for _, fk := range fks {
+		res = append(res, r.foreignKeySQL(fk))
+	}
+	for _, c := range checks {
+		res = append(res, r.checkSQL(c))
+	}
+	return res, nil
+}
+
+// Returns a column definition, e.g. "email varchar(100) NOT NULL"
+func (r *ddlRenderer) columnDefinitionSQL(c *Column) (string, error) {
+	typ, err := r.columnTypeSQL(c)
+	if err != nil {
+		return "", err
+	}
+	res := r.ident(c.name) + " " + typ
+	if !c.def.nullable {
+		res += " NOT NULL"
+	}
+	if def := r.defaultSQL(c); def != "" {
+		res += " DEFAULT " + def
+	}
+	if c.def.autoIncrement {
+		switch r.dialect {
+		case DIALECT_MYSQL:
+			res += " AUTO_INCREMENT"
+		case DIALECT_POSTGRESQL:
+			if !isSerial(c) {
+				res += " GENERATED BY DEFAULT AS IDENTITY"
+			}
+		}
+	}
+	return res, nil
+}
+
+// Returns whether the column is a PostgreSQL SERIAL column, which is an
+// auto-incrementing column whose default is the next value of a sequence
+func isSerial(c *Column) bool {
+	return c.def.autoIncrement && c.def.defaultExpr != nil &&
+		strings.HasPrefix(*c.def.defaultExpr, "nextval(")
+}
+
+var (
+	// Maps the names of PostgreSQL's internal types to their SQL names, for
+	// array types reported as e.g. _int4
+	postgresqlTypeNamesFromInternal = map[string]string{}
+	// The data type for a column described by a Meta of another dialect
+	mysqlSqlTypeNames = map[SqlType]string{
+		SQL_TYPE_CHAR:      "char",
+		SQL_TYPE_INT:       "int",
+		SQL_TYPE_FLOAT:     "double",
+		SQL_TYPE_DECIMAL:   "decimal",
+		SQL_TYPE_VARCHAR:   "varchar",
+		SQL_TYPE_TEXT:      "text",
+		SQL_TYPE_BINARY:    "blob",
+		SQL_TYPE_SMALLINT:  "smallint",
+		SQL_TYPE_BIGINT:    "bigint",
+		SQL_TYPE_BOOLEAN:   "tinyint(1)",
+		SQL_TYPE_DATE:      "date",
+		SQL_TYPE_TIME:      "time",
+		SQL_TYPE_TIMESTAMP: "datetime",
+		SQL_TYPE_JSON:      "json",
+	}
+	postgresqlSqlTypeNames = map[SqlType]string{
+		SQL_TYPE_CHAR:      "character",
+		SQL_TYPE_INT:       "integer",
+		SQL_TYPE_FLOAT:     "double precision",
+		SQL_TYPE_DECIMAL:   "numeric",
+		SQL_TYPE_VARCHAR:   "character varying",
+		SQL_TYPE_TEXT:      "text",
+		SQL_TYPE_BINARY:    "bytea",
+		SQL_TYPE_SMALLINT:  "smallint",
+		SQL_TYPE_BIGINT:    "bigint",
+		SQL_TYPE_BOOLEAN:   "boolean",
+		SQL_TYPE_DATE:      "date",
+		SQL_TYPE_TIME:      "time without time zone",
+		SQL_TYPE_TIMESTAMP: "timestamp without time zone",
+		SQL_TYPE_JSON:      "jsonb",
+	}
+	postgresqlSerialTypes = map[string]string{
+		"integer":  "serial",
+		"smallint": "smallserial",
+		"bigint":   "bigserial",
+	}
+)
+
+func init() {
+	for name, internal := range postgresqlInternalTypeNames {
+		postgresqlTypeNamesFromInternal[internal] = name
+	}
+}
+
+// Returns the column's data type, e.g. "varchar(100)". The raw type is used
+// if the column's Meta has the same dialect as the DDL, otherwise the
+// data type is determined from the column's SqlType.
+func (r *ddlRenderer) columnTypeSQL(c *Column) (string, error) {
+	raw := c.def.rawType
+	if raw == "" {
+		return "", fmt.Errorf("%w Column: %s", ERR_DDL_UNKNOWN_TYPE, c.name)
+	}
+	if c.tbl != nil && c.tbl.meta != nil && c.tbl.meta.dialect != DIALECT_UNKNOWN &&
+		c.tbl.meta.dialect != r.dialect {
+		// The raw type is specific to the dialect of the column's Meta
+		names := mysqlSqlTypeNames
+		if r.dialect == DIALECT_POSTGRESQL {
+			names = postgresqlSqlTypeNames
+		}
+		name, found := names[c.def.sqlType]
+		if !found {
+			return "", fmt.Errorf("%w Column: %s", ERR_DDL_UNKNOWN_TYPE, c.name)
+		}
+		raw = name
+	}
+	if r.dialect == DIALECT_MYSQL {
+		// MySQL's COLUMN_TYPE includes the length, precision and scale
+		if !strings.Contains(raw, "(") {
+			raw += typeArgsSQL(c.def)
+		}
+		return raw, nil
+	}
+	if strings.HasPrefix(raw, "_") {
+		elem := raw[1:]
+		if name, found := postgresqlTypeNamesFromInternal[elem]; found {
+			elem = name
+		}
+		return elem + "[]", nil
+	}
+	if isSerial(c) {
+		if serial, found := postgresqlSerialTypes[raw]; found {
+			return serial, nil
+		}
+	}
+	return raw + typeArgsSQL(c.def), nil
+}
+
+// Returns the parenthesized length, or precision and scale, for data types
+// that have them, e.g. "(100)" or "(10,2)"
+func typeArgsSQL(def columnDef) string {
+	switch def.sqlType {
+	case SQL_TYPE_CHAR, SQL_TYPE_VARCHAR:
+		if def.length > 0 {
+			return "(" + strconv.Itoa(def.length) + ")"
+		}
+	case SQL_TYPE_DECIMAL:
+		if def.precision > 0 {
+			return "(" + strconv.Itoa(def.precision) + "," + strconv.Itoa(def.scale) + ")"
+		}
+	}
+	return ""
+}
+
+// Returns the column's default expression as it must be written in DDL, or
+// "" if the column has no default
+func (r *ddlRenderer) defaultSQL(c *Column) string {
+	if c.def.defaultExpr == nil || isSerial(c) && r.dialect == DIALECT_POSTGRESQL {
+		return ""
+	}
+	expr := *c.def.defaultExpr
+	if r.dialect != DIALECT_MYSQL || isDefaultExpression(expr) {
+		return expr
+	}
+	// MySQL reports string and temporal defaults without quotes
+	switch c.def.sqlType {
+	case SQL_TYPE_INT, SQL_TYPE_SMALLINT, SQL_TYPE_BIGINT, SQL_TYPE_FLOAT,
+		SQL_TYPE_DECIMAL, SQL_TYPE_BOOLEAN:
+		return expr
+	}
+	return "'" + strings.Replace(expr, "'", "''", -1) + "'"
+}
+
+// Returns whether a default reported by MySQL is an expression or literal
+// that must not be quoted, such as CURRENT_TIMESTAMP, NULL or 'quoted'
+func isDefaultExpression(expr string) bool {
+	if expr == "" {
+		return false
+	}
+	if expr[0] == '\'' || expr[0] == '(' || strings.Contains(expr, "(") {
+		return true
+	}
+	if _, err := strconv.ParseFloat(expr, 64); err == nil {
+		return true
+	}
+	switch strings.ToUpper(expr) {
+	case "NULL", "TRUE", "FALSE", "CURRENT_TIMESTAMP", "CURRENT_DATE",
+		"CURRENT_TIME", "LOCALTIME", "LOCALTIMESTAMP":
+		return true
+	}
+	return false
+}
+
+// Returns the actions that change a column's definition
+func (r *ddlRenderer) alterColumnSQL(cd *ColumnDiff) ([]string, error) {
+	c := cd.To
+	if r.dialect == DIALECT_MYSQL {
+		def, err := r.columnDefinitionSQL(c)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"MODIFY COLUMN " + def}, nil
+	}
+	prefix := "ALTER COLUMN " + r.ident(c.name) + " "
+	actions := make([]string, 0, 4)
+	if cd.TypeChanged {
+		typ, err := r.columnTypeSQL(c)
+		if err != nil {
+			return nil, err
+		}
+		if isSerial(c) {
+			// SERIAL is not a real type and may only be used when creating
+			// a column
+			typ = c.def.rawType + typeArgsSQL(c.def)
+		}
+		actions = append(actions, prefix+"TYPE "+typ)
+	}
+	if cd.NullableChanged {
+		if c.def.nullable {
+			actions = append(actions, prefix+"DROP NOT NULL")
+		} else {
+			actions = append(actions, prefix+"SET NOT NULL")
+		}
+	}
+	if cd.AutoIncrementChanged {
+		if c.def.autoIncrement {
+			actions = append(actions, prefix+"ADD GENERATED BY DEFAULT AS IDENTITY")
+		} else {
+			actions = append(actions, prefix+"DROP IDENTITY IF EXISTS")
+		}
+	}
+	if cd.DefaultChanged && !isSerial(c) {
+		if def := r.defaultSQL(c); def != "" {
+			actions = append(actions, prefix+"SET DEFAULT "+def)
+		} else {
+			actions = append(actions, prefix+"DROP DEFAULT")
+		}
+	}
+	return actions, nil
+}
+
+// Returns a PRIMARY KEY or UNIQUE table constraint definition
+func (r *ddlRenderer) constraintSQL(c *Constraint) string {
+	cols := r.columnList(c.columns)
+	if c.ctype == CONSTRAINT_TYPE_PRIMARY_KEY {
+		// MySQL always names the primary key PRIMARY
+		if r.dialect == DIALECT_MYSQL || c.name == "" {
+			return "PRIMARY KEY " + cols
+		}
+		return "CONSTRAINT " + r.ident(c.name) + " PRIMARY KEY " + cols
+	}
+	return "CONSTRAINT " + r.ident(c.name) + " UNIQUE " + cols
+}
+
+func (r *ddlRenderer) dropConstraintSQL(c *Constraint) string {
+	if r.dialect == DIALECT_MYSQL {
+		if c.ctype == CONSTRAINT_TYPE_PRIMARY_KEY {
+			return "DROP PRIMARY KEY"
+		}
+		return "DROP INDEX " + r.ident(c.name)
+	}
+	return "DROP CONSTRAINT " + r.ident(c.name)
+}
+
+// Returns a FOREIGN KEY table constraint definition
+func (r *ddlRenderer) foreignKeySQL(fk *ForeignKey) string {
+	res := "CONSTRAINT " + r.ident(fk.name) + " FOREIGN KEY " +
+		r.columnList(fk.columns) + " REFERENCES " + r.tableName(fk.refTable) +
+		" " + r.columnList(fk.refColumns)
+	if fk.onDelete != REFERENTIAL_ACTION_NO_ACTION {
+		res += " ON DELETE " + fk.onDelete.String()
+	}
+	if fk.onUpdate != REFERENTIAL_ACTION_NO_ACTION {
+		res += " ON UPDATE " + fk.onUpdate.String()
+	}
+	return res
+}
+
+// Returns a CHECK table constraint definition
+func (r *ddlRenderer) checkSQL(c *checkConstraint) string {
+	if c.name == "" {
+		return "CHECK (" + c.expr + ")"
+	}
+	return "CONSTRAINT " + r.ident(c.name) + " CHECK (" + c.expr + ")"
+}
+
+func (r *ddlRenderer) dropCheckSQL(name string) string {
+	if r.dialect == DIALECT_MYSQL {
+		return "DROP CHECK " + r.ident(name)
+	}
+	return "DROP CONSTRAINT " + r.ident(name)
+}
+
+func (r *ddlRenderer) dropForeignKeySQL(fk *ForeignKey) string {
+	if r.dialect == DIALECT_MYSQL {
+		return "DROP FOREIGN KEY " + r.ident(fk.name)
+	}
+	return "DROP CONSTRAINT " + r.ident(fk.name)
+}
+
+func (r *ddlRenderer) createIndexSQL(i *Index, ifNotExists bool) string {
+	res := "CREATE "
+	if i.unique {
+		res += "UNIQUE "
+	}
+	res += "INDEX "
+	if ifNotExists {
+		res += "IF NOT EXISTS "
+	}
+	return res + r.ident(i.name) + " ON " + r.tableName(i.tbl) + " " +
+		r.columnList(i.columns)
+}
+
+func (r *ddlRenderer) dropIndexSQL(i *Index) string {
+	if r.dialect == DIALECT_MYSQL {
+		return "DROP INDEX " + r.ident(i.name) + " ON " + r.tableName(i.tbl)
+	}
+	// PostgreSQL indexes live in the schema of their table
+	if q := i.tbl.qualifier(); q != "" {
+		return "DROP INDEX " + r.ident(q) + "." + r.ident(i.name)
+	}
+	return "DROP INDEX " + r.ident(i.name)
+}
+
+// Returns the table's name, qualified with its schema if needed, with each
+// part quoted if needed
+func (r *ddlRenderer) tableName(t *Table) string {
+	if q := t.qualifier(); q != "" {
+		return r.ident(q) + "." + r.ident(t.name)
+	}
+	return r.ident(t.name)
+}
+
+// Returns the parenthesized, comma-separated names of the supplied columns
+func (r *ddlRenderer) columnList(cols []*Column) string {
+	names := make([]string, len(cols))
+	for x, c := range cols {
+		names[x] = r.ident(c.name)
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+// Returns the supplied identifier, quoted if it is a reserved word or
+// contains characters other than lowercase letters, digits and underscores
+func (r *ddlRenderer) ident(name string) string {
+	if !needsQuoting(name) {
+		return name
+	}
+	if r.dialect == DIALECT_MYSQL {
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// Reserved words that are commonly used as table or column names
+var reservedIdentifiers = map[string]bool{
+	"add": true, "all": true, "alter": true, "and": true, "as": true,
+	"asc": true, "between": true, "by": true, "case": true, "check": true,
+	"column": true, "constraint": true, "create": true, "cross": true,
+	"current_date": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "default": true, "delete": true, "desc": true,
+	"distinct": true, "drop": true, "else": true, "end": true, "exists": true,
+	"false": true, "for": true, "foreign": true, "from": true, "grant": true,
+	"group": true, "having": true, "in": true, "index": true, "inner": true,
+	"insert": true, "interval": true, "into": true, "is": true, "join": true,
+	"key": true, "keys": true, "left": true, "like": true, "limit": true,
+	"not": true, "null": true, "offset": true, "on": true, "or": true,
+	"order": true, "outer": true, "primary": true, "range": true,
+	"references": true, "right": true, "select": true, "set": true,
+	"table": true, "then": true, "to": true, "true": true, "union": true,
+	"unique": true, "update": true, "user": true, "using": true,
+	"values": true, "when": true, "where": true, "with": true,
+}
+
+// Returns whether the supplied identifier must be quoted to be used as is
+// in SQL: reserved words, names starting with a digit and names containing
+// anything but lowercase letters, digits and underscores
+func needsQuoting(name string) bool {
+	if name == "" || reservedIdentifiers[name] || name[0] >= '0' && name[0] <= '9' {
+		return true
+	}
+	for x := 0; x < len(name); x++ {
+		c := name[x]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return true
+		}
+	}
+	return false
+}
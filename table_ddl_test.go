@@ -0,0 +1,393 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns users and articles tables defined with the column setters, which
+// is how the DDL builders are used without reflecting or loading DDL
+func ddlTestMeta(dialect Dialect) *Meta {
+	m := NewMeta(dialect, "test")
+	users := m.NewTable("users")
+	id := users.NewColumn("id").SetType("int").SetAutoIncrement(true)
+	email := users.NewColumn("email").SetType("varchar(100)")
+	users.NewColumn("status").SetType("varchar(10)").SetDefault("'active'")
+	users.NewColumn("bio").SetType("text").SetNullable(true)
+	users.NewPrimaryKey("users_pkey", id)
+	users.NewUniqueConstraint("uix_email", email)
+
+	articles := m.NewTable("articles")
+	aid := articles.NewColumn("id").SetType("serial")
+	author := articles.NewColumn("author_id").SetType("int")
+	articles.NewColumn("key").SetType("varchar(20)")
+	articles.NewColumn("price").SetType("decimal(10,2)").SetNullable(true)
+	articles.NewPrimaryKey("articles_pkey", aid)
+	articles.NewForeignKey("fk_author", []*Column{author}, []*Column{id}).
+		SetOnDelete(REFERENTIAL_ACTION_CASCADE)
+	articles.NewIndex("ix_author", false, author)
+
+	m.NewView("user_names").NewColumn("name")
+	return m
+}
+
+func TestCreateTableQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	my := ddlTestMeta(DIALECT_MYSQL)
+	pg := ddlTestMeta(DIALECT_POSTGRESQL)
+
+	tests := []struct {
+		name string
+		q    *CreateTableQuery
+		qs   string
+		qe   error
+	}{
+		{
+			name: "MySQL AUTO_INCREMENT",
+			q:    CreateTable(my.Table("users")),
+			qs: "CREATE TABLE users (id int NOT NULL AUTO_INCREMENT, " +
+				"email varchar(100) NOT NULL, " +
+				"status varchar(10) NOT NULL DEFAULT 'active', bio text, " +
+				"PRIMARY KEY (id), CONSTRAINT uix_email UNIQUE (email))",
+		},
+		{
+			name: "PostgreSQL IDENTITY",
+			q:    CreateTable(pg.Table("users")),
+			qs: "CREATE TABLE users (id integer NOT NULL GENERATED BY DEFAULT AS IDENTITY, " +
+				"email character varying(100) NOT NULL, " +
+				"status character varying(10) NOT NULL DEFAULT 'active', bio text, " +
+				"CONSTRAINT users_pkey PRIMARY KEY (id), CONSTRAINT uix_email UNIQUE (email))",
+		},
+		{
+			name: "MySQL foreign key and inline index",
+			q:    my.Table("articles").Create(),
+			qs: "CREATE TABLE articles (id bigint unsigned NOT NULL AUTO_INCREMENT, " +
+				"author_id int NOT NULL, `key` varchar(20) NOT NULL, price decimal(10,2), " +
+				"PRIMARY KEY (id), INDEX ix_author (author_id), " +
+				"CONSTRAINT fk_author FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE)",
+		},
+		{
+			name: "PostgreSQL SERIAL and foreign key",
+			q:    pg.Table("articles").Create(),
+			qs: "CREATE TABLE articles (id serial NOT NULL, " +
+				"author_id integer NOT NULL, \"key\" character varying(20) NOT NULL, " +
+				"price numeric(10,2), CONSTRAINT articles_pkey PRIMARY KEY (id), " +
+				"CONSTRAINT fk_author FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE)",
+		},
+		{
+			name: "IF NOT EXISTS and CHECK",
+			q: pg.Table("users").Create().IfNotExists().
+				Check("chk_status", "status IN ('active', 'banned')").
+				Check("", "length(email) > 3"),
+			qs: "CREATE TABLE IF NOT EXISTS users (id integer NOT NULL GENERATED BY DEFAULT AS IDENTITY, " +
+				"email character varying(100) NOT NULL, " +
+				"status character varying(10) NOT NULL DEFAULT 'active', bio text, " +
+				"CONSTRAINT users_pkey PRIMARY KEY (id), CONSTRAINT uix_email UNIQUE (email), " +
+				"CONSTRAINT chk_status CHECK (status IN ('active', 'banned')), " +
+				"CHECK (length(email) > 3))",
+		},
+		{
+			name: "No target",
+			q:    CreateTable(nil),
+			qe:   ERR_DDL_NO_TARGET,
+		},
+		{
+			name: "View target",
+			q:    CreateTable(my.Table("user_names")),
+			qe:   ERR_DDL_NOT_A_TABLE,
+		},
+		{
+			name: "Empty CHECK",
+			q:    CreateTable(my.Table("users")).Check("chk", ""),
+			qe:   ERR_DDL_EMPTY_CHECK,
+		},
+	}
+	for _, test := range tests {
+		if test.qe != nil {
+			assert.Equal(test.qe, test.q.Error(), test.name)
+			assert.False(test.q.IsValid(), test.name)
+			continue
+		}
+		assert.Nil(test.q.Error(), test.name)
+		assert.True(test.q.IsValid(), test.name)
+		qs, qargs := test.q.StringArgs()
+		assert.Equal(test.qs, qs, test.name)
+		assert.Equal(0, len(qargs), test.name)
+		assert.Equal(test.qs, test.q.Interpolated(), test.name)
+	}
+
+	// Columns must have a known data type
+	m := NewMeta(DIALECT_MYSQL, "test")
+	m.NewTable("t").NewColumn("c")
+	assert.True(errors.Is(CreateTable(m.Table("t")).Error(), ERR_DDL_UNKNOWN_TYPE))
+	m.Table("t").C("c").SetType("varchar(")
+	assert.True(errors.Is(CreateTable(m.Table("t")).Error(), ERR_DDL_UNKNOWN_TYPE))
+
+	// The Meta must have a dialect
+	m = NewMeta(DIALECT_UNKNOWN, "test")
+	m.NewTable("t")
+	assert.Equal(ERR_DDL_NO_DIALECT, CreateTable(m.Table("t")).Error())
+}
+
+func TestCreateTableFromDDL(t *testing.T) {
+	assert := assert.New(t)
+
+	// A table loaded from DDL is created as it was defined
+	m := loadTestDDL(t, DIALECT_POSTGRESQL, `
+CREATE TABLE billing.invoices (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  tags TEXT[] NOT NULL DEFAULT '{}',
+  created_on TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+`)
+	assert.Equal(
+		"CREATE TABLE billing.invoices (id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY, "+
+			"tags text[] NOT NULL DEFAULT '{}', "+
+			"created_on timestamp with time zone NOT NULL DEFAULT now(), "+
+			"CONSTRAINT invoices_pkey PRIMARY KEY (id))",
+		CreateTable(m.Table("billing.invoices")).String(),
+	)
+}
+
+func TestAlterTableQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	my := ddlTestMeta(DIALECT_MYSQL)
+	pg := ddlTestMeta(DIALECT_POSTGRESQL)
+	myUsers := my.Table("users")
+	pgUsers := pg.Table("users")
+	myArticles := my.Table("articles")
+	pgArticles := pg.Table("articles")
+	myUsers.NewColumn("created_on").SetType("datetime").SetDefault("CURRENT_TIMESTAMP")
+	pgUsers.NewColumn("created_on").SetType("timestamp").SetDefault("now()")
+
+	tests := []struct {
+		name string
+		q    *AlterTableQuery
+		qs   string
+		qe   error
+	}{
+		{
+			name: "MySQL ADD COLUMN",
+			q:    AlterTable(myUsers).AddColumn(myUsers.C("created_on")),
+			qs:   "ALTER TABLE users ADD COLUMN created_on datetime NOT NULL DEFAULT CURRENT_TIMESTAMP",
+		},
+		{
+			name: "PostgreSQL ADD COLUMN",
+			q:    pgUsers.Alter().AddColumn(pgUsers.C("created_on")),
+			qs:   "ALTER TABLE users ADD COLUMN created_on timestamp without time zone NOT NULL DEFAULT now()",
+		},
+		{
+			name: "MySQL MODIFY COLUMN",
+			q:    AlterTable(myUsers).AlterColumn(myUsers.C("status")),
+			qs:   "ALTER TABLE users MODIFY COLUMN status varchar(10) NOT NULL DEFAULT 'active'",
+		},
+		{
+			name: "PostgreSQL ALTER COLUMN",
+			q:    AlterTable(pgUsers).AlterColumn(pgUsers.C("bio")),
+			qs: "ALTER TABLE users ALTER COLUMN bio TYPE text, " +
+				"ALTER COLUMN bio DROP NOT NULL, ALTER COLUMN bio DROP DEFAULT",
+		},
+		{
+			name: "Multiple actions",
+			q: AlterTable(pgArticles).
+				DropColumn(pgArticles.C("price")).
+				DropForeignKey(pgArticles.ForeignKeys()[0]).
+				AddCheck("chk_key", "key <> ''"),
+			qs: "ALTER TABLE articles DROP COLUMN price, DROP CONSTRAINT fk_author, " +
+				"ADD CONSTRAINT chk_key CHECK (key <> '')",
+		},
+		{
+			name: "MySQL constraints",
+			q: AlterTable(myUsers).
+				DropConstraint(myUsers.Constraints()[1]).
+				DropConstraint(myUsers.Constraints()[0]).
+				AddConstraint(myUsers.Constraints()[0]).
+				DropCheck("chk_status"),
+			qs: "ALTER TABLE users DROP INDEX uix_email, DROP PRIMARY KEY, " +
+				"ADD PRIMARY KEY (id), DROP CHECK chk_status",
+		},
+		{
+			name: "MySQL foreign keys",
+			q: AlterTable(myArticles).
+				DropForeignKey(myArticles.ForeignKeys()[0]).
+				AddForeignKey(myArticles.ForeignKeys()[0]),
+			qs: "ALTER TABLE articles DROP FOREIGN KEY fk_author, " +
+				"ADD CONSTRAINT fk_author FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE",
+		},
+		{
+			name: "Aliased table",
+			q:    AlterTable(myUsers.As("u")).DropColumn(myUsers.C("bio")),
+			qs:   "ALTER TABLE users DROP COLUMN bio",
+		},
+		{
+			name: "No actions",
+			q:    AlterTable(myUsers),
+			qe:   ERR_DDL_NO_ACTIONS,
+		},
+		{
+			name: "Column of another table",
+			q:    AlterTable(myUsers).DropColumn(myArticles.C("price")),
+			qe:   ERR_DDL_WRONG_TABLE,
+		},
+		{
+			name: "Column of another Meta",
+			q:    AlterTable(myUsers).DropColumn(pgUsers.C("bio")),
+			qe:   ERR_DDL_WRONG_TABLE,
+		},
+		{
+			name: "DROP CHECK without a name",
+			q:    AlterTable(myUsers).DropCheck(""),
+			qe:   ERR_DDL_CHECK_NO_NAME,
+		},
+		{
+			name: "View target",
+			q:    AlterTable(my.Table("user_names")).AddCheck("chk", "true"),
+			qe:   ERR_DDL_NOT_A_TABLE,
+		},
+	}
+	for _, test := range tests {
+		if test.qe != nil {
+			assert.Equal(test.qe, test.q.Error(), test.name)
+			assert.False(test.q.IsValid(), test.name)
+			continue
+		}
+		assert.Nil(test.q.Error(), test.name)
+		assert.True(test.q.IsValid(), test.name)
+		assert.Equal(test.qs, test.q.String(), test.name)
+	}
+}
+
+func TestDropTableQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	users := m.NewTable("users")
+	orders := m.NewTable("billing.Orders")
+
+	tests := []struct {
+		name string
+		q    Query
+		qs   string
+		qe   error
+	}{
+		{
+			name: "Simple DROP TABLE",
+			q:    DropTable(users),
+			qs:   "DROP TABLE users",
+		},
+		{
+			name: "IF EXISTS and CASCADE",
+			q:    users.Drop().IfExists().Cascade(),
+			qs:   "DROP TABLE IF EXISTS users CASCADE",
+		},
+		{
+			name: "Quoted schema-qualified table",
+			q:    DropTable(orders),
+			qs:   "DROP TABLE billing.\"Orders\"",
+		},
+		{
+			name: "TRUNCATE",
+			q:    users.Truncate(),
+			qs:   "TRUNCATE TABLE users",
+		},
+		{
+			name: "No target",
+			q:    DropTable(nil),
+			qe:   ERR_DDL_NO_TARGET,
+		},
+		{
+			name: "TRUNCATE view",
+			q:    Truncate(m.NewView("user_names")),
+			qe:   ERR_DDL_NOT_A_TABLE,
+		},
+	}
+	for _, test := range tests {
+		if test.qe != nil {
+			assert.Equal(test.qe, test.q.Error(), test.name)
+			assert.False(test.q.IsValid(), test.name)
+			continue
+		}
+		assert.True(test.q.IsValid(), test.name)
+		assert.Equal(test.qs, test.q.String(), test.name)
+	}
+}
+
+func TestCreateIndexQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	my := ddlTestMeta(DIALECT_MYSQL)
+	pg := ddlTestMeta(DIALECT_POSTGRESQL)
+	users := pg.Table("users")
+	uix := users.NewIndex("uix_status_email", true, users.C("status"), users.C("email"))
+
+	tests := []struct {
+		name string
+		q    *CreateIndexQuery
+		qs   string
+		qe   error
+	}{
+		{
+			name: "CREATE INDEX",
+			q:    CreateIndex(pg.Table("articles").Indexes()[0]),
+			qs:   "CREATE INDEX ix_author ON articles (author_id)",
+		},
+		{
+			name: "CREATE UNIQUE INDEX IF NOT EXISTS",
+			q:    uix.Create().IfNotExists(),
+			qs:   "CREATE UNIQUE INDEX IF NOT EXISTS uix_status_email ON users (status, email)",
+		},
+		{
+			name: "MySQL CREATE INDEX",
+			q:    CreateIndex(my.Table("articles").Indexes()[0]),
+			qs:   "CREATE INDEX ix_author ON articles (author_id)",
+		},
+		{
+			name: "No target",
+			q:    CreateIndex(nil),
+			qe:   ERR_DDL_NO_TARGET,
+		},
+	}
+	for _, test := range tests {
+		if test.qe != nil {
+			assert.Equal(test.qe, test.q.Error(), test.name)
+			assert.False(test.q.IsValid(), test.name)
+			continue
+		}
+		assert.True(test.q.IsValid(), test.name)
+		assert.Equal(test.qs, test.q.String(), test.name)
+	}
+
+	// MySQL doesn't support CREATE INDEX IF NOT EXISTS
+	q := CreateIndex(my.Table("articles").Indexes()[0]).IfNotExists()
+	assert.True(errors.Is(q.Error(), ERR_DDL_UNSUPPORTED))
+}
+
+func TestNeedsQuoting(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		exp  bool
+	}{
+		{name: "users", exp: false},
+		{name: "created_on", exp: false},
+		{name: "key", exp: true},
+		{name: "user", exp: true},
+		{name: "Order", exp: true},
+		{name: "2fa", exp: true},
+		{name: "first name", exp: true},
+		{name: "", exp: true},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, needsQuoting(test.name), test.name)
+	}
+}
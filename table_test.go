@@ -114,3 +114,130 @@ func TestTableC(t *testing.T) {
 	unknown := users.C("unknown")
 	assert.Nil(unknown)
 }
+
+func TestTablePrimaryKey(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	profiles := m.Table("user_profiles")
+
+	// The fixture has no key information
+	assert.Equal(0, len(profiles.PrimaryKey()))
+
+	profiles.C("user").def.pkOrdinal = 1
+	profiles.C("id").def.pkOrdinal = 2
+	pk := profiles.PrimaryKey()
+	assert.Equal(2, len(pk))
+	assert.Equal("user", pk[0].Name())
+	assert.Equal("id", pk[1].Name())
+
+	// Aliased copies of the table keep the column definitions
+	aliased := profiles.As("p")
+	assert.True(aliased.C("id").IsPrimaryKey())
+	assert.False(aliased.C("content").IsPrimaryKey())
+}
+
+func TestTableSchemaQualified(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_MYSQL, "blog")
+	users := m.NewTable("users")
+	users.NewColumn("id")
+	users.NewColumn("name")
+	invoices := m.NewTable("billing.invoices")
+	invoices.NewColumn("id")
+	invoices.NewColumn("user_id")
+
+	tests := []struct {
+		name string
+		q    Query
+		qs   string
+	}{
+		{
+			name: "Default schema is not qualified",
+			q:    Select(users),
+			qs:   "SELECT users.id, users.name FROM users",
+		},
+		{
+			name: "Other schema is qualified",
+			q:    Select(invoices),
+			qs:   "SELECT billing.invoices.id, billing.invoices.user_id FROM billing.invoices",
+		},
+		{
+			name: "Aliased table in other schema",
+			q:    Select(invoices.As("i")),
+			qs:   "SELECT i.id, i.user_id FROM billing.invoices AS i",
+		},
+		{
+			name: "Cross-schema join",
+			q: Select(invoices.C("id"), users.C("name")).Join(
+				users, Equal(invoices.C("user_id"), users.C("id")),
+			),
+			qs: "SELECT billing.invoices.id, users.name FROM billing.invoices JOIN users ON billing.invoices.user_id = users.id",
+		},
+		{
+			name: "INSERT",
+			q:    Insert(invoices, map[string]interface{}{"user_id": 1}),
+			qs:   "INSERT INTO billing.invoices (user_id) VALUES (?)",
+		},
+		{
+			name: "UPDATE",
+			q:    Update(invoices, map[string]interface{}{"user_id": 1}).Where(Equal(invoices.C("id"), 2)),
+			qs:   "UPDATE billing.invoices SET user_id = ? WHERE billing.invoices.id = ?",
+		},
+		{
+			name: "DELETE",
+			q:    Delete(invoices).Where(Equal(invoices.C("id"), 2)),
+			qs:   "DELETE FROM billing.invoices WHERE billing.invoices.id = ?",
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(test.qs, test.q.String(), test.name)
+	}
+}
+
+func TestTableViews(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMeta(DIALECT_POSTGRESQL, "test")
+	users := m.NewTable("users")
+	users.NewColumn("id")
+	titles := m.NewView("article_titles")
+	titles.NewColumn("id")
+	titles.NewColumn("title")
+	counts := m.NewMaterializedView("author_counts")
+	counts.NewColumn("author")
+
+	assert.Equal(TABLE_KIND_TABLE, users.Kind())
+	assert.False(users.IsView())
+	assert.False(users.IsReadOnly())
+
+	assert.Equal(TABLE_KIND_VIEW, titles.Kind())
+	assert.True(titles.IsView())
+	assert.True(titles.IsReadOnly())
+
+	assert.Equal(TABLE_KIND_MATERIALIZED_VIEW, counts.Kind())
+	assert.True(counts.IsReadOnly())
+
+	// Views may be selected from like tables
+	assert.Equal(
+		"SELECT t.id, t.title FROM article_titles AS t",
+		Select(titles.As("t")).String(),
+	)
+
+	values := map[string]interface{}{"title": "foo"}
+	assert.Equal(ERR_INSERT_READ_ONLY, Insert(titles, values).Error())
+	assert.Equal(ERR_UPDATE_READ_ONLY, Update(titles, values).Error())
+	assert.Equal(ERR_DELETE_READ_ONLY, Delete(titles).Error())
+	assert.Equal(ERR_DELETE_READ_ONLY, Delete(counts.As("c")).Error())
+
+	// Updatable views accept modifications
+	titles.SetUpdatable(true)
+	assert.False(titles.IsReadOnly())
+	assert.Nil(Update(titles, values).Error())
+	assert.Equal("INSERT INTO article_titles (title) VALUES ($1)", Insert(titles, values).String())
+
+	// Materialized views are never updatable
+	counts.SetUpdatable(true)
+	assert.True(counts.IsReadOnly())
+}
@@ -0,0 +1,133 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ERR_PARAM_UNBOUND = errors.New("Query parameter has not been bound. Use Compile() and Bind() to supply a value.")
+	ERR_PARAM_MISSING = errors.New("No value supplied for query parameter.")
+	ERR_PARAM_UNKNOWN = errors.New("Value supplied for unknown query parameter.")
+)
+
+// A NamedParam is a placeholder for a query argument whose value is supplied
+// later, when a QueryTemplate is bound. NamedParams are created with Param()
+// and may be used anywhere a value may be used, for instance:
+//
+// q := Select(users).Where(Equal(users.C("email"), Param("email")))
+//
+// A NamedParam is rendered as a normal interpolation marker for the dialect and is
+// placed in the query's argument slice as-is. QueryTemplate.Bind() then
+// replaces each param in the argument slice with the value supplied for its
+// name.
+type NamedParam struct {
+	name string
+}
+
+// Returns a named placeholder for a query argument. See QueryTemplate.
+func Param(name string) *NamedParam {
+	return &NamedParam{name: name}
+}
+
+// Returns the name of the param
+func (p *NamedParam) Name() string {
+	return p.name
+}
+
+// Implements driver.Valuer so that an unbound param that makes its way to a
+// database driver produces a meaningful error instead of being sent as a
+// garbage value
+func (p *NamedParam) Value() (driver.Value, error) {
+	return nil, fmt.Errorf("%w Parameter name: %s", ERR_PARAM_UNBOUND, p.name)
+}
+
+// A QueryTemplate is a query that has been rendered to a SQL string once and
+// can be bound to different sets of parameter values any number of times. Use
+// QueryTemplates on hot code paths that execute the same query shape with
+// different values:
+//
+// tmpl, err := Select(users).Where(Equal(users.C("id"), Param("id"))).Compile()
+// ...
+// args, err := tmpl.Bind(map[string]interface{}{"id": 42})
+// rows, err := db.Query(tmpl.String(), args...)
+//
+// A QueryTemplate is immutable and safe for concurrent use.
+type QueryTemplate struct {
+	qs string
+	// The query arguments, with nil at the position of each param
+	args []interface{}
+	// The param name at each position in args, or "" if the position
+	// contains a literal argument
+	positions []string
+	// The set of distinct param names in the template
+	names map[string]bool
+}
+
+// Constructs a QueryTemplate from a rendered SQL string and its arguments by
+// recording the position of each param in the arguments
+func newQueryTemplate(qs string, qargs []interface{}) *QueryTemplate {
+	t := &QueryTemplate{
+		qs:        qs,
+		args:      qargs,
+		positions: make([]string, len(qargs)),
+		names:     make(map[string]bool, 0),
+	}
+	for x, arg := range qargs {
+		if p, isParam := arg.(*NamedParam); isParam {
+			t.positions[x] = p.name
+			t.names[p.name] = true
+			t.args[x] = nil
+		}
+	}
+	return t
+}
+
+// Returns the SQL string for the template
+func (t *QueryTemplate) String() string {
+	return t.qs
+}
+
+// Returns the sorted, distinct names of the params in the template
+func (t *QueryTemplate) Params() []string {
+	res := make([]string, 0, len(t.names))
+	for name := range t.names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Given a map of param name to value, returns a new slice of query arguments
+// in the positional order expected by the template's SQL string. A param name
+// that appears more than once in the query has its value placed at every
+// position the param appears. Returns an error if a value is not supplied for
+// every param in the template or if a value is supplied for a name that is
+// not a param in the template.
+func (t *QueryTemplate) Bind(values map[string]interface{}) ([]interface{}, error) {
+	for name := range values {
+		if !t.names[name] {
+			return nil, fmt.Errorf("%w Parameter name: %s", ERR_PARAM_UNKNOWN, name)
+		}
+	}
+	res := make([]interface{}, len(t.args))
+	for x, name := range t.positions {
+		if name == "" {
+			res[x] = t.args[x]
+			continue
+		}
+		v, found := values[name]
+		if !found {
+			return nil, fmt.Errorf("%w Parameter name: %s", ERR_PARAM_MISSING, name)
+		}
+		res[x] = v
+	}
+	return res, nil
+}
@@ -0,0 +1,173 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	colUserId := users.C("id")
+	colUserName := users.C("name")
+
+	pm := testFixtureMeta()
+	pm.dialect = DIALECT_POSTGRESQL
+	pusers := pm.Table("users")
+
+	tests := []struct {
+		name   string
+		q      Query
+		qs     string
+		params []string
+		values map[string]interface{}
+		qargs  []interface{}
+		qe     error
+	}{
+		{
+			name:   "SELECT with literal and param",
+			q:      Select(users).Where(Equal(colUserName, Param("name"))).Limit(10),
+			qs:     "SELECT users.id, users.name FROM users WHERE users.name = ? LIMIT ?",
+			params: []string{"name"},
+			values: map[string]interface{}{"name": "foo"},
+			qargs:  []interface{}{"foo", 10},
+		},
+		{
+			name: "SELECT with repeated param names",
+			q: Select(pusers).Where(
+				Or(
+					Equal(pusers.C("name"), Param("name")),
+					Equal(pusers.C("id"), Param("id")),
+				),
+			).Where(NotEqual(pusers.C("name"), Param("name"))),
+			qs:     "SELECT users.id, users.name FROM users WHERE (users.name = $1 OR users.id = $2) AND users.name != $3",
+			params: []string{"id", "name"},
+			values: map[string]interface{}{"name": "foo", "id": 1},
+			qargs:  []interface{}{"foo", 1, "foo"},
+		},
+		{
+			name:   "SELECT with params in IN list",
+			q:      Select(users).Where(In(colUserId, Param("a"), 2, Param("b"))),
+			qs:     "SELECT users.id, users.name FROM users WHERE users.id IN (?, ?, ?)",
+			params: []string{"a", "b"},
+			values: map[string]interface{}{"a": 1, "b": 3},
+			qargs:  []interface{}{1, 2, 3},
+		},
+		{
+			name:   "INSERT",
+			q:      Insert(users, map[string]interface{}{"name": Param("name")}),
+			qs:     "INSERT INTO users (name) VALUES (?)",
+			params: []string{"name"},
+			values: map[string]interface{}{"name": "foo"},
+			qargs:  []interface{}{"foo"},
+		},
+		{
+			name:   "UPDATE",
+			q:      Update(users, map[string]interface{}{"name": Param("name")}).Where(Equal(colUserId, Param("id"))),
+			qs:     "UPDATE users SET name = ? WHERE users.id = ?",
+			params: []string{"id", "name"},
+			values: map[string]interface{}{"name": "foo", "id": 1},
+			qargs:  []interface{}{"foo", 1},
+		},
+		{
+			name:   "DELETE",
+			q:      Delete(users).Where(Equal(colUserId, Param("id"))),
+			qs:     "DELETE FROM users WHERE users.id = ?",
+			params: []string{"id"},
+			values: map[string]interface{}{"id": 1},
+			qargs:  []interface{}{1},
+		},
+		{
+			name:   "Missing param value",
+			q:      Delete(users).Where(Equal(colUserId, Param("id"))),
+			qs:     "DELETE FROM users WHERE users.id = ?",
+			params: []string{"id"},
+			values: map[string]interface{}{},
+			qe:     ERR_PARAM_MISSING,
+		},
+		{
+			name:   "Unknown param value",
+			q:      Delete(users).Where(Equal(colUserId, Param("id"))),
+			qs:     "DELETE FROM users WHERE users.id = ?",
+			params: []string{"id"},
+			values: map[string]interface{}{"id": 1, "name": "foo"},
+			qe:     ERR_PARAM_UNKNOWN,
+		},
+	}
+	for _, test := range tests {
+		var tmpl *QueryTemplate
+		var err error
+		switch q := test.q.(type) {
+		case *SelectQuery:
+			tmpl, err = q.Compile()
+		case *InsertQuery:
+			tmpl, err = q.Compile()
+		case *UpdateQuery:
+			tmpl, err = q.Compile()
+		case *DeleteQuery:
+			tmpl, err = q.Compile()
+		}
+		assert.Nil(err, test.name)
+		assert.Equal(test.qs, tmpl.String(), test.name)
+		assert.Equal(test.params, tmpl.Params(), test.name)
+
+		qargs, err := tmpl.Bind(test.values)
+		if test.qe != nil {
+			assert.True(errors.Is(err, test.qe), test.name)
+			continue
+		}
+		assert.Nil(err, test.name)
+		assert.Equal(test.qargs, qargs, test.name)
+	}
+}
+
+func TestQueryTemplateErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+
+	// Compiling a query that has an error returns the query's error
+	tmpl, err := Insert(users, nil).Compile()
+	assert.Nil(tmpl)
+	assert.Equal(ERR_INSERT_NO_VALUES, err)
+
+	// An unbound param sent to a database driver produces an error
+	_, qargs := Select(users).Where(Equal(users.C("id"), Param("id"))).StringArgs()
+	p, isParam := qargs[0].(*NamedParam)
+	assert.True(isParam)
+	assert.Equal("id", p.Name())
+	_, err = p.Value()
+	assert.True(errors.Is(err, ERR_PARAM_UNBOUND))
+}
+
+func TestQueryTemplateBindIsIndependent(t *testing.T) {
+	assert := assert.New(t)
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	q := Select(users).Where(Equal(users.C("id"), Param("id")))
+
+	tmpl, err := q.Compile()
+	assert.Nil(err)
+
+	qargs1, _ := tmpl.Bind(map[string]interface{}{"id": 1})
+	qargs2, _ := tmpl.Bind(map[string]interface{}{"id": 2})
+	assert.Equal([]interface{}{1}, qargs1)
+	assert.Equal([]interface{}{2}, qargs2)
+
+	// Modifying the query after compiling does not affect the template
+	q.Limit(10)
+	assert.Equal("SELECT users.id, users.name FROM users WHERE users.id = ?", tmpl.String())
+
+	assert.Equal("SELECT users.id, users.name FROM users WHERE users.id = :id LIMIT 10", q.Interpolated())
+}
@@ -0,0 +1,150 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+var (
+	ERR_TX_NO_DB = errors.New("No database supplied.")
+)
+
+const (
+	// MySQL error numbers for a deadlock and for a lock wait timeout
+	_MYSQL_ER_LOCK_DEADLOCK     = 1213
+	_MYSQL_ER_LOCK_WAIT_TIMEOUT = 1205
+	// PostgreSQL SQLSTATEs for a serialization failure and a deadlock
+	_PG_SERIALIZATION_FAILURE = "40001"
+	_PG_DEADLOCK_DETECTED     = "40P01"
+)
+
+// TxOptions controls how WithTx() begins and retries a transaction
+type TxOptions struct {
+	// The isolation level and read-only flag passed to sql.DB.BeginTx()
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// The number of times the transaction is retried after a deadlock or
+	// serialization failure. Zero disables retries.
+	MaxRetries int
+	// The delay before the first retry, which is doubled for each retry
+	// after that
+	Backoff time.Duration
+}
+
+// The TxOptions used by WithTx() when none are supplied
+var defaultTxOptions = TxOptions{
+	MaxRetries: 3,
+	Backoff:    10 * time.Millisecond,
+}
+
+// A Tx is a database transaction started by WithTx(). It embeds *sql.Tx, so
+// it is an Executor that queries may be run with.
+type Tx struct {
+	*sql.Tx
+	// The number of savepoints created in the transaction, used to give each
+	// savepoint a unique name
+	savepoints int
+}
+
+// Runs the supplied function in a transaction that is committed if the
+// function returns nil and rolled back if it returns an error or panics.
+//
+// If the function or the commit fails because of a deadlock or serialization
+// failure (see IsRetryable()), the whole transaction is retried, with a
+// backoff between attempts, up to opts.MaxRetries times. The function must
+// therefore be safe to run more than once. If opts is nil, the transaction
+// uses the database's default isolation level and is retried up to 3 times,
+// starting with a 10ms backoff.
+func WithTx(ctx context.Context, db *sql.DB, opts *TxOptions, fn func(tx *Tx) error) error {
+	if db == nil {
+		return ERR_TX_NO_DB
+	}
+	o := defaultTxOptions
+	if opts != nil {
+		o = *opts
+	}
+	backoff := o.Backoff
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, &o, fn)
+		if err == nil || attempt >= o.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+		if backoff > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, o *TxOptions, fn func(tx *Tx) error) error {
+	sqlTx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(&Tx{Tx: sqlTx}); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// Runs the supplied function inside a SAVEPOINT. If the function returns an
+// error, the transaction is rolled back to the savepoint, undoing only the
+// function's changes, and the error is returned. Savepoints may be nested by
+// calling Savepoint() from within the function.
+func (tx *Tx) Savepoint(ctx context.Context, fn func(tx *Tx) error) error {
+	tx.savepoints++
+	name := "sqlb_sp_" + strconv.Itoa(tx.savepoints)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		// If rolling back to the savepoint fails, the transaction is unusable
+		// and will be rolled back by WithTx(), so we return the function's
+		// error, which is the more useful of the two
+		tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// Returns whether the supplied error, or an error it wraps, is a deadlock or
+// serialization failure after which the transaction may succeed if retried:
+// MySQL errors 1213 (ER_LOCK_DEADLOCK) and 1205 (ER_LOCK_WAIT_TIMEOUT) and
+// PostgreSQL SQLSTATEs 40001 (serialization_failure) and 40P01
+// (deadlock_detected).
+func IsRetryable(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == _MYSQL_ER_LOCK_DEADLOCK || myErr.Number == _MYSQL_ER_LOCK_WAIT_TIMEOUT
+	}
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == _PG_SERIALIZATION_FAILURE || pgErr.Code == _PG_DEADLOCK_DETECTED
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+package sqlb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTx(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	m := testFixtureMeta()
+	users := m.Table("users")
+	insert := users.Insert(map[string]interface{}{"name": "fred"})
+
+	err := WithTx(ctx, db, nil, func(tx *Tx) error {
+		_, err := insert.Exec(ctx, tx)
+		return err
+	})
+	assert.Nil(err)
+	assert.Equal([]string{"BEGIN", "INSERT INTO users (name) VALUES (?)", "COMMIT"}, rec.stmts)
+
+	// An error from the function rolls the transaction back and is returned
+	rec.stmts = nil
+	failed := errors.New("failed")
+	err = WithTx(ctx, db, nil, func(tx *Tx) error {
+		insert.Exec(ctx, tx)
+		return failed
+	})
+	assert.Equal(failed, err)
+	assert.Equal([]string{"BEGIN", "INSERT INTO users (name) VALUES (?)", "ROLLBACK"}, rec.stmts)
+
+	// A panic rolls the transaction back and is propagated
+	rec.stmts = nil
+	assert.Panics(func() {
+		WithTx(ctx, db, nil, func(tx *Tx) error {
+			panic("boom")
+		})
+	})
+	assert.Equal([]string{"BEGIN", "ROLLBACK"}, rec.stmts)
+
+	assert.Equal(ERR_TX_NO_DB, WithTx(ctx, nil, nil, func(tx *Tx) error { return nil }))
+}
+
+func TestWithTxRetry(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+	calls := 0
+	err := WithTx(ctx, db, &TxOptions{MaxRetries: 2, Backoff: time.Millisecond}, func(tx *Tx) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("inserting user: %w", deadlock)
+		}
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(3, calls)
+	assert.Equal([]string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}, rec.stmts)
+
+	// The last error is returned once the retries are used up
+	calls = 0
+	serialization := &pq.Error{Code: "40001"}
+	err = WithTx(ctx, db, &TxOptions{MaxRetries: 1}, func(tx *Tx) error {
+		calls++
+		return serialization
+	})
+	assert.Equal(serialization, err)
+	assert.Equal(2, calls)
+
+	// Other errors are not retried
+	calls = 0
+	err = WithTx(ctx, db, nil, func(tx *Tx) error {
+		calls++
+		return &pq.Error{Code: "23505"}
+	})
+	assert.NotNil(err)
+	assert.Equal(1, calls)
+
+	// Waiting to retry stops when the context is done
+	cctx, cancel := context.WithCancel(ctx)
+	calls = 0
+	err = WithTx(cctx, db, &TxOptions{MaxRetries: 5, Backoff: time.Hour}, func(tx *Tx) error {
+		calls++
+		cancel()
+		return deadlock
+	})
+	assert.Equal(context.Canceled, err)
+	assert.Equal(1, calls)
+}
+
+func TestTxSavepoint(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, rec := testRecordingDB(t)
+	defer db.Close()
+
+	failed := errors.New("failed")
+	err := WithTx(ctx, db, nil, func(tx *Tx) error {
+		err := tx.Savepoint(ctx, func(tx *Tx) error {
+			return tx.Savepoint(ctx, func(tx *Tx) error {
+				return failed
+			})
+		})
+		assert.Equal(failed, err)
+		return tx.Savepoint(ctx, func(tx *Tx) error {
+			return nil
+		})
+	})
+	assert.Nil(err)
+	assert.Equal([]string{
+		"BEGIN",
+		"SAVEPOINT sqlb_sp_1",
+		"SAVEPOINT sqlb_sp_2",
+		"ROLLBACK TO SAVEPOINT sqlb_sp_2",
+		"ROLLBACK TO SAVEPOINT sqlb_sp_1",
+		"SAVEPOINT sqlb_sp_3",
+		"RELEASE SAVEPOINT sqlb_sp_3",
+		"COMMIT",
+	}, rec.stmts)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		err error
+		exp bool
+	}{
+		{&mysql.MySQLError{Number: 1213}, true},
+		{&mysql.MySQLError{Number: 1205}, true},
+		{&mysql.MySQLError{Number: 1062}, false},
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{fmt.Errorf("wrapped: %w", &pq.Error{Code: "40P01"}), true},
+		{errors.New("other"), false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		assert.Equal(test.exp, IsRetryable(test.err), fmt.Sprintf("%v", test.err))
+	}
+}
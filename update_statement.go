@@ -8,10 +8,11 @@ package sqlb
 // UPDATE <table> SET <column_value_list>[ WHERE <predicates>]
 
 type updateStatement struct {
-	table   *Table
-	columns []*Column
-	values  []interface{}
-	where   *whereClause
+	table    *Table
+	columns  []*Column
+	values   []interface{}
+	where    *whereClause
+	comments *commentClause
 }
 
 func (s *updateStatement) argCount() int {
@@ -23,7 +24,7 @@ func (s *updateStatement) argCount() int {
 }
 
 func (s *updateStatement) size(scanner *sqlScanner) int {
-	size := len(Symbols[SYM_UPDATE]) + len(s.table.name) + len(Symbols[SYM_SET])
+	size := len(Symbols[SYM_UPDATE]) + s.table.nameSize() + len(Symbols[SYM_SET])
 	ncols := len(s.columns)
 	for _, c := range s.columns {
 		// We don't add the table identifier or use an alias when outputting
@@ -33,20 +34,26 @@ func (s *updateStatement) size(scanner *sqlScanner) int {
 	// NOTE(jaypipes): We do not include the length of interpolation markers,
 	// since that differs based on the SQL dialect
 	size += len(Symbols[SYM_EQUAL]) * ncols
-	// Two comma-delimited lists of same number of elements (columns and
-	// values)
-	size += 2 * (len(Symbols[SYM_COMMA_WS]) * (ncols - 1)) // the commas...
+	// One comma-delimited list of <column> = <value> elements
+	size += len(Symbols[SYM_COMMA_WS]) * (ncols - 1)
 	if s.where != nil {
 		size += s.where.size(scanner)
 	}
+	if s.comments != nil {
+		size += s.comments.leadingSize(scanner)
+		size += s.comments.trailingSize(scanner)
+	}
 	return size
 }
 
 func (s *updateStatement) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	bw := 0
+	if s.comments != nil {
+		bw += s.comments.scanLeading(scanner, b[bw:])
+	}
 	bw += copy(b[bw:], Symbols[SYM_UPDATE])
 	// We don't add any table alias when outputting the table identifier
-	bw += copy(b[bw:], s.table.name)
+	bw += s.table.scanName(b[bw:])
 	bw += copy(b[bw:], Symbols[SYM_SET])
 
 	ncols := len(s.columns)
@@ -56,7 +63,7 @@ func (s *updateStatement) scan(scanner *sqlScanner, b []byte, args []interface{}
 		// statement
 		bw += copy(b[bw:], c.name)
 		bw += copy(b[bw:], Symbols[SYM_EQUAL])
-		bw += scanInterpolationMarker(scanner.dialect, b[bw:], *curArg)
+		bw += scanner.scanMarker(b[bw:], *curArg)
 		args[*curArg] = s.values[x]
 		*curArg++
 		if x != (ncols - 1) {
@@ -67,6 +74,9 @@ func (s *updateStatement) scan(scanner *sqlScanner, b []byte, args []interface{}
 	if s.where != nil {
 		bw += s.where.scan(scanner, b[bw:], args, curArg)
 	}
+	if s.comments != nil {
+		bw += s.comments.scanTrailing(scanner, b[bw:])
+	}
 	return bw
 }
 
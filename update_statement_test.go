@@ -49,6 +49,16 @@ func TestUpdateStatement(t *testing.T) {
 			qs:    "UPDATE users SET name = ? WHERE users.name = ?",
 			qargs: []interface{}{"foo", "bar"},
 		},
+		{
+			name: "UPDATE multiple columns",
+			s: &updateStatement{
+				table:   users,
+				columns: []*Column{colUserName, users.C("id")},
+				values:  []interface{}{"foo", 1},
+			},
+			qs:    "UPDATE users SET name = ?, id = ?",
+			qargs: []interface{}{"foo", 1},
+		},
 	}
 	for _, test := range tests {
 		expArgc := len(test.qargs)
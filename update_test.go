@@ -60,6 +60,12 @@ func TestUpdateQuery(t *testing.T) {
 			qs:    "UPDATE users SET name = ? WHERE users.name = ?",
 			qargs: []interface{}{"bar", "foo"},
 		},
+		{
+			name:  "UPDATE multiple columns in name order",
+			q:     Update(users, map[string]interface{}{"name": "foo", "id": 1}),
+			qs:    "UPDATE users SET id = ?, name = ?",
+			qargs: []interface{}{1, "foo"},
+		},
 	}
 	for _, test := range tests {
 		if test.qe != nil {
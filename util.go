@@ -5,6 +5,8 @@
 //
 package sqlb
 
+import "sort"
+
 // Given a slice of interface{} variables, returns a slice of element members.
 // If any of the interface{} variables are *not* of type element already, we
 // construct a Value{} for the variable.
@@ -32,3 +34,13 @@ func toValueList(vars ...interface{}) *List {
 	}
 	return &List{elements: els}
 }
+
+// Returns the keys of the supplied map in sorted order
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -31,12 +31,6 @@ func (v *value) As(alias string) *value {
 	}
 }
 
-func (v *value) disableAliasScan() func() {
-	origAlias := v.alias
-	v.alias = ""
-	return func() { v.alias = origAlias }
-}
-
 func (v *value) argCount() int {
 	return 1
 }
@@ -47,7 +41,7 @@ func (v *value) size(scanner *sqlScanner) int {
 	// top-level scanning struct before malloc'ing the buffer to inject the SQL
 	// string into.
 	size := 0
-	if v.alias != "" {
+	if v.alias != "" && !scanner.disableAliases {
 		size += len(Symbols[SYM_AS]) + len(v.alias)
 	}
 	return size
@@ -55,9 +49,9 @@ func (v *value) size(scanner *sqlScanner) int {
 
 func (v *value) scan(scanner *sqlScanner, b []byte, args []interface{}, curArg *int) int {
 	args[*curArg] = v.val
-	bw := scanInterpolationMarker(scanner.dialect, b, *curArg)
+	bw := scanner.scanMarker(b, *curArg)
 	*curArg++
-	if v.alias != "" {
+	if v.alias != "" && !scanner.disableAliases {
 		bw += copy(b[bw:], Symbols[SYM_AS])
 		bw += copy(b[bw:], v.alias)
 	}